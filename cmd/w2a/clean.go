@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mrclmr/w2a/internal/audio"
+	"github.com/mrclmr/w2a/internal/config"
+	"github.com/mrclmr/w2a/internal/lock"
+
+	"github.com/spf13/cobra"
+)
+
+func newCleanCmd() *cobra.Command {
+	cleanCmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove the intermediate files and the output files",
+		Long: `Remove the intermediate files and the output files.
+Without flags the intermediate files are removed. They are created again on the next run.
+Only the files recorded by w2a are removed, other files in the directories are kept.
+A directory without the manifest of the intermediate files or the outputs.json
+of the output files is refused.
+With --cache only the least recently used intermediate files beyond --max-size
+and the intermediate files unused for longer than --max-age are removed.`,
+		Example:      "w2a clean --all\nw2a clean --cache --max-size 2GB --max-age 30d",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			temp, err := cmd.Flags().GetBool("temp")
+			if err != nil {
+				return err
+			}
+			output, err := cmd.Flags().GetBool("output")
+			if err != nil {
+				return err
+			}
+			all, err := cmd.Flags().GetBool("all")
+			if err != nil {
+				return err
+			}
+			outDir, err := dirFlag(cmd, "output-dir", "")
+			if err != nil {
+				return err
+			}
+			tmpDir, err := dirFlag(cmd, "temp-dir", "")
+			if err != nil {
+				return err
+			}
+			cache, err := cmd.Flags().GetBool("cache")
+			if err != nil {
+				return err
+			}
+			removeTemp := cache || temp || all || !output
+			removeOutput := !cache && (output || all)
+			var dirs []string
+			if removeTemp {
+				dirs = append(dirs, tmpDir)
+			}
+			if removeOutput {
+				dirs = append(dirs, outDir)
+			}
+			l, err := lockExisting(dirs)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = errors.Join(err, l.Release())
+			}()
+			if cache {
+				return cleanCache(cmd, tmpDir)
+			}
+			if removeTemp {
+				err = printRemoved(audio.CleanTemp(tmpDir))
+				if err != nil {
+					return err
+				}
+			}
+			if removeOutput {
+				return printRemoved(audio.CleanOutput(outDir))
+			}
+			return nil
+		},
+	}
+
+	cleanCmd.Flags().Bool("temp", false, "Remove the intermediate files")
+	cleanCmd.Flags().Bool("output", false, "Remove the output files")
+	cleanCmd.Flags().Bool("all", false, "Remove the intermediate files and the output files")
+	cleanCmd.Flags().Bool("cache", false, "Remove the least recently used intermediate files beyond the limits")
+	cleanCmd.Flags().String("max-size", "2GB", "Maximum size of the intermediate files with --cache")
+	cleanCmd.Flags().String("max-age", "30d", "Remove intermediate files unused for longer with --cache")
+	cleanCmd.MarkFlagsMutuallyExclusive("cache", "temp")
+	cleanCmd.MarkFlagsMutuallyExclusive("cache", "output")
+	cleanCmd.MarkFlagsMutuallyExclusive("cache", "all")
+
+	return cleanCmd
+}
+
+// lockExisting locks the existing dirs, so the files of a running w2a are not removed.
+// Missing dirs are not created.
+func lockExisting(dirs []string) (*lock.Lock, error) {
+	var existing []string
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err == nil {
+			existing = append(existing, dir)
+		}
+	}
+	return lock.Acquire(existing...)
+}
+
+// printRemoved prints the removed files and returns err.
+func printRemoved(removed []string, err error) error {
+	for _, path := range removed {
+		_, printErr := fmt.Fprintln(os.Stdout, "removed", path)
+		err = errors.Join(err, printErr)
+	}
+	return err
+}
+
+// cleanCache removes the least recently used intermediate files in tmpDir beyond the limits of the flags.
+func cleanCache(cmd *cobra.Command, tmpDir string) error {
+	maxSize, err := cmd.Flags().GetString("max-size")
+	if err != nil {
+		return err
+	}
+	size, err := config.ParseByteSize(maxSize)
+	if err != nil {
+		return err
+	}
+	maxAge, err := cmd.Flags().GetString("max-age")
+	if err != nil {
+		return err
+	}
+	age, err := config.ParseAge(maxAge)
+	if err != nil {
+		return err
+	}
+	return printRemoved(audio.CleanCache(tmpDir, (&config.Cache{MaxSize: size, MaxAge: age}).Limits()))
+}
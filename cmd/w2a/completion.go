@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/mrclmr/w2a/internal/audio"
+	"github.com/mrclmr/w2a/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// sayVoiceReg matches a line of 'say -v ?', e.g. 'Bad News    en_US    # The light you see ...'.
+var sayVoiceReg = regexp.MustCompile(`^(.+?)\s+([a-z]{2,3}_\w+)\s+#`)
+
+// completeFormats completes the audio formats.
+func completeFormats(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	var formats []string
+	for f := range audio.Unknown {
+		formats = append(formats, strings.ToLower(f.String()))
+	}
+	return formats, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeVoices completes the installed voices of the TTS engine of the workout yaml
+// passed as argument. Without a TTS engine the detected TTS engine is used.
+func completeVoices(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	var ttsCmd *config.TTSCmd
+	if len(args) > 0 {
+		cfg, err := loadWorkout(cmd, args[0])
+		if err == nil {
+			ttsCmd = cfg.TTS
+		}
+	}
+	if ttsCmd == nil {
+		var err error
+		ttsCmd, err = config.DetectTTS(lookPath, runtime.GOOS, "")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+	switch ttsCmd.TTS().TTSCmd {
+	case audio.Say:
+		return sayVoices(), cobra.ShellCompDirectiveNoFileComp
+	case audio.EspeakNG:
+		return eSpeakNGVoices(), cobra.ShellCompDirectiveNoFileComp
+	default:
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// sayVoices returns the voices of 'say -v ?' with the locale as description.
+func sayVoices() []string {
+	out, err := command("say", "-v", "?").Output()
+	if err != nil {
+		return nil
+	}
+	var voices []string
+	for line := range strings.Lines(string(out)) {
+		m := sayVoiceReg.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		voices = append(voices, m[1]+"\t"+m[2])
+	}
+	return voices
+}
+
+// eSpeakNGVoices returns the languages of 'espeak-ng --voices' with the voice name as description.
+func eSpeakNGVoices() []string {
+	out, err := command("espeak-ng", "--voices").Output()
+	if err != nil {
+		return nil
+	}
+	var voices []string
+	for line := range strings.Lines(string(out)) {
+		// Pty Language Age/Gender VoiceName File Other Languages
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] == "Pty" {
+			continue
+		}
+		voices = append(voices, fields[1]+"\t"+fields[3])
+	}
+	return voices
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+func newDiffCmd() *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff workout.yaml",
+		Short: "Print which audio files a run would rebuild, skip or delete",
+		Long: `Print which audio files a run would rebuild, copy, skip or delete without running any command.
+The planned output files are compared with the existing files of the output directory.
+A rebuilt file is shown with the file it replaces, e.g. after a text of the workout changed.`,
+		Example:           "w2a diff workout.yaml",
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: autoComplete,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			cfg, err := loadWorkout(cmd, path)
+			if err != nil {
+				return err
+			}
+			err = detectTTS(cfg)
+			if err != nil {
+				return err
+			}
+			creator, err := newFileCreator(cfg, filepath.Dir(path), runOptions{})
+			if err != nil {
+				return err
+			}
+			d, err := creator.DryRun(workoutFiles(cfg))
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(os.Stdout, d.Diff())
+			return err
+		},
+	}
+
+	addWorkoutFlag(diffCmd)
+
+	return diffCmd
+}
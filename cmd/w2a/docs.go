@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mrclmr/w2a/internal/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+func newDocsCmd(rootCmd *cobra.Command) *cobra.Command {
+	docsCmd := &cobra.Command{
+		Use:   "docs",
+		Short: "Print the reference of every workout yaml key",
+		Long: `Print the reference of every workout yaml key with type, required, default and description as markdown.
+The reference is generated from the config structs.`,
+		Example:      "w2a docs > docs/config.md",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			_, err := fmt.Fprint(os.Stdout, config.ReferenceMarkdown())
+			return err
+		},
+	}
+
+	docsCmd.AddCommand(&cobra.Command{
+		Use:   "markdown dir",
+		Short: "Generate markdown docs of all commands and the workout yaml reference",
+		Long: `Generate a markdown file per command and the workout yaml reference config.md into dir,
+e.g. to publish them on a website.`,
+		Example:           "w2a docs markdown site",
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE: func(_ *cobra.Command, args []string) error {
+			dir := args[0]
+			err := os.MkdirAll(dir, os.ModePerm)
+			if err != nil {
+				return err
+			}
+			err = doc.GenMarkdownTree(rootCmd, dir)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(dir, "config.md"), []byte(config.ReferenceMarkdown()), 0o644)
+		},
+	})
+
+	return docsCmd
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/mrclmr/w2a/internal/audio"
+	"github.com/mrclmr/w2a/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// installHints tell how to install a program per GOOS.
+// The empty GOOS is used if there is no hint for the GOOS.
+var installHints = map[string]map[string]string{
+	"sox_ng": {
+		"darwin": "install with 'brew install sox_ng'",
+		"":       "see https://codeberg.org/sox_ng/sox_ng",
+	},
+	"ffmpeg": {
+		"darwin":  "install with 'brew install ffmpeg'",
+		"linux":   "install with 'sudo apt install ffmpeg'",
+		"windows": "install with 'scoop install ffmpeg'",
+		"":        "see https://ffmpeg.org",
+	},
+	"espeak-ng": {
+		"darwin": "install with 'brew install espeak-ng'",
+		"linux":  "install with 'sudo apt install espeak-ng'",
+		"":       "see https://github.com/espeak-ng/espeak-ng",
+	},
+	"afconvert": {
+		"": "afconvert is pre-installed on macOS, reinstall the command line tools with 'xcode-select --install'",
+	},
+	"say": {
+		"": "say is pre-installed on macOS, choose another TTS engine with key 'tts'",
+	},
+}
+
+func newDoctorCmd() *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor [workout.yaml]",
+		Short: "Check the programs and permissions needed to create the audio files",
+		Long: `Check the programs and permissions needed to create the audio files
+and print how to install missing programs.
+With a workout yaml the configured TTS engine and voice are checked.`,
+		Example:           "w2a doctor example.yaml",
+		SilenceUsage:      true,
+		Args:              cobra.RangeArgs(0, 1),
+		ValidArgsFunction: autoComplete,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ttsCmd *config.TTSCmd
+			cfg := &config.Workout{}
+			var err error
+			if len(args) == 1 {
+				cfg, err = loadWorkout(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				ttsCmd = cfg.TTS
+			} else {
+				err = applyDirFlags(cmd, cfg)
+				if err != nil {
+					return err
+				}
+			}
+			return doctor(os.Stdout, ttsCmd, cfg.OutputDir, cfg.TempDir)
+		},
+	}
+
+	addWorkoutFlag(doctorCmd)
+
+	return doctorCmd
+}
+
+type check struct {
+	name string
+	err  error
+	hint string
+}
+
+// doctor prints the result of all checks and fails if a check failed.
+func doctor(w io.Writer, ttsCmd *config.TTSCmd, outDir string, tmpDir string) error {
+	checks := []check{
+		checkProgram("sox_ng"),
+		checkProgram("ffmpeg"),
+		checkProgram("ffprobe"),
+	}
+	if runtime.GOOS == "darwin" {
+		checks = append(checks, checkProgram("afconvert"))
+	}
+	checks = append(checks, checkTTS(ttsCmd)...)
+	checks = append(checks,
+		checkWritable("output dir", outDir),
+		checkWritable("temp dir", tmpDir),
+	)
+
+	failed := 0
+	for _, c := range checks {
+		var err error
+		if c.err == nil {
+			_, err = fmt.Fprintf(w, "ok       %s\n", c.name)
+		} else {
+			failed++
+			_, err = fmt.Fprintf(w, "failed   %s: %v\n", c.name, c.err)
+			if err == nil && c.hint != "" {
+				_, err = fmt.Fprintf(w, "         %s\n", c.hint)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+func checkProgram(name string) check {
+	_, err := lookPath(name)
+	return check{name: name, err: err, hint: installHint(name)}
+}
+
+// checkPrograms fails if one of programs is not installed. The error contains the install hints.
+func checkPrograms(programs []string) error {
+	var errs []error
+	for _, program := range programs {
+		c := checkProgram(program)
+		if c.err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s", c.err, c.hint))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkTTS checks the TTS engine and the voice. Without ttsCmd
+// the TTS engine which would be detected is checked.
+func checkTTS(ttsCmd *config.TTSCmd) []check {
+	if ttsCmd == nil {
+		var err error
+		ttsCmd, err = config.DetectTTS(lookPath, runtime.GOOS, "")
+		if err != nil {
+			return []check{{name: "tts", err: err, hint: installHint("espeak-ng")}}
+		}
+	}
+	tts := ttsCmd.TTS()
+	switch tts.TTSCmd {
+	case audio.Say:
+		return checkVoice("say", tts.Voice, "-v", "?")
+	case audio.EspeakNG:
+		return checkVoice("espeak-ng", tts.Voice, "--voices")
+	default:
+		program, _, _ := strings.Cut(strings.TrimSpace(tts.Voice), " ")
+		return []check{checkProgram(program)}
+	}
+}
+
+// checkVoice checks the program and searches the voice in the output of the program called with listArgs.
+func checkVoice(program string, voice string, listArgs ...string) []check {
+	c := checkProgram(program)
+	if c.err != nil {
+		return []check{c}
+	}
+	voiceCheck := check{name: program + " voice " + voice}
+	out, err := command(program, listArgs...).CombinedOutput()
+	if err != nil {
+		voiceCheck.err = fmt.Errorf("listing voices failed: %w", err)
+		return []check{c, voiceCheck}
+	}
+	if !strings.Contains(strings.ToLower(string(out)), strings.ToLower(voice)) {
+		voiceCheck.err = errors.New("voice not installed")
+		voiceCheck.hint = fmt.Sprintf("list the installed voices with '%s %s'", program, strings.Join(listArgs, " "))
+	}
+	return []check{c, voiceCheck}
+}
+
+// checkWritable creates a file in dir. A missing dir is created like on a run.
+func checkWritable(name string, dir string) check {
+	c := check{name: name + " " + dir + " writable"}
+	existed := true
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		existed = false
+	}
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	f, err := os.CreateTemp(dir, ".w2a-doctor-")
+	if err == nil {
+		err = errors.Join(f.Close(), os.Remove(f.Name()))
+	}
+	if !existed {
+		err = errors.Join(err, os.Remove(dir))
+	}
+	c.err = err
+	return c
+}
+
+func installHint(program string) string {
+	// ffprobe is part of ffmpeg.
+	if program == "ffprobe" {
+		program = "ffmpeg"
+	}
+	hints := installHints[program]
+	return cmp.Or(hints[runtime.GOOS], hints[""])
+}
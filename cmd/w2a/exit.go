@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+
+	"github.com/mrclmr/w2a/internal/audio"
+	"github.com/mrclmr/w2a/internal/config"
+)
+
+// Exit codes of w2a. They are documented in the help of the root command.
+const (
+	ExitOK         = 0
+	ExitFailure    = 1
+	ExitConfig     = 2
+	ExitDependency = 3
+	ExitTTS        = 4
+	ExitConversion = 5
+	// ExitCanceled is the exit code of a shell for a process terminated by SIGINT.
+	ExitCanceled = 130
+)
+
+// exitCodesHelp is appended to the help of the root command.
+const exitCodesHelp = `
+Exit codes:
+  0    success
+  1    other failure
+  2    invalid or missing workout yaml or a hash collision fixed by a longer hash_length
+  3    missing program, e.g. no TTS engine, sox_ng or ffmpeg
+  4    failed TTS command
+  5    failed sox_ng or ffmpeg command
+  130  canceled, e.g. by Ctrl+C`
+
+// configError marks an error of the workout yaml.
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string {
+	return e.err.Error()
+}
+
+func (e *configError) Unwrap() error {
+	return e.err
+}
+
+// ExitCode returns the exit code for the error returned by ExecuteContext.
+// ctx is the context passed to ExecuteContext.
+func ExitCode(ctx context.Context, err error) int {
+	var cfgErr *configError
+	switch {
+	case err == nil:
+		return ExitOK
+	case ctx.Err() != nil || errors.Is(err, context.Canceled):
+		return ExitCanceled
+	case errors.As(err, &cfgErr) || errors.Is(err, audio.ErrHashCollision):
+		return ExitConfig
+	case errors.Is(err, exec.ErrNotFound) || errors.Is(err, config.ErrNoTTS):
+		return ExitDependency
+	case errors.Is(err, audio.ErrTTS):
+		return ExitTTS
+	case errors.Is(err, audio.ErrConversion):
+		return ExitConversion
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/audio"
+	"github.com/mrclmr/w2a/internal/m3u"
+
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export [workout.yaml]",
+		Short: "Bundle the output files and the playlist into a zip or tar archive",
+		Long: `Bundle the output files and the playlist into a zip or tar archive, e.g. to copy them to a phone.
+The playlist in the archive refers to the files by relative paths.
+With a workout yaml its output directory is exported.
+The archive format is chosen by the extension of --output: .zip, .tar, .tar.gz or .tgz.
+The file SHA256SUMS in the archive lists the checksums of all files.
+Verify them with 'sha256sum -c SHA256SUMS' in the extracted directory.`,
+		Example:           "w2a export --include-config example.yaml",
+		SilenceUsage:      true,
+		Args:              cobra.RangeArgs(0, 1),
+		ValidArgsFunction: autoComplete,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			archivePath, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+			includeConfig, err := cmd.Flags().GetBool("include-config")
+			if err != nil {
+				return err
+			}
+			if includeConfig && len(args) == 0 {
+				return errors.New("flag --include-config needs the path to the yaml file")
+			}
+
+			var configPath string
+			var name string
+			var dir string
+			if len(args) == 1 {
+				cfg, err := loadWorkout(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				dir = cfg.OutputDir
+				name = sanitizeFilename(cfg.Name)
+				if includeConfig {
+					configPath = args[0]
+				}
+			} else {
+				dir, err = dirFlag(cmd, "output-dir", "")
+				if err != nil {
+					return err
+				}
+				name = filepath.Base(dir)
+			}
+			if archivePath == "" {
+				archivePath = name + ".zip"
+			}
+
+			err = export(archivePath, dir, configPath)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(os.Stdout, "exported %s\n", archivePath)
+			return err
+		},
+	}
+
+	exportCmd.Flags().StringP("output", "o", "", "Path of the archive (default name of the workout with extension .zip)")
+	exportCmd.Flags().Bool("include-config", false, "Add the workout yaml to the archive")
+	addWorkoutFlag(exportCmd)
+
+	return exportCmd
+}
+
+// archiveExts are the extensions of the supported archive formats.
+var archiveExts = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+func archiveExt(name string) string {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// archiveWriter adds files to a zip or tar archive.
+type archiveWriter interface {
+	add(name string, size int64, modTime time.Time, r io.Reader) error
+	Close() error
+}
+
+// export writes the output files of dir and, if configPath is set, the workout yaml into the archive at archivePath.
+// The files are put into a directory named like the archive without extension.
+func export(archivePath string, dir string, configPath string) (err error) {
+	base := filepath.Base(archivePath)
+	ext := archiveExt(base)
+	if ext == "" {
+		return fmt.Errorf("unknown archive format of '%s': use .zip, .tar, .tar.gz or .tgz", archivePath)
+	}
+	outputFiles, err := audio.OutputFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+		if err != nil {
+			_ = os.Remove(archivePath)
+		}
+	}()
+
+	var w archiveWriter
+	switch ext {
+	case ".zip":
+		w = &zipWriter{zip.NewWriter(f)}
+	case ".tar":
+		w = &tarWriter{w: tar.NewWriter(f)}
+	default:
+		gz := gzip.NewWriter(f)
+		w = &tarWriter{w: tar.NewWriter(gz), gz: gz}
+	}
+	defer func() {
+		err = errors.Join(err, w.Close())
+	}()
+
+	root := strings.TrimSuffix(base, ext)
+	paths := outputFiles
+	if configPath != "" {
+		paths = append(paths, configPath)
+	}
+
+	// The checksums are verified with 'sha256sum -c SHA256SUMS' in the extracted directory.
+	sums := strings.Builder{}
+	for _, p := range paths {
+		h := sha256.New()
+		err = addFile(w, path.Join(root, filepath.Base(p)), p, h)
+		if err != nil {
+			return err
+		}
+		sums.WriteString(fmt.Sprintf("%x  %s\n", h.Sum(nil), filepath.Base(p)))
+	}
+	return w.add(path.Join(root, checksumsFile), int64(sums.Len()), time.Now().Truncate(time.Second), strings.NewReader(sums.String()))
+}
+
+// checksumsFile lists the SHA-256 checksums of all files in the archive.
+const checksumsFile = "SHA256SUMS"
+
+// addFile adds the file at p to the archive and writes the added content to h.
+// The paths of a playlist are made relative.
+func addFile(w archiveWriter, name string, p string, h io.Writer) error {
+	file, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(p) != ".m3u" {
+		return w.add(name, info.Size(), info.ModTime(), io.TeeReader(file, h))
+	}
+	buf := &bytes.Buffer{}
+	err = m3u.Relative(file, buf)
+	if err != nil {
+		return err
+	}
+	return w.add(name, int64(buf.Len()), info.ModTime(), io.TeeReader(buf, h))
+}
+
+type zipWriter struct {
+	w *zip.Writer
+}
+
+func (z *zipWriter) add(name string, _ int64, modTime time.Time, r io.Reader) error {
+	fw, err := z.w.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, r)
+	return err
+}
+
+func (z *zipWriter) Close() error {
+	return z.w.Close()
+}
+
+type tarWriter struct {
+	w *tar.Writer
+	// gz is nil for an uncompressed tar archive.
+	gz *gzip.Writer
+}
+
+func (t *tarWriter) add(name string, size int64, modTime time.Time, r io.Reader) error {
+	err := t.w.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(t.w, r)
+	return err
+}
+
+func (t *tarWriter) Close() error {
+	err := t.w.Close()
+	if t.gz != nil {
+		err = errors.Join(err, t.gz.Close())
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/config"
+	"github.com/mrclmr/w2a/internal/fit"
+
+	"github.com/spf13/cobra"
+)
+
+func newFitCmd() *cobra.Command {
+	fitCmd := &cobra.Command{
+		Use:   "fit workout.yaml",
+		Short: "Write the workout as FIT file for Garmin watches",
+		Long: `Write the pauses and exercises as steps of a FIT workout file.
+Copy the file into the folder 'NewFiles' of a Garmin watch to start the workout on the watch
+together with the audio files. The steps have the same durations as the audio files.
+If the workout has a before workout announcement, the first step lasts until the lap button is pressed.`,
+		Example:           "w2a fit -o workout.fit example.yaml",
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: autoComplete,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+			cfg, err := loadWorkout(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				output = sanitizeFilename(cfg.Name) + ".fit"
+			}
+
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			err = fit.Encode(f, fitWorkout(cfg), time.Now())
+			if err != nil {
+				_ = f.Close()
+				return err
+			}
+			err = f.Close()
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(os.Stdout, "written %s\n", output)
+			return err
+		},
+	}
+
+	fitCmd.Flags().StringP("output", "o", "", "Path of the FIT file (default name of the workout with extension .fit)")
+	addWorkoutFlag(fitCmd)
+
+	return fitCmd
+}
+
+// fitWorkout returns the steps in the order of the audio files.
+func fitWorkout(cfg *config.Workout) fit.Workout {
+	var steps []fit.Step
+	if cfg.BeforeWorkoutText != nil {
+		steps = append(steps, fit.Step{Name: "Get ready", Intensity: fit.Rest})
+	}
+	for _, e := range newWorkoutStats(cfg) {
+		steps = append(steps,
+			fit.Step{Name: "Pause", Duration: e.pause, Intensity: fit.Rest},
+			fit.Step{Name: e.name, Duration: e.work, Intensity: fit.Active},
+		)
+	}
+	return fit.Workout{Name: cfg.Name, Steps: steps}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+func newGraphCmd() *cobra.Command {
+	graphCmd := &cobra.Command{
+		Use:   "graph workout.yaml",
+		Short: "Print the commands to create the audio files as graph",
+		Long: `Print the commands to create the audio files as graph without running them.
+The graph shows what will be generated and which intermediate files are shared.
+Render the Graphviz output with e.g. 'dot -Tsvg'.`,
+		Example:           "w2a graph workout.yaml | dot -Tsvg > workout.svg",
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: autoComplete,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				return err
+			}
+			if format != "dot" && format != "mermaid" {
+				return fmt.Errorf("unknown graph format: %s", format)
+			}
+			path := args[0]
+			cfg, err := loadWorkout(cmd, path)
+			if err != nil {
+				return err
+			}
+			err = detectTTS(cfg)
+			if err != nil {
+				return err
+			}
+			creator, err := newFileCreator(cfg, filepath.Dir(path), runOptions{})
+			if err != nil {
+				return err
+			}
+			graph, err := creator.Graph(workoutFiles(cfg), format == "mermaid")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(os.Stdout, graph)
+			return err
+		},
+	}
+
+	addWorkoutFlag(graphCmd)
+	graphCmd.Flags().StringP("format", "f", "dot", "Graph format: dot or mermaid")
+	// Registering fails only for unknown flags.
+	_ = graphCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"dot", "mermaid"}, cobra.ShellCompDirectiveNoFileComp))
+
+	return graphCmd
+}
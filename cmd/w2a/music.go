@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// musicScript replaces the tracks of a Music.app playlist with the passed files.
+// The first argument is the name of the playlist which is created if it does not exist.
+const musicScript = `on run argv
+	set playlistName to item 1 of argv
+	tell application "Music"
+		if not (exists user playlist playlistName) then
+			make new user playlist with properties {name:playlistName}
+		end if
+		delete every track of user playlist playlistName
+		repeat with i from 2 to count of argv
+			add (POSIX file (item i of argv)) to user playlist playlistName
+		end repeat
+	end tell
+end run`
+
+// checkMusicPlaylist checks the value of the flag --music-playlist before the audio files are created.
+func checkMusicPlaylist(playlist string) error {
+	if playlist != "" && runtime.GOOS != "darwin" {
+		return errors.New("flag --music-playlist is only available on macOS")
+	}
+	return nil
+}
+
+// addToMusic replaces the tracks of the Music.app playlist with the files of the m3u playlists.
+// Nothing is added if playlist is empty.
+func addToMusic(ctx context.Context, playlist string, m3uPlaylists []string) error {
+	if playlist == "" || len(m3uPlaylists) == 0 {
+		return nil
+	}
+	args := []string{"-e", musicScript, playlist}
+	for _, m3uPlaylist := range m3uPlaylists {
+		paths, err := readPlaylist(m3uPlaylist)
+		if err != nil {
+			return err
+		}
+		args = append(args, paths...)
+	}
+	out, err := exec.CommandContext(ctx, "osascript", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to add files to Music playlist '%s': %w: %s", playlist, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// openers are the commands per GOOS which open a file or directory with the default application.
+// The path is appended to the arguments.
+var openers = map[string][]string{
+	"darwin":  {"open"},
+	"windows": {"explorer"},
+}
+
+// defaultOpener is used on every GOOS without an entry in openers.
+var defaultOpener = []string{"xdg-open"}
+
+// checkOpenTarget checks the value of the flag --open before the audio files are created.
+func checkOpenTarget(target string) error {
+	switch target {
+	case "", "dir", "playlist":
+		return nil
+	default:
+		return fmt.Errorf("unknown open target '%s': use dir or playlist", target)
+	}
+}
+
+// openOutput opens the output dir of every playlist or, if target is 'playlist', every playlist.
+// Nothing is opened if target is empty.
+func openOutput(target string, playlists []string) error {
+	if target == "" {
+		return nil
+	}
+	opener, ok := openers[runtime.GOOS]
+	if !ok {
+		opener = defaultOpener
+	}
+	for _, playlist := range playlists {
+		path := playlist
+		if target == "dir" {
+			path = filepath.Dir(playlist)
+		}
+		// The opener is not waited for because some openers like explorer
+		// only return when the window is closed or exit with a non-zero code.
+		c := exec.Command(opener[0], append(opener[1:], path)...)
+		err := c.Start()
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		err = c.Process.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
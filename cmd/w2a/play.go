@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mrclmr/w2a/internal/m3u"
+
+	"github.com/spf13/cobra"
+)
+
+// players are tried in order. The file is appended to the arguments.
+var players = [][]string{
+	{"afplay"},
+	{"ffplay", "-nodisp", "-autoexit", "-loglevel", "error"},
+	{"paplay"},
+}
+
+func newPlayCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "play [file]",
+		Short: "Play an output file or the whole playlist",
+		Long: `Play an output file or the whole playlist with afplay, ffplay or paplay.
+Without argument the playlist of the output directory is played.
+If there are several playlists, the one with the shortest name is played.
+A file is also found in the output directory by the beginning of its name, e.g. '03-1'.`,
+		Example:           "w2a play && w2a play 03-1",
+		SilenceUsage:      true,
+		Args:              cobra.RangeArgs(0, 1),
+		ValidArgsFunction: playAutoComplete,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			player, err := lookPlayer()
+			if err != nil {
+				return err
+			}
+			dir, err := dirFlag(cmd, "output-dir", "")
+			if err != nil {
+				return err
+			}
+			var path string
+			if len(args) == 1 {
+				path, err = findOutputFile(dir, args[0])
+			} else {
+				path, err = findPlaylist(dir)
+			}
+			if err != nil {
+				return err
+			}
+			paths := []string{path}
+			if filepath.Ext(path) == ".m3u" {
+				paths, err = readPlaylist(path)
+				if err != nil {
+					return err
+				}
+			}
+			return play(cmd.Context(), player, paths)
+		},
+	}
+}
+
+func playAutoComplete(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	dir, err := dirFlag(cmd, "output-dir", "")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func lookPlayer() ([]string, error) {
+	for _, player := range players {
+		if _, err := exec.LookPath(player[0]); err == nil {
+			return player, nil
+		}
+	}
+	return nil, errors.New("no player found: install ffplay (ffmpeg) or paplay")
+}
+
+// findOutputFile returns path if it exists, otherwise the file of the output
+// directory whose name starts with path.
+func findOutputFile(outputDir string, path string) (string, error) {
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), path) {
+			matches = append(matches, entry.Name())
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("file not found: %s", path)
+	case 1:
+		return filepath.Join(outputDir, matches[0]), nil
+	default:
+		return "", fmt.Errorf("file name ambiguous: %s", strings.Join(matches, ", "))
+	}
+}
+
+// findPlaylist returns the playlist of the output directory. If there are several playlists,
+// e.g. with the key exercises_playlist, the one with the shortest name is returned.
+func findPlaylist(outputDir string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(outputDir, "*.m3u"))
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("no playlist found in %s", outputDir)
+	}
+	return slices.MinFunc(paths, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+	}), nil
+}
+
+func readPlaylist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return m3u.Read(f, filepath.Dir(path))
+}
+
+func play(ctx context.Context, player []string, paths []string) error {
+	for _, path := range paths {
+		_, _ = fmt.Fprintln(os.Stdout, filepath.Base(path))
+		cmd := exec.CommandContext(ctx, player[0], append(player[1:], path)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+		if err != nil {
+			return fmt.Errorf("%s: %w", player[0], err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// programAlternatives returns the programs tried per GOOS if program is not in the PATH.
+func programAlternatives(goos string, program string) []string {
+	if goos != "windows" {
+		return nil
+	}
+	switch program {
+	case "sox_ng":
+		// The Windows builds of sox_ng are named like the original SoX.
+		return []string{"sox"}
+	case "espeak-ng":
+		// The installer of eSpeak NG does not add it to the PATH.
+		programFiles := cmp.Or(os.Getenv("ProgramFiles"), `C:\Program Files`)
+		return []string{filepath.Join(programFiles, "eSpeak NG", "espeak-ng.exe")}
+	default:
+		return nil
+	}
+}
+
+// lookPath is exec.LookPath which also tries the alternatives of program.
+// The error is the one of program.
+func lookPath(program string) (string, error) {
+	path, err := exec.LookPath(program)
+	if err == nil {
+		return path, nil
+	}
+	for _, alternative := range programAlternatives(runtime.GOOS, program) {
+		altPath, altErr := exec.LookPath(alternative)
+		if altErr == nil {
+			return altPath, nil
+		}
+	}
+	return "", err
+}
+
+// commandContext is exec.CommandContext which runs the program found by lookPath.
+func commandContext(ctx context.Context, name string, arg ...string) *exec.Cmd {
+	path, err := lookPath(name)
+	if err == nil {
+		name = path
+	}
+	return exec.CommandContext(ctx, name, arg...)
+}
+
+// command is exec.Command which runs the program found by lookPath.
+func command(name string, arg ...string) *exec.Cmd {
+	return commandContext(context.Background(), name, arg...)
+}
@@ -5,26 +5,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/mrclmr/w2a/internal/audio"
 	"github.com/mrclmr/w2a/internal/config"
+	"github.com/mrclmr/w2a/internal/lock"
 	"github.com/mrclmr/w2a/internal/log"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 )
 
-func ExecuteContext(ctx context.Context, version string) error {
-	rootCmd, err := newRootCmd(version)
+func ExecuteContext(ctx context.Context, buildInfo BuildInfo) error {
+	rootCmd, err := newRootCmd(buildInfo.withDefaults())
 	if err != nil {
 		return err
 	}
@@ -32,21 +34,21 @@ func ExecuteContext(ctx context.Context, version string) error {
 }
 
 func newRootCmd(
-	version string,
+	buildInfo BuildInfo,
 ) (*cobra.Command, error) {
 	rootCmd := &cobra.Command{
-		Version:           version,
+		Version:           buildInfo.Version,
 		Use:               "w2a",
 		Short:             "Convert workout yaml to audio files",
-		Long:              "Convert workout yaml to audio files.",
+		Long:              "Convert workout yaml to audio files.\n" + exitCodesHelp,
 		SilenceUsage:      true,
 		SilenceErrors:     true,
 		DisableAutoGenTag: true,
 		CompletionOptions: cobra.CompletionOptions{
 			HiddenDefaultCmd: true,
 		},
-		Args:              cobra.RangeArgs(0, 1),
-		ValidArgsFunction: autoComplete,
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: autoCompleteAll,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if cmd.Flags().Changed("example") {
 				example, err := config.Example()
@@ -56,45 +58,125 @@ func newRootCmd(
 				_, err = fmt.Fprintln(os.Stdout, example)
 				return err
 			}
-			if len(args) != 1 {
+			if len(args) == 0 {
 				return errors.New("argument missing: path to yaml file")
 			}
-			path := args[0]
-			if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
-				return fmt.Errorf("configuration not found: %w", err)
+			watchFlag, err := cmd.Flags().GetBool("watch")
+			if err != nil {
+				return err
+			}
+			open, err := cmd.Flags().GetString("open")
+			if err != nil {
+				return err
+			}
+			err = checkOpenTarget(open)
+			if err != nil {
+				return err
+			}
+			musicPlaylist, err := cmd.Flags().GetString("music-playlist")
+			if err != nil {
+				return err
 			}
-			f, err := os.OpenFile(path, os.O_RDONLY, 0o600)
+			err = checkMusicPlaylist(musicPlaylist)
 			if err != nil {
 				return err
 			}
-			defer func() {
-				_ = f.Close()
-			}()
-			cfg, err := config.Parse(f)
+			if watchFlag {
+				opened := false
+				return watch(cmd.Context(), args, func() error {
+					playlists, err := runWorkouts(cmd, args)
+					if err != nil {
+						return err
+					}
+					err = addToMusic(cmd.Context(), musicPlaylist, playlists)
+					if err != nil || opened {
+						return err
+					}
+					opened = true
+					return openOutput(open, playlists)
+				})
+			}
+			playlists, err := runWorkouts(cmd, args)
 			if err != nil {
 				return err
 			}
-			switch cfg.LogLevel {
-			case slog.LevelInfo:
-				slog.SetDefault(slog.New(log.NewMsgHandler(os.Stdout, cfg.LogLevel)))
-			default:
-				slog.SetLogLoggerLevel(cfg.LogLevel)
+			err = addToMusic(cmd.Context(), musicPlaylist, playlists)
+			if err != nil {
+				return err
 			}
-			return run(cmd.Context(), cfg)
+			return openOutput(open, playlists)
 		},
 	}
 
 	// https://github.com/spf13/cobra/blob/6dec1ae26659a130bdb4c985768d1853b0e1bc06/command.go#L2064
+	// The external programs are only looked up if the version is printed.
+	cobra.AddTemplateFunc("buildVersion", func() string { return buildVersion(buildInfo) })
+	cobra.AddTemplateFunc("toolVersions", toolVersions)
 	rootCmd.SetVersionTemplate(`{{with .DisplayName}}{{printf "%s " .}}{{end}}{{printf "version %s" .Version}}
+{{buildVersion}}
+
+{{toolVersions}}
 
 Sound Credits
-* Race Start (start-2929965.wav) by JustInvoke -- https://freesound.org/s/446142/ -- License: Attribution 4.0
-* success.wav (success-a1a69bc.wav) by maxmakessounds -- https://freesound.org/s/353546/ -- License: Attribution 4.0
+* Race Start (start.wav) by JustInvoke -- https://freesound.org/s/446142/ -- License: Attribution 4.0
+* success.wav by maxmakessounds -- https://freesound.org/s/353546/ -- License: Attribution 4.0
 `)
 
+	rootCmd.PersistentFlags().String("output-dir", outputDir, "Directory of the output files, takes precedence over key 'output_dir'")
+	rootCmd.PersistentFlags().String("temp-dir", filepath.Join(tempDir(), intermediateFilesDir), "Directory of the intermediate files, takes precedence over key 'temp_dir'")
 	rootCmd.Flags().BoolP("example", "e", false, "Print example workout yaml")
+	rootCmd.Flags().StringP("format", "f", "", "Audio format of the output files, takes precedence over key 'audio_format'")
+	rootCmd.Flags().Bool("texts", false, "Print all texts passed to the TTS engine grouped by output file")
+	rootCmd.Flags().Bool("metrics", false, "Print the run time per command, the slowest TTS commands and the cache hit ratio")
+	rootCmd.Flags().Bool("dry-run", false, "Print the output files which would be created, copied or removed without running any command")
+	rootCmd.Flags().Bool("keep-going", false, "Create all files not affected by a failed command and report all failures at the end")
+	rootCmd.Flags().IntP("jobs", "j", 0, "Commands running at the same time (default number of CPUs)")
+	rootCmd.Flags().Bool("watch", false, "Create the audio files again whenever the workout yaml or a referenced file changes")
+	rootCmd.Flags().Bool("force", false, "Create all intermediate and output files again, e.g. after a TTS voice was updated")
+	rootCmd.Flags().BoolP("verbose", "v", false, "Print debug logs, takes precedence over key 'log_level'")
+	rootCmd.Flags().BoolP("quiet", "q", false, "Print only warnings and errors, takes precedence over key 'log_level'")
+	rootCmd.Flags().String("log-file", "", "Append all logs including debug logs to this file, the console output is unchanged")
+	rootCmd.Flags().Bool("tui", false, "Show the running commands, the state of every output file and a summary in a full screen view")
+	rootCmd.Flags().String("open", "", "Open the output directory (dir) or the playlist (playlist) after a successful run")
+	rootCmd.Flags().Lookup("open").NoOptDefVal = "dir"
+	rootCmd.Flags().String("sync", "", "Mirror the output files to this directory after a successful run, takes precedence over key 'sync'")
+	rootCmd.Flags().String("music-playlist", "", "Replace the tracks of this Music.app playlist with the output files after a successful run (macOS only)")
+	rootCmd.Flags().String("voice", "", "Voice of say or espeak-ng, takes precedence over the voice of key 'tts'")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	err := rootCmd.MarkFlagDirname("sync")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, flag := range []string{"output-dir", "temp-dir"} {
+		err := rootCmd.MarkPersistentFlagDirname(flag)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for flag, fn := range map[string]cobra.CompletionFunc{
+		"format": completeFormats,
+		"voice":  completeVoices,
+		"open":   cobra.FixedCompletions([]string{"dir", "playlist"}, cobra.ShellCompDirectiveNoFileComp),
+	} {
+		err := rootCmd.RegisterFlagCompletionFunc(flag, fn)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	rootCmd.AddCommand(newManCmd(rootCmd))
+	rootCmd.AddCommand(newPlayCmd())
+	rootCmd.AddCommand(newGraphCmd())
+	rootCmd.AddCommand(newCleanCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newDocsCmd(rootCmd))
+	rootCmd.AddCommand(newExportCmd())
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newFitCmd())
+	rootCmd.AddCommand(newSheetCmd())
 
 	return rootCmd, nil
 }
@@ -106,14 +188,20 @@ func autoComplete(_ *cobra.Command, args []string, _ string) ([]string, cobra.Sh
 	return []string{"yml", "yaml"}, cobra.ShellCompDirectiveFilterFileExt
 }
 
+// autoCompleteAll completes yaml files for every argument.
+func autoCompleteAll(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return []string{"yml", "yaml"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
 const (
 	outputDir            = "output-w2a"
 	intermediateFilesDir = "w2a-intermediate-files"
 )
 
 var (
-	underscoreReg      = regexp.MustCompile(`__+`)
-	filenameNormalizer = strings.NewReplacer(
+	underscoreReg       = regexp.MustCompile(`__+`)
+	reservedFilenameReg = regexp.MustCompile(`(?i)^(CON|PRN|AUX|NUL|COM[1-9]|LPT[1-9])(\.|$)`)
+	filenameNormalizer  = strings.NewReplacer(
 		" ", "_",
 		"<", "_",
 		">", "_",
@@ -127,19 +215,592 @@ var (
 	)
 )
 
-func run(ctx context.Context, cfg *config.Workout) error {
-	creator, err := audio.NewFileCreator(
-		audio.ToExecCmdCtx(exec.CommandContext),
-		cfg.TTS.TTS(),
-		cfg.AudioFormat,
-		filepath.Join(tempDir(), intermediateFilesDir),
-		outputDir,
-		audio.ToCreatePlaylistFunc(os.Create),
-	)
+// loadWorkout loads the workout of the yaml file at path and sets its dirs like a run does.
+// A yaml file with several workouts needs the flag --workout to select one.
+func loadWorkout(cmd *cobra.Command, path string) (*config.Workout, error) {
+	cfgs, err := loadWorkouts(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := selectWorkout(cmd, cfgs)
+	if err != nil {
+		return nil, &configError{err}
+	}
+	err = applyOutputDirs(cmd, cfg, len(cfgs) > 1)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// addWorkoutFlag adds the flag --workout of loadWorkout to cmd.
+func addWorkoutFlag(cmd *cobra.Command) {
+	cmd.Flags().String("workout", "", "Name of the workout if the yaml file contains several")
+}
+
+// selectWorkout returns the workout named by the flag --workout.
+// Without the flag the yaml file must contain one workout.
+func selectWorkout(cmd *cobra.Command, cfgs []*config.Workout) (*config.Workout, error) {
+	var name string
+	if cmd.Flags().Lookup("workout") != nil {
+		var err error
+		name, err = cmd.Flags().GetString("workout")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if name == "" && len(cfgs) == 1 {
+		return cfgs[0], nil
+	}
+	names := make([]string, len(cfgs))
+	for i, cfg := range cfgs {
+		if cfg.Name == name {
+			return cfg, nil
+		}
+		names[i] = cfg.Name
+	}
+	switch {
+	case cmd.Flags().Lookup("workout") == nil:
+		return nil, fmt.Errorf("%s supports one workout per yaml file, found %d: %s", cmd.Name(), len(cfgs), strings.Join(names, ", "))
+	case name == "":
+		return nil, fmt.Errorf("found %d workouts, select one with --workout: %s", len(cfgs), strings.Join(names, ", "))
+	default:
+		return nil, fmt.Errorf("workout %q not found, the workouts are: %s", name, strings.Join(names, ", "))
+	}
+}
+
+// loadWorkouts loads every workout of the yaml file at path.
+// Workouts without name are named after the file and, if there are several, their position.
+func loadWorkouts(path string) ([]*config.Workout, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, &configError{fmt.Errorf("configuration not found: %w", err)}
+	}
+	f, err := os.OpenFile(path, os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	cfgs, err := config.ParseAll(f)
+	if err != nil {
+		return nil, &configError{err}
+	}
+	for i, cfg := range cfgs {
+		if cfg.Name == "" {
+			cfg.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			if len(cfgs) > 1 {
+				cfg.Name = fmt.Sprintf("%s-%d", cfg.Name, i+1)
+			}
+		}
+		if cfg.Cover != "" && !filepath.IsAbs(cfg.Cover) {
+			cfg.Cover = filepath.Join(filepath.Dir(path), cfg.Cover)
+		}
+		for _, dir := range []*string{&cfg.OutputDir, &cfg.TempDir, &cfg.Sync} {
+			if *dir == "" {
+				continue
+			}
+			*dir, err = expandHome(*dir)
+			if err != nil {
+				return nil, err
+			}
+			if !filepath.IsAbs(*dir) {
+				*dir = filepath.Join(filepath.Dir(path), *dir)
+			}
+		}
+	}
+	return cfgs, nil
+}
+
+// applyDirFlags sets the output and temp dir of the workout from the flags.
+func applyDirFlags(cmd *cobra.Command, cfg *config.Workout) error {
+	var err error
+	cfg.OutputDir, err = dirFlag(cmd, "output-dir", cfg.OutputDir)
+	if err != nil {
+		return err
+	}
+	cfg.TempDir, err = dirFlag(cmd, "temp-dir", cfg.TempDir)
+	return err
+}
+
+// dirFlag returns the directory of the flag if it is set, otherwise cfgDir.
+// If both are not set, the default of the flag is returned.
+func dirFlag(cmd *cobra.Command, flag string, cfgDir string) (string, error) {
+	if cfgDir != "" && !cmd.Flags().Changed(flag) {
+		return cfgDir, nil
+	}
+	dir, err := cmd.Flags().GetString(flag)
+	if err != nil {
+		return "", err
+	}
+	return expandHome(dir)
+}
+
+// expandHome replaces a leading '~' with the home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// detectTTS sets the TTS engine available on the system if none is configured.
+func detectTTS(cfg *config.Workout) error {
+	if cfg.TTS != nil {
+		return nil
+	}
+	var err error
+	cfg.TTS, err = config.DetectTTS(lookPath, runtime.GOOS, cfg.I18n.Language)
+	if err != nil {
+		return err
+	}
+	tts := cfg.TTS.TTS()
+	slog.Info("detected tts\t", "engine", tts.TTSCmd, "voice", tts.Voice)
+	return nil
+}
+
+// runWorkouts creates the audio files of the workouts at paths one after another.
+// A yaml file may contain several workouts separated by '---'.
+// The workouts share the intermediate files. The playlists of the created files are returned.
+func runWorkouts(cmd *cobra.Command, paths []string) ([]string, error) {
+	var workouts []workoutRun
+	for _, path := range paths {
+		cfgs, err := loadWorkouts(path)
+		if err != nil {
+			if len(paths) > 1 {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			return nil, err
+		}
+		for _, cfg := range cfgs {
+			label := path
+			if len(cfgs) > 1 {
+				label = fmt.Sprintf("%s (%s)", path, cfg.Name)
+			}
+			workouts = append(workouts, workoutRun{path: path, label: label, cfg: cfg})
+		}
+	}
+	// Every workout has its own output dir because the files of other workouts
+	// in the output dir are removed after a run.
+	subdir := len(workouts) > 1
+	outputDirs := make(map[string]string)
+	for _, w := range workouts {
+		err := applyOutputDirs(cmd, w.cfg, subdir)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := outputDirs[w.cfg.OutputDir]; ok {
+			return nil, &configError{fmt.Errorf("%s and %s use the same output dir %s: set a different name or output_dir", other, w.label, w.cfg.OutputDir)}
+		}
+		outputDirs[w.cfg.OutputDir] = w.label
+	}
+
+	var playlists []string
+	for _, w := range workouts {
+		playlist, err := runWorkout(cmd, w.path, w.cfg)
+		if err != nil {
+			if subdir {
+				return nil, fmt.Errorf("%s: %w", w.label, err)
+			}
+			return nil, err
+		}
+		if playlist != "" {
+			playlists = append(playlists, playlist)
+		}
+	}
+	return playlists, nil
+}
+
+// workoutRun is a workout of the yaml file at path.
+// The label names the workout in errors.
+type workoutRun struct {
+	path  string
+	label string
+	cfg   *config.Workout
+}
+
+// applyOutputDirs sets the output, temp and sync dir of the workout from the flags.
+// If subdir is set, the output files are created in a subdirectory named after the workout
+// unless the workout yaml sets its own output dir.
+func applyOutputDirs(cmd *cobra.Command, cfg *config.Workout, subdir bool) error {
+	ownOutputDir := cfg.OutputDir != "" && !cmd.Flags().Changed("output-dir")
+	err := applyDirFlags(cmd, cfg)
 	if err != nil {
 		return err
 	}
+	if subdir && !ownOutputDir {
+		cfg.OutputDir = filepath.Join(cfg.OutputDir, sanitizeFilename(cfg.Name))
+	}
+	if cmd.Flags().Changed("sync") {
+		cfg.Sync, err = dirFlag(cmd, "sync", "")
+		if err != nil {
+			return err
+		}
+		if subdir {
+			cfg.Sync = filepath.Join(cfg.Sync, sanitizeFilename(cfg.Name))
+		}
+	}
+	return nil
+}
 
+// runWorkout creates the audio files of the workout of the yaml file at path.
+// The playlist is returned if files were created.
+func runWorkout(cmd *cobra.Command, path string, cfg *config.Workout) (string, error) {
+	if cmd.Flags().Changed("format") {
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return "", err
+		}
+		cfg.AudioFormat, err = audio.ParseFormat(format)
+		if err != nil {
+			return "", err
+		}
+	}
+	err := applyLogLevelFlags(cmd, cfg)
+	if err != nil {
+		return "", err
+	}
+	logFile, err := openLogFile(cmd)
+	if err != nil {
+		return "", err
+	}
+	if logFile != nil {
+		defer func() {
+			_ = logFile.Close()
+		}()
+		defer slog.SetDefault(slog.Default())
+	}
+	// setLogger sets the console handler. All records are also written to the log file.
+	setLogger := func(h slog.Handler) {
+		if logFile != nil {
+			h = log.NewTeeHandler(h, slog.NewTextHandler(logFile, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		}
+		slog.SetDefault(slog.New(h))
+	}
+	switch cfg.LogLevel {
+	case slog.LevelInfo:
+		setLogger(log.NewMsgHandler(os.Stdout, cfg.LogLevel))
+	default:
+		slog.SetLogLoggerLevel(cfg.LogLevel)
+		if logFile != nil {
+			setLogger(slog.Default().Handler())
+		}
+	}
+	if cmd.Flags().Changed("texts") {
+		return "", printTexts(os.Stdout, workoutFiles(cfg))
+	}
+	err = detectTTS(cfg)
+	if err != nil {
+		return "", err
+	}
+	if cmd.Flags().Changed("voice") {
+		voice, err := cmd.Flags().GetString("voice")
+		if err != nil {
+			return "", err
+		}
+		err = cfg.TTS.SetVoice(voice)
+		if err != nil {
+			return "", err
+		}
+	}
+	if cmd.Flags().Changed("dry-run") {
+		return "", dryRun(cfg, filepath.Dir(path))
+	}
+	opts, err := runFlags(cmd)
+	if err != nil {
+		return "", err
+	}
+	tuiFlag, err := cmd.Flags().GetBool("tui")
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case tuiFlag:
+		if !isTerminal(os.Stdout) {
+			return "", errors.New("flag --tui needs a terminal")
+		}
+		t := newTUI(os.Stdout, path)
+		defer slog.SetDefault(slog.Default())
+		setLogger(log.NewMsgHandler(t, slog.LevelWarn))
+		opts.progress = t.setProgress
+		opts.file = t.setFile
+		t.start()
+		defer t.stop()
+	case cfg.LogLevel == slog.LevelInfo && isTerminal(os.Stdout):
+		// The progress bar replaces the info lines of the created files.
+		// Warnings are printed above the progress bar.
+		bar := log.NewProgressBar(os.Stdout, progressBarWidth)
+		defer slog.SetDefault(slog.Default())
+		setLogger(log.NewMsgHandler(bar, slog.LevelWarn))
+		opts.progress = func(p audio.Progress) {
+			bar.Update(p.Completed, p.Total, strings.Join(p.Running, ", "))
+		}
+		defer bar.Done()
+	}
+	err = run(cmd.Context(), cfg, filepath.Dir(path), opts)
+	if err == nil && cfg.Sync != "" {
+		err = syncDir(cfg.OutputDir, cfg.Sync)
+	}
+	if err != nil {
+		// The error is printed by main but is needed in the log file as well.
+		slog.Debug("failed", "path", path, "err", err)
+	}
+	return filepath.Join(cfg.OutputDir, cfg.PlaylistName+".m3u"), err
+}
+
+// openLogFile opens the file of flag --log-file for appending.
+// It returns nil if the flag is not set.
+func openLogFile(cmd *cobra.Command) (*os.File, error) {
+	path, err := cmd.Flags().GetString("log-file")
+	if err != nil || path == "" {
+		return nil, err
+	}
+	path, err = expandHome(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+}
+
+// progressBarWidth is the width of the progress bar in characters.
+const progressBarWidth = 80
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// applyLogLevelFlags overrides the log level of the workout yaml with the flags --verbose and --quiet.
+func applyLogLevelFlags(cmd *cobra.Command, cfg *config.Workout) error {
+	verbose, err := cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return err
+	}
+	quiet, err := cmd.Flags().GetBool("quiet")
+	if err != nil {
+		return err
+	}
+	switch {
+	case verbose:
+		cfg.LogLevel = slog.LevelDebug
+	case quiet:
+		cfg.LogLevel = slog.LevelWarn
+	}
+	return nil
+}
+
+// runOptions are set by the flags of the root command.
+type runOptions struct {
+	printMetrics bool
+	keepGoing    bool
+	// jobs limits the commands running at the same time. Zero is the number of CPUs.
+	jobs int
+	// force ignores existing files.
+	force bool
+	// progress is called while the files are created.
+	progress func(audio.Progress)
+	// file is called when the state of an output file changes.
+	file func(audio.FileStatus)
+}
+
+func runFlags(cmd *cobra.Command) (runOptions, error) {
+	var opts runOptions
+	var err error
+	opts.printMetrics, err = cmd.Flags().GetBool("metrics")
+	if err != nil {
+		return opts, err
+	}
+	opts.keepGoing, err = cmd.Flags().GetBool("keep-going")
+	if err != nil {
+		return opts, err
+	}
+	opts.jobs, err = cmd.Flags().GetInt("jobs")
+	if err != nil {
+		return opts, err
+	}
+	if opts.jobs < 0 {
+		return opts, errors.New("jobs must not be negative")
+	}
+	opts.force, err = cmd.Flags().GetBool("force")
+	if err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func run(ctx context.Context, cfg *config.Workout, cfgDir string, opts runOptions) (err error) {
+	// A second run, e.g. of watch mode, would race on the intermediate files
+	// and remove the files of this run.
+	l, err := lock.Acquire(cfg.TempDir, cfg.OutputDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, l.Release())
+	}()
+
+	creator, err := newFileCreator(cfg, cfgDir, opts)
+	if err != nil {
+		return err
+	}
+	// A missing program fails the run before the first command instead of in the middle of it.
+	creator.OnPrograms(checkPrograms)
+
+	err = creator.BatchCreate(ctx, workoutFiles(cfg))
+	if err != nil {
+		return err
+	}
+
+	metrics := creator.Metrics()
+	slog.Debug("metrics", "cached", metrics.Cached, "total", metrics.Total, "cache_hit_ratio", metrics.CacheHitRatio())
+	if opts.printMetrics {
+		_, err = fmt.Fprintln(os.Stdout, metrics)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = creator.RemoveOtherFiles()
+	if err != nil {
+		return err
+	}
+	return creator.CollectGarbage(cfg.Cache.Limits())
+}
+
+// dryRun prints what run would do.
+func dryRun(cfg *config.Workout, cfgDir string) error {
+	creator, err := newFileCreator(cfg, cfgDir, runOptions{})
+	if err != nil {
+		return err
+	}
+	d, err := creator.DryRun(workoutFiles(cfg))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout, d)
+	return err
+}
+
+func newFileCreator(cfg *config.Workout, cfgDir string, opts runOptions) (*audio.FileCreator, error) {
+	bgMusic, err := backgroundMusic(cfg.BackgroundMusic, cfgDir)
+	if err != nil {
+		return nil, err
+	}
+
+	soundsDir := cfg.SoundsDir
+	if soundsDir != "" && !filepath.IsAbs(soundsDir) {
+		soundsDir = filepath.Join(cfgDir, soundsDir)
+	}
+
+	creator, err := audio.NewFileCreator(audio.Options{
+		ExecCmdCtx:         audio.ToExecCmdCtx(commandContext),
+		TTS:                cfg.TTS.TTS(),
+		Format:             cfg.AudioFormat,
+		Bitrate:            cfg.AudioBitrate,
+		SampleRate:         cfg.PipelineSampleRate,
+		Channels:           cfg.Channels,
+		ReplayGain:         cfg.ReplayGain,
+		TempDir:            cfg.TempDir,
+		OutputDir:          cfg.OutputDir,
+		CreatePlaylistFunc: audio.ToCreatePlaylistFunc(os.Create),
+		Retries:            cfg.Retry.Retries(),
+		Timeout:            cfg.Timeout,
+		ContinueOnError:    opts.keepGoing,
+		MaxParallel:        opts.jobs,
+		Force:              opts.force,
+		Recordings:         recordings(cfg.Recordings, cfgDir),
+		LoudnessTarget:     cfg.LoudnessTarget,
+		BackgroundMusic:    bgMusic,
+		SoundsDir:          soundsDir,
+		Captions:           audio.Captions{LRC: cfg.LRC, VTT: cfg.VTT},
+		Playlists:          audio.Playlists{Name: cfg.PlaylistName, Paths: cfg.PlaylistPaths.PathStyle()},
+		HashLength:         cfg.HashLength,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.progress != nil {
+		creator.OnProgress(opts.progress)
+	}
+	if opts.file != nil {
+		creator.OnFile(opts.file)
+	}
+	return creator, nil
+}
+
+// recordings resolves relative paths of recordings from the configuration directory.
+func recordings(recs map[string]string, cfgDir string) map[string]string {
+	resolved := make(map[string]string, len(recs))
+	for text, path := range recs {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cfgDir, path)
+		}
+		resolved[text] = path
+	}
+	return resolved
+}
+
+// backgroundMusic resolves the music files. A directory is resolved to its files in lexical order.
+func backgroundMusic(bgMusic *config.BackgroundMusic, cfgDir string) (*audio.BackgroundMusic, error) {
+	if bgMusic == nil {
+		return nil, nil
+	}
+	path := bgMusic.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfgDir, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("background music: %w", err)
+	}
+
+	paths := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		paths = nil
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			paths = append(paths, filepath.Join(path, entry.Name()))
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("background music: no files in %s", path)
+		}
+	}
+
+	return &audio.BackgroundMusic{
+		Paths:   paths,
+		Volume:  bgMusic.Volume,
+		Ducking: *bgMusic.Ducking,
+	}, nil
+}
+
+// printTexts prints the texts of every file so the spoken content can be proofread.
+func printTexts(w io.Writer, files []audio.File) error {
+	for _, file := range files {
+		_, err := fmt.Fprintln(w, file.Name)
+		if err != nil {
+			return err
+		}
+		for _, text := range audio.Texts(file.Segments) {
+			_, err = fmt.Fprintf(w, "    %s\n", text)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func workoutFiles(cfg *config.Workout) []audio.File {
 	i18n := cfg.I18n
 
 	workoutDur, workoutDurWithoutPauses := workoutDurations(cfg)
@@ -150,11 +811,23 @@ func run(ctx context.Context, cfg *config.Workout) error {
 		WorkoutDurationWithoutPauses: workoutDurWithoutPauses,
 	}
 
+	album := cfg.Name
+	var comment string
+	if cfg.Tags != nil {
+		if cfg.Tags.Album != nil {
+			album = cfg.Tags.Album.Replace(tmplValues)
+		}
+		if cfg.Tags.Comment != nil {
+			comment = cfg.Tags.Comment.Replace(tmplValues)
+		}
+	}
+
 	var audioFiles []audio.File
 
 	if cfg.BeforeWorkoutText != nil {
 		audioFiles = append(audioFiles, audio.File{
-			Name: "00-Before_Workout",
+			Name:     "00-Before_Workout",
+			Metadata: audio.Metadata{Title: "Before Workout"},
 			Segments: []audio.Segment{
 				&audio.Text{Value: cfg.BeforeWorkoutText.Replace(tmplValues)},
 			},
@@ -166,11 +839,11 @@ func run(ctx context.Context, cfg *config.Workout) error {
 
 	start := 5
 	countdownDur := time.Duration(start) * time.Second
-	countdown := make([]audio.Segment, 0)
-	for i := start; 0 < i; i-- {
-		countdown = append(countdown,
-			&audio.Text{Value: fmt.Sprintf("%d", i), Length: 1 * time.Second},
-		)
+	countdown := countdownSegments(cfg.Countdown, start)
+
+	var exercisesPlaylist []string
+	if cfg.ExercisesPlaylist {
+		exercisesPlaylist = []string{cfg.PlaylistName + "-exercises"}
 	}
 
 	for i, e := range cfg.Exercises {
@@ -181,12 +854,17 @@ func run(ctx context.Context, cfg *config.Workout) error {
 
 		tmplValues.ExerciseDuration = i18n.DurToText(e.Duration)
 		tmplValues.ExerciseName = e.Name
+		title := e.Name
+		if cfg.Tags != nil && cfg.Tags.Title != nil {
+			title = cfg.Tags.Title.Replace(tmplValues)
+		}
 
 		audioFiles = append(audioFiles, audio.File{
-			Name: fmt.Sprintf("%02d-0-Pause", i+1),
+			Name:     fmt.Sprintf("%02d-0-Pause", i+1),
+			Metadata: audio.Metadata{Title: "Pause - " + e.Name},
 			Segments: slices.Concat(
 				[]audio.Segment{
-					&audio.Sound{Filename: "start-2929965.wav", Length: exerciseStartSoundDur},
+					&audio.Sound{Filename: "start.wav", Length: exerciseStartSoundDur},
 					&audio.Text{Value: cfg.Pause.Text.Replace(tmplValues), Length: pauseDurRemainder},
 				},
 				countdown,
@@ -195,14 +873,14 @@ func run(ctx context.Context, cfg *config.Workout) error {
 
 		// Exercise
 		startAndName := []audio.Segment{
-			&audio.Sound{Filename: "start-2929965.wav", Length: exerciseStartSoundDur},
+			&audio.Sound{Filename: "start.wav", Length: exerciseStartSoundDur},
 			&audio.Text{Value: cfg.ExerciseBeginning.Replace(tmplValues), Length: exerciseNameDur},
 		}
 
 		var texts []audio.Segment
 		for _, text := range e.Texts {
 			texts = append(texts,
-				&audio.Text{Value: text + ", "},
+				&audio.Text{Value: text.Text + ", ", Voice: text.Voice},
 				&audio.Silence{Length: 1 * time.Second},
 			)
 		}
@@ -218,7 +896,7 @@ func run(ctx context.Context, cfg *config.Workout) error {
 					Value:  cfg.HalfTime.Text.Replace(tmplValues),
 					Length: cfg.HalfTime.Duration,
 				},
-				&audio.Sound{Filename: "start-2929965.wav", Length: exerciseStartSoundDur},
+				&audio.Sound{Filename: "start.wav", Length: exerciseStartSoundDur},
 				&audio.Silence{Length: e.Duration/2 - (exerciseStartSoundDur + countdownDur)},
 			}
 		} else {
@@ -231,51 +909,118 @@ func run(ctx context.Context, cfg *config.Workout) error {
 		}
 
 		audioFiles = append(audioFiles, audio.File{
-			Name: fmt.Sprintf("%02d-1-%s", i+1, sanitizeFilename(e.Name)),
+			Name:     fmt.Sprintf("%02d-1-%s", i+1, sanitizeFilename(e.Name)),
+			Metadata: audio.Metadata{Title: title},
 			Segments: slices.Concat(
 				startAndName,
 				textsOptHalfTime,
 				countdown,
 			),
+			Playlists: exercisesPlaylist,
 		})
 	}
 
 	if cfg.AfterWorkoutText != nil {
 		audioFiles = append(audioFiles, audio.File{
-			Name: fmt.Sprintf("%02d-After_Workout", len(cfg.Exercises)+1),
+			Name:     fmt.Sprintf("%02d-After_Workout", len(cfg.Exercises)+1),
+			Metadata: audio.Metadata{Title: "After Workout"},
 			Segments: []audio.Segment{
-				&audio.Sound{Filename: "success-a1a69bc.wav"},
+				&audio.Sound{Filename: "success.wav"},
 				&audio.Text{Value: cfg.AfterWorkoutText.Replace(tmplValues)},
 			},
 		})
 	}
 
-	err = creator.BatchCreate(ctx, audioFiles)
-	if err != nil {
-		return err
+	for i := range audioFiles {
+		audioFiles[i].Metadata.Album = album
+		audioFiles[i].Metadata.Artist = "w2a"
+		audioFiles[i].Metadata.Comment = comment
+		audioFiles[i].Metadata.Track = i + 1
+		audioFiles[i].Metadata.TrackTotal = len(audioFiles)
+		audioFiles[i].Metadata.Cover = cfg.Cover
 	}
 
-	return creator.RemoveOtherFiles()
+	// m4b is an audiobook with chapters instead of a playlist.
+	if cfg.Output == config.OutputSingle || cfg.AudioFormat == audio.M4b {
+		return []audio.File{audio.MergeFiles(sanitizeFilename(cfg.Name), audio.Metadata{
+			Title:   album,
+			Album:   album,
+			Artist:  "w2a",
+			Comment: comment,
+			Cover:   cfg.Cover,
+		}, audioFiles)}
+	}
+
+	return audioFiles
 }
 
-func workoutDurations(cfg *config.Workout) (string, string) {
-	var workoutDur time.Duration
-	var workoutDurWithoutPauses time.Duration
-	for _, e := range cfg.Exercises {
-		workoutDur += e.Duration + cfg.Pause.Duration
-		workoutDurWithoutPauses += e.Duration
+// countdownSegments returns one second long segments counting down from start to 1.
+func countdownSegments(countdown config.Countdown, start int) []audio.Segment {
+	const (
+		beepFrequency      = 880
+		finalBeepFrequency = 1760
+		beepDur            = 150 * time.Millisecond
+		finalBeepDur       = 500 * time.Millisecond
+	)
+
+	segments := make([]audio.Segment, 0)
+	for i := start; 0 < i; i-- {
+		beep := &audio.Tone{Frequency: beepFrequency, Length: beepDur}
+		if i == 1 {
+			beep = &audio.Tone{Frequency: finalBeepFrequency, Length: finalBeepDur}
+		}
+		number := &audio.Text{Value: fmt.Sprintf("%d", i), Length: 1 * time.Second}
+
+		switch countdown {
+		case config.CountdownBeeps:
+			segments = append(segments, beep, &audio.Silence{Length: 1*time.Second - beep.Length})
+		case config.CountdownBoth:
+			// The beep and the number take longer than a second, so they are trimmed to keep the countdown in time.
+			segments = append(segments, &audio.Group{
+				Segments: []audio.Segment{beep, &audio.Text{Value: number.Value}},
+				Length:   1 * time.Second,
+				Trim:     true,
+			})
+		default:
+			segments = append(segments, number)
+		}
 	}
-	return cfg.I18n.DurToText(workoutDur), cfg.I18n.DurToText(workoutDurWithoutPauses)
+	return segments
+}
+
+func workoutDurations(cfg *config.Workout) (string, string) {
+	stats := newWorkoutStats(cfg)
+	return cfg.I18n.DurToText(stats.work() + stats.pause()), cfg.I18n.DurToText(stats.work())
 }
 
+// maxFilenameLen is the maximum length in bytes of a sanitized filename. Paths in
+// deep directories can still exceed the limit of 260 characters of Windows
+// if long paths are not enabled in Windows.
+const maxFilenameLen = 100
+
+// sanitizeFilename returns filename without characters which are invalid on
+// Windows, macOS or Linux.
 func sanitizeFilename(filename string) string {
-	return underscoreReg.ReplaceAllString(
+	filename = underscoreReg.ReplaceAllString(
 		strings.Trim(
 			filenameNormalizer.Replace(filename),
 			"_"),
 		"_")
+	if len(filename) > maxFilenameLen {
+		end := maxFilenameLen
+		for end > 0 && !utf8.RuneStart(filename[end]) {
+			end--
+		}
+		filename = filename[:end]
+	}
+	// Windows removes trailing dots and spaces and reserves device names.
+	filename = strings.TrimRight(filename, ". _")
+	return reservedFilenameReg.ReplaceAllString(filename, "${1}_$2")
 }
 
+// tempDir returns the parent dir of the intermediate files. On macOS
+// os.TempDir is a per-user dir below /var/folders, /tmp is kept for the
+// documented path. Windows uses %TEMP%.
 func tempDir() string {
 	switch runtime.GOOS {
 	case "linux", "darwin":
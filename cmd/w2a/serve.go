@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/audio"
+	"github.com/mrclmr/w2a/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed serve.html.tmpl
+var serveHTML string
+
+var serveTmpl = template.Must(template.New("serve").Parse(serveHTML))
+
+// tokenHeader is the header of the token of the server. A custom header
+// also makes browsers ask the server before sending requests of other sites.
+const tokenHeader = "X-W2a-Token"
+
+func newServeCmd() *cobra.Command {
+	serveCmd := &cobra.Command{
+		Use:   "serve workout.yaml",
+		Short: "Edit the workout yaml and create the audio files in the browser",
+		Long: `Run a local web server to edit the workout yaml, create the audio files
+with live progress and play the created files in the browser.
+Only the page of the server can create the audio files and the keys
+output_dir, temp_dir and sync cannot be changed in the browser.`,
+		Example:           "w2a serve example.yaml",
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: autoComplete,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := cmd.Flags().GetString("addr")
+			if err != nil {
+				return err
+			}
+			s := &server{cmd: cmd, path: args[0], addr: addr, token: rand.Text()}
+			cfg, err := s.loadWorkout()
+			if err != nil {
+				return err
+			}
+			s.outputDir = cfg.OutputDir
+			yaml, err := os.ReadFile(s.path)
+			if err != nil {
+				return err
+			}
+			s.dirs, err = parseDirs(yaml)
+			if err != nil {
+				return &configError{err}
+			}
+			return s.listenAndServe(cmd.Context(), addr)
+		},
+	}
+
+	serveCmd.Flags().String("addr", "localhost:8080", "Address the server listens on")
+
+	return serveCmd
+}
+
+type server struct {
+	cmd  *cobra.Command
+	path string
+	addr string
+	// token is embedded in the page and required to create the audio files,
+	// so other sites cannot send requests to the server.
+	token string
+	// dirs are the dirs of the yaml at the start. The browser cannot change them.
+	dirs workoutDirs
+
+	lock      sync.Mutex
+	outputDir string
+	running   bool
+	progress  audio.Progress
+	err       error
+}
+
+// workoutDirs are the keys of the workout yaml with directories whose files w2a writes or removes.
+type workoutDirs struct {
+	outputDir string
+	tempDir   string
+	sync      string
+}
+
+// parseDirs returns the dirs of the workout yaml as written in the yaml.
+func parseDirs(yaml []byte) (workoutDirs, error) {
+	cfg, err := config.Parse(strings.NewReader(string(yaml)))
+	if err != nil {
+		return workoutDirs{}, err
+	}
+	return workoutDirs{outputDir: cfg.OutputDir, tempDir: cfg.TempDir, sync: cfg.Sync}, nil
+}
+
+type serverStatus struct {
+	Running      bool     `json:"running"`
+	Completed    int      `json:"completed"`
+	Total        int      `json:"total"`
+	RunningNames []string `json:"running_names"`
+	Err          string   `json:"error,omitempty"`
+	Files        []string `json:"files"`
+}
+
+func (s *server) listenAndServe(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("POST /generate", func(w http.ResponseWriter, r *http.Request) {
+		s.handleGenerate(ctx, w, r)
+	})
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /files/{name}", s.handleFile)
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.checkHost(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+	_, err := fmt.Fprintf(os.Stdout, "serving %s on http://%s\n", s.path, addr)
+	if err != nil {
+		return err
+	}
+	err = srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// checkHost rejects requests whose Host is not the listen address,
+// so other sites cannot read the server by rebinding their domain to it.
+func (s *server) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			http.Error(w, "host not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether host is the listen address.
+// If the server listens on all interfaces, the loopback addresses are allowed.
+func (s *server) allowedHost(host string) bool {
+	if host == s.addr {
+		return true
+	}
+	addrHost, port, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		return false
+	}
+	if ip := net.ParseIP(addrHost); addrHost != "" && (ip == nil || !ip.IsUnspecified()) {
+		return false
+	}
+	for _, loopback := range []string{"localhost", "127.0.0.1", "::1"} {
+		if host == net.JoinHostPort(loopback, port) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadWorkout loads the workout of the yaml. A yaml with several workouts is rejected
+// because the page edits and runs one workout.
+func (s *server) loadWorkout() (*config.Workout, error) {
+	return loadWorkout(s.cmd, s.path)
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, _ *http.Request) {
+	yaml, err := os.ReadFile(s.path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = serveTmpl.Execute(w, struct {
+		Path  string
+		YAML  string
+		Token string
+	}{s.path, string(yaml), s.token})
+	if err != nil {
+		slog.Error("failed to render page\t", "err", err)
+	}
+}
+
+// handleGenerate saves the valid workout yaml of the body and creates the audio files in the background.
+// The request must be sent by the page of the server with the token in the header tokenHeader.
+func (s *server) handleGenerate(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin != "" && origin != "http://"+r.Host {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(tokenHeader)), []byte(s.token)) != 1 {
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+	}
+	yaml, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dirs, err := parseDirs(yaml)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dirs != s.dirs {
+		http.Error(w, "output_dir, temp_dir and sync cannot be changed, restart w2a serve to change them", http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.running {
+		http.Error(w, "audio files are already created", http.StatusConflict)
+		return
+	}
+	err = os.WriteFile(s.path, yaml, 0o600)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.running = true
+	s.progress = audio.Progress{}
+	s.err = nil
+	go s.generate(ctx)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *server) generate(ctx context.Context) {
+	cfg, err := s.loadWorkout()
+	if err == nil {
+		s.lock.Lock()
+		s.outputDir = cfg.OutputDir
+		s.lock.Unlock()
+		err = detectTTS(cfg)
+	}
+	if err == nil {
+		err = run(ctx, cfg, filepath.Dir(s.path), runOptions{progress: s.setProgress})
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.running = false
+	s.err = err
+}
+
+func (s *server) setProgress(p audio.Progress) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.progress = p
+}
+
+func (s *server) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	s.lock.Lock()
+	status := serverStatus{
+		Running:      s.running,
+		Completed:    s.progress.Completed,
+		Total:        s.progress.Total,
+		RunningNames: s.progress.Running,
+		Files:        []string{},
+	}
+	if s.err != nil {
+		status.Err = s.err.Error()
+	}
+	outputDir := s.outputDir
+	s.lock.Unlock()
+
+	if status.RunningNames == nil {
+		status.RunningNames = []string{}
+	}
+	// Before the first run there are no outputs.
+	outputs, _ := audio.Outputs(outputDir)
+	for _, path := range outputs {
+		status.Files = append(status.Files, filepath.Base(path))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(status)
+	if err != nil {
+		slog.Error("failed to write status\t", "err", err)
+	}
+}
+
+func (s *server) handleFile(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	outputDir := s.outputDir
+	s.lock.Unlock()
+	http.ServeFile(w, r, filepath.Join(outputDir, filepath.Base(r.PathValue("name"))))
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/audio"
+	"github.com/mrclmr/w2a/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed sheet.md.tmpl
+var sheetMarkdown string
+
+//go:embed sheet.html.tmpl
+var sheetHTML string
+
+var (
+	sheetMarkdownTmpl = template.Must(template.New("sheet").Funcs(template.FuncMap{
+		"cell": func(s string) string { return strings.ReplaceAll(s, "|", `\|`) },
+	}).Parse(sheetMarkdown))
+	sheetHTMLTmpl = htmltemplate.Must(htmltemplate.New("sheet").Parse(sheetHTML))
+)
+
+func newSheetCmd() *cobra.Command {
+	sheetCmd := &cobra.Command{
+		Use:   "sheet workout.yaml",
+		Short: "Print a workout sheet to follow the workout on paper",
+		Long: `Print a printable sheet of the workout as markdown or HTML with the exercises,
+their durations and texts, the pauses and the total time.
+The announcements are rendered with the same template values as the audio files.`,
+		Example:           "w2a sheet --format html -o workout.html example.yaml",
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: autoComplete,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				return err
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+			if format != "markdown" && format != "html" {
+				return fmt.Errorf("unknown sheet format '%s': use markdown or html", format)
+			}
+			cfg, err := loadWorkout(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = os.Stdout
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					err = errors.Join(err, f.Close())
+				}()
+				w = f
+			}
+			if format == "html" {
+				return sheetHTMLTmpl.Execute(w, newSheet(cfg))
+			}
+			return sheetMarkdownTmpl.Execute(w, newSheet(cfg))
+		},
+	}
+
+	sheetCmd.Flags().String("format", "markdown", "Format of the sheet: markdown or html")
+	sheetCmd.Flags().StringP("output", "o", "", "Path of the sheet (default stdout)")
+	addWorkoutFlag(sheetCmd)
+	// Registering fails only for unknown flags.
+	_ = sheetCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"markdown", "html"}, cobra.ShellCompDirectiveNoFileComp))
+
+	return sheetCmd
+}
+
+type sheetExercise struct {
+	Number   int
+	Name     string
+	Duration time.Duration
+	// Pause is the pause before the exercise.
+	Pause    time.Duration
+	HalfTime bool
+	Texts    []string
+}
+
+type sheet struct {
+	Name      string
+	Total     time.Duration
+	Work      time.Duration
+	Pause     time.Duration
+	Before    string
+	After     string
+	Exercises []sheetExercise
+}
+
+func newSheet(cfg *config.Workout) sheet {
+	stats := newWorkoutStats(cfg)
+	workoutDur, workoutDurWithoutPauses := workoutDurations(cfg)
+	tmplValues := audio.TextTmplValues{
+		WorkoutExercisesCount:        len(cfg.Exercises),
+		WorkoutDuration:              workoutDur,
+		WorkoutDurationWithoutPauses: workoutDurWithoutPauses,
+	}
+
+	s := sheet{
+		Name:  cfg.Name,
+		Total: stats.work() + stats.pause(),
+		Work:  stats.work(),
+		Pause: stats.pause(),
+	}
+	if cfg.BeforeWorkoutText != nil {
+		s.Before = cfg.BeforeWorkoutText.Replace(tmplValues)
+	}
+	if cfg.AfterWorkoutText != nil {
+		s.After = cfg.AfterWorkoutText.Replace(tmplValues)
+	}
+	for i, e := range cfg.Exercises {
+		texts := make([]string, 0, len(e.Texts))
+		for _, text := range e.Texts {
+			texts = append(texts, text.Text)
+		}
+		s.Exercises = append(s.Exercises, sheetExercise{
+			Number:   i + 1,
+			Name:     e.Name,
+			Duration: e.Duration,
+			Pause:    stats[i].pause,
+			HalfTime: e.HalfTime,
+			Texts:    texts,
+		})
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+func newStatsCmd() *cobra.Command {
+	statsCmd := &cobra.Command{
+		Use:   "stats workout.yaml",
+		Short: "Print the durations of the workout and the count of audio files",
+		Long: `Print the total duration, the work and pause time, the durations per exercise
+and the count of audio files which would be created. Only the workout yaml is read.`,
+		Example:           "w2a stats workout.yaml",
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: autoComplete,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadWorkout(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			return printStats(os.Stdout, newWorkoutStats(cfg), len(workoutFiles(cfg)))
+		},
+	}
+
+	addWorkoutFlag(statsCmd)
+
+	return statsCmd
+}
+
+type exerciseStats struct {
+	name string
+	work time.Duration
+	// pause is the pause before the exercise.
+	pause time.Duration
+}
+
+type workoutStats []exerciseStats
+
+func newWorkoutStats(cfg *config.Workout) workoutStats {
+	stats := make(workoutStats, 0, len(cfg.Exercises))
+	for _, e := range cfg.Exercises {
+		stats = append(stats, exerciseStats{
+			name:  e.Name,
+			work:  e.Duration,
+			pause: cmp.Or(e.PauseDurationOverride, cfg.Pause.Duration),
+		})
+	}
+	return stats
+}
+
+func (s workoutStats) work() time.Duration {
+	var d time.Duration
+	for _, e := range s {
+		d += e.work
+	}
+	return d
+}
+
+func (s workoutStats) pause() time.Duration {
+	var d time.Duration
+	for _, e := range s {
+		d += e.pause
+	}
+	return d
+}
+
+func printStats(w io.Writer, s workoutStats, files int) error {
+	total := s.work() + s.pause()
+	share := func(d time.Duration) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(d) / float64(total) * 100
+	}
+	_, err := fmt.Fprintf(w, "exercises  %d\nfiles      %d\ntotal      %s\nwork       %s (%.0f%%)\npause      %s (%.0f%%)\n\n",
+		len(s), files, total, s.work(), share(s.work()), s.pause(), share(s.pause()))
+	if err != nil {
+		return err
+	}
+
+	nameLen := len("exercise")
+	for _, e := range s {
+		nameLen = max(nameLen, len(e.name))
+	}
+	_, err = fmt.Fprintf(w, "%-*s %8s %8s\n", nameLen, "exercise", "work", "pause")
+	if err != nil {
+		return err
+	}
+	for _, e := range s {
+		_, err = fmt.Fprintf(w, "%-*s %8s %8s\n", nameLen, e.name, e.work, e.pause)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mrclmr/w2a/internal/audio"
+	"github.com/mrclmr/w2a/internal/m3u"
+)
+
+// syncedFile lists the files copied by the last sync into the destination.
+// Only these files are deleted from the destination, other files are kept.
+const syncedFile = ".w2a-sync"
+
+// syncDir mirrors the output files in src to dst, e.g. a mounted phone or USB stick.
+// Files are copied if their size or modification time differ.
+// The playlist refers to the files by relative paths.
+// Files of the previous sync which are not in src anymore are deleted from dst.
+func syncDir(src string, dst string) error {
+	err := os.MkdirAll(dst, 0o755)
+	if err != nil {
+		return err
+	}
+	paths, err := audio.OutputFiles(src)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = filepath.Base(path)
+	}
+
+	previous, err := readSynced(dst)
+	if err != nil {
+		return err
+	}
+	// The files are recorded before they are copied, so the next sync removes
+	// the temporary files of an interrupted sync.
+	recorded := slices.Compact(slices.Sorted(slices.Values(slices.Concat(previous, names))))
+	err = writeSynced(dst, recorded)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		copied, err := syncFile(filepath.Join(src, name), filepath.Join(dst, name))
+		if err != nil {
+			return err
+		}
+		if copied {
+			slog.Info("synced\t", "path", filepath.Join(dst, name))
+		}
+	}
+	for _, name := range recorded {
+		err = removeIfExists(audio.TempPath(filepath.Join(dst, name)))
+		if err != nil {
+			return err
+		}
+		if slices.Contains(names, name) {
+			continue
+		}
+		err = removeIfExists(filepath.Join(dst, name))
+		if err != nil {
+			return err
+		}
+	}
+	return writeSynced(dst, names)
+}
+
+// removeIfExists removes the file at path and logs it. A missing file is ignored.
+func removeIfExists(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("removed\t", "path", path)
+	return nil
+}
+
+// writeSynced records the names as the files of the sync into dst.
+func writeSynced(dst string, names []string) error {
+	return os.WriteFile(filepath.Join(dst, syncedFile), []byte(strings.Join(names, "\n")+"\n"), 0o644)
+}
+
+// syncFile copies src to dst if dst differs and reports whether it was copied.
+func syncFile(src string, dst string) (bool, error) {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false, err
+	}
+	var content []byte
+	if filepath.Ext(src) == ".m3u" {
+		content, err = relativePlaylist(src)
+		if err != nil {
+			return false, err
+		}
+	}
+	dstInfo, err := os.Stat(dst)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return false, err
+	case content != nil:
+		old, err := os.ReadFile(dst)
+		if err == nil && bytes.Equal(old, content) {
+			return false, nil
+		}
+	case dstInfo.Size() == srcInfo.Size() && dstInfo.ModTime().Equal(srcInfo.ModTime()):
+		return false, nil
+	}
+
+	if content != nil {
+		return true, os.WriteFile(dst, content, 0o644)
+	}
+	err = audio.CopyFile(src, dst)
+	if err != nil {
+		return false, err
+	}
+	// The modification time is compared on the next sync.
+	return true, os.Chtimes(dst, srcInfo.ModTime(), srcInfo.ModTime())
+}
+
+func relativePlaylist(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	buf := &bytes.Buffer{}
+	err = m3u.Relative(f, buf)
+	return buf.Bytes(), err
+}
+
+// readSynced returns the files of the previous sync into dst.
+func readSynced(dst string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dst, syncedFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		// Only files directly in dst are deleted.
+		if name != "" && filepath.Base(name) == name {
+			names = append(names, name)
+		}
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/audio"
+)
+
+const (
+	// tuiFileRows is the maximum count of files shown at once.
+	tuiFileRows = 15
+	// tuiLogRows is the count of the last warnings shown.
+	tuiLogRows = 5
+	// tuiBarLen is the length of the progress bar of the commands.
+	tuiBarLen = 30
+	// tuiRedrawInterval limits how often the view is drawn.
+	tuiRedrawInterval = 100 * time.Millisecond
+
+	enterAltScreen = "\033[?1049h\033[?25l"
+	exitAltScreen  = "\033[?25h\033[?1049l"
+	clearScreen    = "\033[H\033[2J"
+)
+
+// tuiStates are the file states in the order they are summarized.
+var tuiStates = []string{"created", "copied", "exists", "queued", "failed"}
+
+// tui draws the running commands grouped by command, the state of every
+// output file, the cache hits and the last warnings on the alternate screen
+// of a terminal. The summary is printed when it stops.
+type tui struct {
+	writer io.Writer
+	title  string
+
+	lock     sync.Mutex
+	started  time.Time
+	drawn    time.Time
+	progress audio.Progress
+	// files are the paths in the order they were first reported.
+	files  []string
+	states map[string]string
+	logs   []string
+	// pending holds written bytes until the log line is complete.
+	pending []byte
+}
+
+func newTUI(writer io.Writer, title string) *tui {
+	return &tui{
+		writer: writer,
+		title:  title,
+		states: make(map[string]string),
+	}
+}
+
+func (t *tui) start() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.started = time.Now()
+	_, _ = fmt.Fprint(t.writer, enterAltScreen)
+	t.draw()
+}
+
+// stop leaves the alternate screen and prints the summary.
+func (t *tui) stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	_, _ = fmt.Fprint(t.writer, exitAltScreen)
+	_, _ = fmt.Fprintln(t.writer, t.summary())
+	for _, l := range t.logs {
+		_, _ = fmt.Fprintln(t.writer, l)
+	}
+}
+
+func (t *tui) setProgress(p audio.Progress) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.progress = p
+	t.redraw()
+}
+
+func (t *tui) setFile(s audio.FileStatus) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.states[s.Path]; !ok {
+		t.files = append(t.files, s.Path)
+	}
+	t.states[s.Path] = s.State
+	t.redraw()
+}
+
+// Write keeps the last complete log lines.
+func (t *tui) Write(b []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.pending = append(t.pending, b...)
+	for {
+		idx := bytes.IndexByte(t.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		t.logs = append(t.logs, string(t.pending[:idx]))
+		t.pending = t.pending[idx+1:]
+	}
+	t.logs = t.logs[max(0, len(t.logs)-tuiLogRows):]
+	t.redraw()
+	return len(b), nil
+}
+
+// redraw draws the view if it was not drawn within tuiRedrawInterval.
+func (t *tui) redraw() {
+	if time.Since(t.drawn) < tuiRedrawInterval {
+		return
+	}
+	t.draw()
+}
+
+func (t *tui) draw() {
+	t.drawn = time.Now()
+	b := strings.Builder{}
+	b.WriteString(clearScreen)
+	b.WriteString(fmt.Sprintf("%s  %s\n\n", t.title, time.Since(t.started).Round(time.Second)))
+
+	p := t.progress
+	done := 0
+	if p.Total > 0 {
+		done = tuiBarLen * p.Completed / p.Total
+	}
+	b.WriteString(fmt.Sprintf("commands [%s%s] %d/%d, cache hits %d\n",
+		strings.Repeat("#", done), strings.Repeat(" ", tuiBarLen-done), p.Completed, p.Total, p.Cached))
+	b.WriteString("running  " + runningCommands(p.Running) + "\n\n")
+
+	b.WriteString("files    " + t.stateCounts() + "\n")
+	// Unfinished files are shown first.
+	files := slices.Clone(t.files)
+	slices.SortStableFunc(files, func(a, b string) int {
+		return cmp.Compare(stateRank(t.states[a]), stateRank(t.states[b]))
+	})
+	for _, f := range files[:min(len(files), tuiFileRows)] {
+		b.WriteString(fmt.Sprintf("  %-8s %s\n", t.states[f], f))
+	}
+	if len(files) > tuiFileRows {
+		b.WriteString(fmt.Sprintf("  ... %d more\n", len(files)-tuiFileRows))
+	}
+
+	if len(t.logs) > 0 {
+		b.WriteString("\n")
+	}
+	for _, l := range t.logs {
+		b.WriteString(l + "\n")
+	}
+	_, _ = fmt.Fprint(t.writer, b.String())
+}
+
+// runningCommands groups the running nodes by command, e.g. 'espeak-ng 3, sox_ng 1'.
+func runningCommands(running []string) string {
+	counts := make(map[string]int)
+	for _, name := range running {
+		command, _, _ := strings.Cut(name, " ")
+		counts[command]++
+	}
+	var commands []string
+	for _, command := range slices.Sorted(maps.Keys(counts)) {
+		commands = append(commands, fmt.Sprintf("%s %d", command, counts[command]))
+	}
+	return strings.Join(commands, ", ")
+}
+
+// stateRank sorts failed and queued files before finished files.
+func stateRank(state string) int {
+	switch state {
+	case "failed":
+		return 0
+	case "queued":
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (t *tui) stateCounts() string {
+	counts := make(map[string]int)
+	for _, state := range t.states {
+		counts[state]++
+	}
+	var parts []string
+	for _, state := range tuiStates {
+		if counts[state] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", state, counts[state]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (t *tui) summary() string {
+	return fmt.Sprintf("%s: %d files (%s), %d commands, %d cache hits in %s",
+		t.title, len(t.files), t.stateCounts(), t.progress.Completed, t.progress.Cached,
+		time.Since(t.started).Round(time.Millisecond))
+}
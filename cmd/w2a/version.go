@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// BuildInfo describes the build of the binary. Goreleaser sets it with linker flags.
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// withDefaults fills the unset commit and date from the build info of the go command,
+// e.g. for 'go install'.
+func (b BuildInfo) withDefaults() BuildInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	if b.Version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		b.Version = info.Main.Version
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.Commit = cmp.Or(b.Commit, s.Value)
+		case "vcs.time":
+			b.Date = cmp.Or(b.Date, s.Value)
+		}
+	}
+	return b
+}
+
+// versionTools are the external programs printed by --version
+// with the arguments to print their version. Without arguments only
+// the path is printed because the program has no version flag.
+var versionTools = []struct {
+	name string
+	args []string
+}{
+	{"sox_ng", []string{"--version"}},
+	{"ffmpeg", []string{"-version"}},
+	{"afconvert", nil},
+	{"espeak-ng", []string{"--version"}},
+	{"say", nil},
+}
+
+// buildVersion returns the commit, the build date and the Go version.
+func buildVersion(b BuildInfo) string {
+	return fmt.Sprintf("commit:     %s\nbuilt:      %s\ngo:         %s %s/%s",
+		cmp.Or(b.Commit, "unknown"), cmp.Or(b.Date, "unknown"), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// toolVersions returns the first line of the version output or the path of every external program.
+func toolVersions() string {
+	lines := make([]string, 0, len(versionTools))
+	for _, tool := range versionTools {
+		path, err := lookPath(tool.name)
+		version := path
+		switch {
+		case err != nil:
+			version = "not found"
+		case tool.args != nil:
+			out, err := exec.Command(path, tool.args...).CombinedOutput()
+			if err != nil {
+				version = fmt.Sprintf("%s (version failed: %v)", path, err)
+				break
+			}
+			version, _, _ = strings.Cut(strings.TrimSpace(string(out)), "\n")
+		}
+		lines = append(lines, fmt.Sprintf("%-11s %s", tool.name+":", version))
+	}
+	return strings.Join(lines, "\n")
+}
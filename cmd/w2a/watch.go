@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// watchInterval is the time between two checks for changed files.
+const watchInterval = 500 * time.Millisecond
+
+// watch runs fn and runs it again whenever one of the workout yamls at paths or a file
+// they reference changes. An error of fn is logged and the next change is awaited.
+// watch returns if ctx is done.
+func watch(ctx context.Context, paths []string, fn func() error) error {
+	for {
+		err := fn()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			slog.Error("failed\t", "err", err)
+		}
+		var watched []string
+		for _, path := range paths {
+			watched = append(watched, watchedPaths(path)...)
+		}
+		slog.Info("watching for changes\t", "paths", strings.Join(paths, " "))
+		if !waitForChange(ctx, watched) {
+			return nil
+		}
+	}
+}
+
+// waitForChange returns true as soon as one of the paths changes
+// and false if ctx is done before.
+func waitForChange(ctx context.Context, paths []string) bool {
+	before := modTimes(paths)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			if !maps.Equal(before, modTimes(paths)) {
+				return true
+			}
+		}
+	}
+}
+
+// watchedPaths returns the workout yaml and the files and directories its workouts reference.
+// If the workout yaml is invalid, only the workout yaml is watched.
+func watchedPaths(path string) []string {
+	paths := []string{path}
+	cfgs, err := loadWorkouts(path)
+	if err != nil {
+		return paths
+	}
+	cfgDir := filepath.Dir(path)
+	for _, cfg := range cfgs {
+		if cfg.Cover != "" {
+			paths = append(paths, cfg.Cover)
+		}
+		if cfg.SoundsDir != "" {
+			paths = append(paths, resolvePath(cfg.SoundsDir, cfgDir))
+		}
+		if cfg.BackgroundMusic != nil {
+			paths = append(paths, resolvePath(cfg.BackgroundMusic.Path, cfgDir))
+		}
+		recs := recordings(cfg.Recordings, cfgDir)
+		for _, text := range slices.Sorted(maps.Keys(recs)) {
+			paths = append(paths, recs[text])
+		}
+	}
+	return paths
+}
+
+// modTimes returns the modification times of the paths and of the files in directories.
+// Missing paths are left out, so a created path is a change as well.
+func modTimes(paths []string) map[string]time.Time {
+	times := make(map[string]time.Time)
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		times[p] = info.ModTime()
+		if !info.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			times[filepath.Join(p, entry.Name())] = info.ModTime()
+		}
+	}
+	return times
+}
+
+func resolvePath(path string, cfgDir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(cfgDir, path)
+}
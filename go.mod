@@ -5,7 +5,6 @@ go 1.25
 require (
 	github.com/spf13/cobra v1.10.1
 	go.yaml.in/yaml/v3 v3.0.4
-	golang.org/x/sync v0.17.0
 	golang.org/x/text v0.29.0
 )
 
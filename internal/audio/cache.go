@@ -0,0 +1,118 @@
+package audio
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+)
+
+// Cache limits the intermediate files in the temp dir. Zero values are no limit.
+type Cache struct {
+	// MaxSize is the maximum size in bytes of the intermediate files.
+	MaxSize int64
+	// MaxAge removes the intermediate files which were not used for longer.
+	MaxAge time.Duration
+}
+
+// CollectGarbage removes the least recently used intermediate files beyond the limits of cache.
+// The intermediate files of the run of this FileCreator are kept.
+func (f *FileCreator) CollectGarbage(cache Cache) error {
+	if cache == (Cache{}) {
+		return nil
+	}
+	removed, err := f.manifest.collectGarbage(f.cmdBuilder.tempDir, cache, f.created)
+	for _, path := range removed {
+		slog.Debug("removed", "path", path)
+	}
+	return errors.Join(err, f.manifest.save())
+}
+
+// CleanCache removes the least recently used intermediate files in tempDir beyond the limits of cache.
+// It returns the removed files.
+func CleanCache(tempDir string, cache Cache) ([]string, error) {
+	if _, err := os.Stat(tempDir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	m, err := loadManifest(tempDir, tempDir)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := m.collectGarbage(tempDir, cache, time.Now())
+	return removed, errors.Join(err, m.save())
+}
+
+// use marks the file at path as used, so the garbage collection keeps it longer.
+// A nil manifest marks nothing.
+func (m *manifest) use(path string) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	e, ok := m.entries[path]
+	if !ok {
+		return
+	}
+	e.Used = time.Now()
+	m.entries[path] = e
+}
+
+// collectGarbage removes the recorded files in dir which were not used within cache.MaxAge.
+// Then it removes the least recently used files until all files take at most cache.MaxSize bytes.
+// Files used since keepSince are kept.
+func (m *manifest) collectGarbage(dir string, cache Cache, keepSince time.Time) ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	type file struct {
+		path string
+		used time.Time
+		size int64
+	}
+	var files []file
+	var size int64
+	for p, e := range m.entries {
+		ok, err := inDir(p, dir)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			delete(m.entries, p)
+			continue
+		}
+		// Files are marked as used since the garbage collection exists.
+		used := e.Used
+		if e.ModTime.After(used) {
+			used = e.ModTime
+		}
+		files = append(files, file{path: p, used: used, size: info.Size()})
+		size += info.Size()
+	}
+	slices.SortFunc(files, func(a, b file) int {
+		return a.used.Compare(b.used)
+	})
+
+	var removed []string
+	for _, f := range files {
+		expired := cache.MaxAge > 0 && time.Since(f.used) > cache.MaxAge
+		tooLarge := cache.MaxSize > 0 && size > cache.MaxSize
+		// The files are sorted, so all following files are used later.
+		if !f.used.Before(keepSince) || !expired && !tooLarge {
+			break
+		}
+		err := os.Remove(f.path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+		delete(m.entries, f.path)
+		size -= f.size
+		removed = append(removed, f.path)
+	}
+	return removed, nil
+}
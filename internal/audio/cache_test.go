@@ -0,0 +1,66 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestManifest_CollectGarbage(t *testing.T) {
+	tempDir := t.TempDir()
+	now := time.Now()
+	// The files are 100 bytes each and were last used the given days ago.
+	files := map[string]int{
+		"old-0000001.wav":    40,
+		"older-0000002.wav":  50,
+		"recent-0000003.wav": 2,
+		"lru-0000004.wav":    10,
+		"used-0000005.wav":   20,
+	}
+	m, err := loadManifest(tempDir, tempDir)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	for name, days := range files {
+		p := filepath.Join(tempDir, name)
+		err = os.WriteFile(p, make([]byte, 100), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		mtime := now.Add(-time.Duration(days) * 24 * time.Hour)
+		err = os.Chtimes(p, mtime, mtime)
+		if err != nil {
+			t.Fatalf("failed to change mtime: %v", err)
+		}
+		m.add(p, "", "")
+	}
+	// The file is old but used by the current run.
+	keepSince := now.Add(-time.Minute)
+	m.use(filepath.Join(tempDir, "used-0000005.wav"))
+
+	removed, err := m.collectGarbage(tempDir, Cache{MaxSize: 250, MaxAge: 30 * 24 * time.Hour}, keepSince)
+	if err != nil {
+		t.Fatalf("collectGarbage(): %v", err)
+	}
+	// old and older are expired, lru is the least recently used file beyond the size.
+	want := []string{
+		filepath.Join(tempDir, "older-0000002.wav"),
+		filepath.Join(tempDir, "old-0000001.wav"),
+		filepath.Join(tempDir, "lru-0000004.wav"),
+	}
+	if !slices.Equal(removed, want) {
+		t.Fatalf("removed %v, want %v", removed, want)
+	}
+	for name := range files {
+		p := filepath.Join(tempDir, name)
+		_, err = os.Stat(p)
+		if gotExists, wantExists := err == nil, !slices.Contains(want, p); gotExists != wantExists {
+			t.Errorf("%s exists = %v, want %v", name, gotExists, wantExists)
+		}
+		if _, ok := m.entries[p]; ok != !slices.Contains(want, p) {
+			t.Errorf("%s in manifest = %v", name, ok)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/wav"
+)
+
+// Captions defines the caption files written next to every output file.
+type Captions struct {
+	// LRC writes a .lrc file for players with lyrics support.
+	LRC bool
+	// VTT writes a WebVTT .vtt file, e.g. for videos or screen readers.
+	VTT bool
+}
+
+// lrcTagReplacer replaces the bracket which ends an ID tag.
+var lrcTagReplacer = strings.NewReplacer("]", ")", "\n", " ")
+
+// vttTextReplacer escapes the characters of the cue text which start tags, entities or the cue timings.
+var vttTextReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// caption is a spoken text and its time span in the output file.
+type caption struct {
+	start time.Duration
+	end   time.Duration
+	text  string
+}
+
+// lrcPath returns the path of the LRC file of the output file.
+func lrcPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".lrc"
+}
+
+// vttPath returns the path of the WebVTT file of the output file.
+func vttPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".vtt"
+}
+
+// paths returns the paths of the caption files of the output file.
+func (c Captions) paths(outputPath string) []string {
+	var paths []string
+	if c.LRC {
+		paths = append(paths, lrcPath(outputPath))
+	}
+	if c.VTT {
+		paths = append(paths, vttPath(outputPath))
+	}
+	return paths
+}
+
+// writeCaptions writes the caption files of the output file.
+// The captions are only known after the creation of the segment wav files.
+func (f *FileCreator) writeCaptions(outputPath string, file File, duration time.Duration) error {
+	if !f.captions.LRC && !f.captions.VTT {
+		return nil
+	}
+	captions, err := f.segmentCaptions(file.Segments, 0)
+	if err != nil {
+		return err
+	}
+	if f.captions.LRC {
+		err = os.WriteFile(lrcPath(outputPath), []byte(lrc(file.Metadata, duration, captions)), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	if f.captions.VTT {
+		err = os.WriteFile(vttPath(outputPath), []byte(vtt(captions)), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// segmentCaptions returns the texts of the segments with their time spans beginning at start.
+func (f *FileCreator) segmentCaptions(segments []Segment, start time.Duration) ([]caption, error) {
+	var captions []caption
+	for _, s := range segments {
+		wavFile, ok := f.segmentWavs[s]
+		if !ok {
+			return nil, fmt.Errorf("no wav file of segment %T", s)
+		}
+		length, err := wav.FileDuration(filepath.Join(f.cmdBuilder.tempDir, wavFile))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", wavFile, err)
+		}
+		switch v := s.(type) {
+		case *Text:
+			if text := strings.TrimRight(strings.TrimSpace(v.Value), ", "); text != "" {
+				captions = append(captions, caption{
+					start: start,
+					end:   start + length,
+					text:  strings.ReplaceAll(text, "\n", " "),
+				})
+			}
+		case *Group:
+			groupCaptions, err := f.segmentCaptions(v.values(), start)
+			if err != nil {
+				return nil, err
+			}
+			captions = append(captions, groupCaptions...)
+		}
+		start += length
+	}
+	return captions, nil
+}
+
+// lrc returns the captions in the LRC format with a line per caption,
+// so players with lyrics support show what is said.
+func lrc(metadata Metadata, duration time.Duration, captions []caption) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[ti:%s]\n", lrcTagReplacer.Replace(metadata.Title))
+	fmt.Fprintf(&b, "[ar:%s]\n", lrcTagReplacer.Replace(metadata.Artist))
+	fmt.Fprintf(&b, "[al:%s]\n", lrcTagReplacer.Replace(metadata.Album))
+	fmt.Fprintf(&b, "[length:%02d:%02d]\n", int(duration.Minutes()), int(duration.Seconds())%60)
+	for _, c := range captions {
+		fmt.Fprintf(&b, "[%s]%s\n", lrcTime(c.start), c.text)
+	}
+	return b.String()
+}
+
+// lrcTime formats the duration as mm:ss.xx with xx in hundredths of a second.
+func lrcTime(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%02d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
+// vtt returns the captions in the WebVTT format with a cue per caption.
+func vtt(captions []caption) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for _, c := range captions {
+		fmt.Fprintf(&b, "\n%s --> %s\n%s\n", vttTime(c.start), vttTime(c.end), vttTextReplacer.Replace(c.text))
+	}
+	return b.String()
+}
+
+// vttTime formats the duration as hh:mm:ss.ttt.
+func vttTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,133 @@
+package audio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mrclmr/w2a/internal/m3u"
+)
+
+// ErrNotW2aDir is returned by CleanTemp and CleanOutput for a directory without the bookkeeping
+// files of w2a, so a mistyped directory is not cleaned.
+var ErrNotW2aDir = errors.New("not a directory of w2a")
+
+// CleanTemp removes the intermediate files recorded in the manifest of tempDir and the manifest.
+// Other files are kept. It returns the removed files. A missing tempDir is ignored.
+func CleanTemp(tempDir string) ([]string, error) {
+	manifestPath := filepath.Join(tempDir, manifestFile)
+	ok, err := bookkept(tempDir, manifestPath)
+	if !ok || err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var j manifestJSON
+	err = json.Unmarshal(data, &j)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", manifestPath, err)
+	}
+	var paths []string
+	for _, e := range j.Files {
+		ok, err := inDir(e.Path, tempDir)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			paths = append(paths, e.Path)
+		}
+	}
+	slices.Sort(paths)
+	return removeFiles(append(paths, manifestPath))
+}
+
+// CleanOutput removes the files of OutputFiles. Other files are kept.
+// It returns the removed files. A missing outputDir is ignored.
+func CleanOutput(outputDir string) ([]string, error) {
+	ok, err := bookkept(outputDir, filepath.Join(outputDir, outputsFile))
+	if !ok || err != nil {
+		return nil, err
+	}
+	paths, err := OutputFiles(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	return removeFiles(paths)
+}
+
+// bookkept reports whether dir exists. It fails if dir exists without the bookkeeping file at path.
+func bookkept(dir string, path string) (bool, error) {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("%w: %s has no %s", ErrNotW2aDir, dir, filepath.Base(path))
+	}
+	return err == nil, err
+}
+
+// outputPlaylists returns the playlists in outputDir whose entries are all outputs.
+// The outputs are absolute paths.
+func outputPlaylists(outputDir string, outputs map[string]bool) ([]string, error) {
+	playlists, err := filepath.Glob(filepath.Join(outputDir, "*.m3u"))
+	if err != nil {
+		return nil, err
+	}
+	absDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, p := range playlists {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := m3u.Read(f, absDir)
+		err = errors.Join(err, f.Close())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		if !slices.ContainsFunc(entries, func(e string) bool { return !outputs[filepath.Clean(e)] }) {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// inDir reports whether the file at path is in dir. Relative paths are
+// resolved from the working directory, so a manifest path written relative matches
+// an absolute dir and the other way around.
+func inDir(path string, dir string) (bool, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	return filepath.Dir(absPath) == absDir, nil
+}
+
+// removeFiles removes the files and returns the removed ones. Missing files are ignored.
+func removeFiles(paths []string) ([]string, error) {
+	var removed []string
+	for _, p := range paths {
+		err := os.Remove(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, p)
+	}
+	return removed, nil
+}
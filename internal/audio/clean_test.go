@@ -0,0 +1,139 @@
+package audio
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCleanTemp(t *testing.T) {
+	tempDir := t.TempDir()
+	m, err := loadManifest(tempDir, tempDir)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	recorded := filepath.Join(tempDir, "recorded-0000001.wav")
+	other := filepath.Join(tempDir, "other.wav")
+	for _, p := range []string{recorded, other} {
+		err = os.WriteFile(p, []byte("wav"), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	m.add(recorded, "", "")
+	err = m.save()
+	if err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	removed, err := CleanTemp(tempDir)
+	if err != nil {
+		t.Fatalf("failed to clean: %v", err)
+	}
+	want := []string{recorded, filepath.Join(tempDir, manifestFile)}
+	if !slices.Equal(removed, want) {
+		t.Fatalf("removed %v, want %v", removed, want)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("unrecorded file removed: %v", err)
+	}
+}
+
+func TestCleanOutput(t *testing.T) {
+	outputDir := t.TempDir()
+	files := map[string]string{
+		"workout-0000001.mp3": "mp3",
+		"workout-0000001.cue": "cue",
+		"workout.m3u":         "#EXTM3U\nworkout-0000001.mp3\n",
+		"mixed.m3u":           "#EXTM3U\nworkout-0000001.mp3\nmusic.mp3\n",
+		"music.mp3":           "mp3",
+		outputsFile:           `{"files": [{"file": "workout-0000001.mp3"}, {"file": "../music.mp3"}]}`,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(outputDir, name), []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	removed, err := CleanOutput(outputDir)
+	if err != nil {
+		t.Fatalf("failed to clean: %v", err)
+	}
+	var want []string
+	for _, name := range []string{"workout-0000001.cue", "workout-0000001.mp3", "workout.m3u", outputsFile} {
+		want = append(want, filepath.Join(outputDir, name))
+	}
+	if !slices.Equal(removed, want) {
+		t.Fatalf("removed %v, want %v", removed, want)
+	}
+	for _, name := range []string{"mixed.m3u", "music.mp3"} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Fatalf("file not created by w2a removed: %v", err)
+		}
+	}
+}
+
+func TestClean_NotW2aDir(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "document.txt"), []byte("text"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	for name, clean := range map[string]func(string) ([]string, error){
+		"temp":   CleanTemp,
+		"output": CleanOutput,
+	} {
+		t.Run(name, func(t *testing.T) {
+			removed, err := clean(dir)
+			if !errors.Is(err, ErrNotW2aDir) {
+				t.Fatalf("got error %v, want %v", err, ErrNotW2aDir)
+			}
+			if len(removed) > 0 {
+				t.Fatalf("removed %v", removed)
+			}
+			removed, err = clean(filepath.Join(dir, "missing"))
+			if err != nil || len(removed) > 0 {
+				t.Fatalf("missing dir: removed %v, err %v", removed, err)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(dir, "document.txt")); err != nil {
+		t.Fatalf("file removed: %v", err)
+	}
+}
+
+func TestCleanTemp_RelativeManifest(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	// The run used a relative temp dir, clean gets the absolute one.
+	err := os.Mkdir("temp", 0o700)
+	if err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	m, err := loadManifest("temp", "temp")
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	recorded := filepath.Join("temp", "recorded-0000001.wav")
+	err = os.WriteFile(recorded, []byte("wav"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	m.add(recorded, "", "")
+	err = m.save()
+	if err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	removed, err := CleanTemp(filepath.Join(dir, "temp"))
+	if err != nil {
+		t.Fatalf("failed to clean: %v", err)
+	}
+	want := []string{recorded, filepath.Join(dir, "temp", manifestFile)}
+	if !slices.Equal(removed, want) {
+		t.Fatalf("removed %v, want %v", removed, want)
+	}
+}
@@ -1,51 +1,75 @@
 package audio
 
 import (
-	"bytes"
+	"bufio"
 	"context"
-	"crypto/sha256"
-	"encoding/gob"
-	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/dag"
+	"github.com/mrclmr/w2a/internal/wav"
 )
 
 type cmd struct {
 	execCmdCtx ExecCmdCtx
+	retry      Retry
 	cmdStr     string
 	args       []string
 	outFile    string
-	hash       string
+	// outPath is the output file with directory. It is removed if the command fails.
+	outPath string
+	hash    string
+	// sum is the full digest the hash is shortened from.
+	sum string
 }
 
 func newCmd(
-	execCmdCtx ExecCmdCtx,
+	class cmdClass,
+	cmdStr string,
+	args []string,
+	hashLen int,
+) *cmd {
+	return newCmdWithDigest(class, cmdStr, args, digest(cmdStr, argsBasePath(args)), hashLen)
+}
+
+// newCmdWithDigest uses the passed digest instead of hashing the command and its arguments.
+func newCmdWithDigest(
+	class cmdClass,
 	cmdStr string,
 	args []string,
+	sum string,
+	hashLen int,
 ) *cmd {
-	argsReplaced, outFile, hash := replaceHash(cmdStr, args)
+	hash := sum[:hashLen]
+	argsReplaced, outPath := insertHash(args, hash)
 	return &cmd{
-		execCmdCtx: execCmdCtx,
+		execCmdCtx: class.execCmdCtx,
+		retry:      class.retry,
 		cmdStr:     cmdStr,
 		args:       argsReplaced,
-		outFile:    outFile,
+		outFile:    filepath.Base(outPath),
+		outPath:    outPath,
 		hash:       hash,
+		sum:        sum,
 	}
 }
 
-// replaceHash replaces <hash> with actual hash.
-func replaceHash(cmdStr string, argsOrig []string) (args []string, outFile string, hash string) {
-	h := hashShort(cmdStr, argsBasePath(argsOrig))
-
+// insertHash replaces <hash> with the passed hash.
+func insertHash(argsOrig []string, hash string) (args []string, outPath string) {
 	idx := slices.IndexFunc(argsOrig, func(arg string) bool { return strings.Contains(arg, "<hash>") })
 
-	filePathReplaced := strings.ReplaceAll(argsOrig[idx], "<hash>", h)
+	filePathReplaced := strings.ReplaceAll(argsOrig[idx], "<hash>", hash)
 	argsOrig[idx] = filePathReplaced
 
-	return argsOrig, filepath.Base(filePathReplaced), h
+	return argsOrig, filePathReplaced
 }
 
 // argsBasePath removes paths from file so hashing
@@ -73,46 +97,136 @@ func (c *cmd) Hash() string {
 	return c.hash
 }
 
+func (c *cmd) digest() string {
+	return c.sum
+}
+
+func (c *cmd) program() string {
+	return c.cmdStr
+}
+
+// Retry implements dag.Retrier with the retry of the command type.
+func (c *cmd) Retry() dag.Retry {
+	return c.retry
+}
+
 func (c *cmd) Name() string {
 	return c.cmdStr + " " + strings.Join(c.args, " ")
 }
 
 func (c *cmd) Run(ctx context.Context, _ []fileOperation) (fileOperation, error) {
-	command := c.execCmdCtx(ctx, c.cmdStr, c.args...)
+	// An existing output file is not trusted, e.g. because the run is forced.
+	// Commands like ffmpeg refuse to overwrite it.
+	removePartialFile(c.outPath)
+	// The command writes the temporary file which is renamed on success,
+	// so an interrupted command leaves no truncated output file.
+	args := slices.Clone(c.args)
+	idx := slices.Index(args, c.outPath)
+	if idx >= 0 {
+		args[idx] = TempPath(c.outPath)
+		removePartialFile(args[idx])
+	}
+	command := c.execCmdCtx(ctx, c.cmdStr, args...)
 	out, err := command.CombinedOutput()
 	if err != nil {
-		return 0, cmdError(c.cmdStr, c.args, out)
+		if idx >= 0 {
+			removePartialFile(args[idx])
+		}
+		removePartialFile(c.outPath)
+		return 0, cmdError(c.cmdStr, c.args, out, err)
+	}
+	if idx >= 0 {
+		// Commands which wrote the output file themselves leave no temporary file.
+		err = os.Rename(args[idx], c.outPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return 0, err
+		}
 	}
 	return created, nil
 }
 
-type noopNode struct {
-	outFile string
+// TempPath is the path a file is written to before it is renamed to path.
+// The extension is kept because commands like ffmpeg derive the format from it.
+func TempPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".tmp" + ext
 }
 
-func (n *noopNode) Hash() string {
-	return hashShort(n.outFile)
+// createFile writes the file at path with write. The file is written to
+// the temporary path and renamed to path on success, so an interrupted
+// run leaves no truncated file.
+func createFile(path string, write func(w io.Writer) error) (err error) {
+	tmp := TempPath(path)
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			removePartialFile(tmp)
+		}
+	}()
+	w := bufio.NewWriter(f)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
 }
 
-func (n *noopNode) Name() string {
-	return n.outFile
+// removePartialFile removes the output file of a failed or canceled node
+// so it is not mistaken for a complete file by the next run.
+// It also removes stale output files before a node runs.
+func removePartialFile(path string) {
+	err := os.Remove(path)
+	switch {
+	case err == nil:
+		slog.Debug("removed partial file", "path", path)
+	case !errors.Is(err, fs.ErrNotExist):
+		slog.Warn("failed to remove partial file\t", "path", path, "err", err)
+	}
+}
+
+type noopNode struct {
+	*dag.NodeFunc[fileOperation]
+	outFile string
 }
 
-func (n *noopNode) Run(_ context.Context, _ []fileOperation) (fileOperation, error) {
-	return noop, nil
+func newNoopNode(outFile string, hashLen int) *noopNode {
+	return &noopNode{
+		NodeFunc: dag.NewNodeFunc("noop "+outFile, digest(outFile)[:hashLen], func(_ context.Context, _ []fileOperation) (fileOperation, error) {
+			return noop, nil
+		}),
+		outFile: outFile,
+	}
 }
 
 func (n *noopNode) outputFile() string {
 	return n.outFile
 }
 
+// digest is unknown because the file is created by another node.
+func (n *noopNode) digest() string {
+	return ""
+}
+
 type copyNode struct {
 	srcPath string
 	dstPath string
+	hashLen int
 }
 
 func (c *copyNode) Hash() string {
-	return hashShort(c.srcPath, c.dstPath)
+	return digest(c.srcPath, c.dstPath)[:c.hashLen]
+}
+
+// digest is unknown because the copied file is created by another node.
+func (c *copyNode) digest() string {
+	return ""
 }
 
 func (c *copyNode) Name() string {
@@ -120,8 +234,9 @@ func (c *copyNode) Name() string {
 }
 
 func (c *copyNode) Run(_ context.Context, _ []fileOperation) (fileOperation, error) {
-	err := copyFile(c.srcPath, c.dstPath)
+	err := CopyFile(c.srcPath, c.dstPath)
 	if err != nil {
+		removePartialFile(c.dstPath)
 		return 0, err
 	}
 	return copied, nil
@@ -131,34 +246,209 @@ func (c *copyNode) outputFile() string {
 	return filepath.Base(c.dstPath)
 }
 
-func hashShort(str string, data ...any) string {
-	var buf bytes.Buffer
-	buf.WriteString(str)
-	enc := gob.NewEncoder(&buf)
-	for _, d := range data {
-		_ = enc.Encode(d)
+// silenceNode writes a silent wav file without calling an external command.
+type silenceNode struct {
+	dir      string
+	format   wav.Format
+	duration time.Duration
+	hashLen  int
+}
+
+func (s *silenceNode) Hash() string {
+	return s.digest()[:s.hashLen]
+}
+
+func (s *silenceNode) digest() string {
+	return digest("silence", s.duration.String(), s.format.SampleRate)
+}
+
+func (s *silenceNode) Name() string {
+	return strings.Join([]string{"silence", s.duration.String(), s.outputFile()}, " ")
+}
+
+func (s *silenceNode) Run(_ context.Context, _ []fileOperation) (fileOperation, error) {
+	err := createFile(filepath.Join(s.dir, s.outputFile()), func(w io.Writer) error {
+		return wav.WriteSilence(w, s.format, s.duration)
+	})
+	if err != nil {
+		return 0, err
 	}
-	h := sha256.Sum256(buf.Bytes())
-	return hex.EncodeToString(h[:4])[:7]
+	return created, nil
 }
 
-func copyFile(src, dst string) error {
-	fin, err := os.Open(src)
+func (s *silenceNode) outputFile() string {
+	return fmt.Sprintf("silence_%s-%s.wav", s.duration, s.Hash())
+}
+
+// toneNode writes a tone wav file without calling an external command.
+type toneNode struct {
+	dir     string
+	format  wav.Format
+	tone    *Tone
+	hashLen int
+}
+
+func (t *toneNode) Hash() string {
+	return t.digest()[:t.hashLen]
+}
+
+func (t *toneNode) digest() string {
+	return digest("tone", t.tone.Waveform.String(), t.tone.Frequency, t.tone.Length.String(), t.format.SampleRate)
+}
+
+func (t *toneNode) Name() string {
+	return strings.Join([]string{"tone", t.tone.Waveform.String(), t.outputFile()}, " ")
+}
+
+func (t *toneNode) Run(_ context.Context, _ []fileOperation) (fileOperation, error) {
+	err := createFile(filepath.Join(t.dir, t.outputFile()), func(w io.Writer) error {
+		return wav.WriteTone(w, t.format, t.tone.Length, t.tone.Frequency, t.tone.Waveform.oscillator())
+	})
 	if err != nil {
-		return err
+		return 0, err
+	}
+	return created, nil
+}
+
+func (t *toneNode) outputFile() string {
+	return fmt.Sprintf("tone_%gHz_%s-%s.wav", t.tone.Frequency, t.tone.Length, t.Hash())
+}
+
+// concatNode concatenates wav files without calling an external command.
+type concatNode struct {
+	dir        string
+	inputFiles []string
+	hashLen    int
+}
+
+func (c *concatNode) Hash() string {
+	return c.digest()[:c.hashLen]
+}
+
+func (c *concatNode) digest() string {
+	return digest("concat", strings.Join(c.inputFiles, "\n"))
+}
+
+func (c *concatNode) Name() string {
+	return strings.Join(append(append([]string{"concat"}, c.inputFiles...), c.outputFile()), " ")
+}
+
+func (c *concatNode) Run(_ context.Context, _ []fileOperation) (fileOperation, error) {
+	inputs := make([]io.ReadSeeker, len(c.inputFiles))
+	for i, name := range c.inputFiles {
+		fin, err := os.Open(filepath.Join(c.dir, name))
+		if err != nil {
+			return 0, err
+		}
+		defer func() {
+			_ = fin.Close()
+		}()
+		inputs[i] = fin
+	}
+
+	err := createFile(filepath.Join(c.dir, c.outputFile()), func(w io.Writer) error {
+		err := wav.Concat(w, inputs...)
+		if err != nil {
+			return fmt.Errorf("concat %s: %w", strings.Join(c.inputFiles, " "), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+func (c *concatNode) outputFile() string {
+	return fmt.Sprintf("concat-%s.wav", c.Hash())
+}
+
+// padNode extends a wav file with silence to a length without calling an external command.
+// The length of the input file is only known after its creation.
+type padNode struct {
+	dir       string
+	inputFile string
+	length    time.Duration
+	// cut cuts a longer wav file to the length.
+	cut     bool
+	hashLen int
+}
+
+func (p *padNode) Hash() string {
+	return p.digest()[:p.hashLen]
+}
+
+func (p *padNode) digest() string {
+	return digest(p.op(), p.inputFile, p.length.String())
+}
+
+// op is "pad" for wav files which are only extended and "fit" for wav files which are also cut.
+func (p *padNode) op() string {
+	if p.cut {
+		return "fit"
+	}
+	return "pad"
+}
+
+func (p *padNode) Name() string {
+	return strings.Join([]string{p.op(), p.inputFile, p.length.String(), p.outputFile()}, " ")
+}
+
+func (p *padNode) Run(_ context.Context, _ []fileOperation) (fileOperation, error) {
+	fin, err := os.Open(filepath.Join(p.dir, p.inputFile))
+	if err != nil {
+		return 0, err
 	}
 	defer func() {
 		_ = fin.Close()
 	}()
 
-	fout, err := os.Create(dst)
+	err = createFile(filepath.Join(p.dir, p.outputFile()), func(w io.Writer) error {
+		resize := wav.Pad
+		if p.cut {
+			resize = wav.Fit
+		}
+		err := resize(w, fin, p.length)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", p.op(), p.inputFile, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+func (p *padNode) outputFile() string {
+	ext := filepath.Ext(p.inputFile)
+	suffix := "extended"
+	if p.cut {
+		suffix = "fit"
+	}
+	return fmt.Sprintf("%s_%s-%s-%s%s", strings.TrimSuffix(p.inputFile, ext), suffix, p.length, p.Hash(), ext)
+}
+
+const (
+	// DefaultHashLength is the number of hex characters of the hashes in file names.
+	DefaultHashLength = 7
+	// MaxHashLength is the length of the full SHA-256 digest in hex characters.
+	MaxHashLength = 64
+)
+
+// CopyFile copies src to dst. Like createFile, it copies to the TempPath of dst
+// and renames it to dst, so an interrupted copy leaves no truncated dst.
+func CopyFile(src, dst string) error {
+	fin, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		_ = fout.Close()
+		_ = fin.Close()
 	}()
 
-	_, err = io.Copy(fout, fin)
-	return err
+	return createFile(dst, func(w io.Writer) error {
+		_, err := io.Copy(w, fin)
+		return err
+	})
 }
@@ -1,76 +1,144 @@
 package audio
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
+	"math"
+	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mrclmr/w2a/internal/wav"
 )
 
 type cmdBuilder struct {
 	fileCacheBuilder *fileCacheBuilder
-	execCmdCtx       ExecCmdCtx
+	ttsClass         cmdClass
+	soxClass         cmdClass
+	convertClass     cmdClass
 	tempDir          string
 	outputDir        string
 	tts              *TTS
 	audioFormat      Format
+	bitrate          string
+	sampleRate       int
+	channels         int
+	replayGain       bool
+	// hashLen is the number of hex characters of the hashes in file names.
+	hashLen int
+	// fileHashes caches the content hashes of the input files outside the temp dir by path.
+	fileHashes map[string]string
+	// ttsBatcher speaks the batchable texts in batches.
+	ttsBatcher *ttsBatcher
 }
 
+// newCmdBuilder builds the commands with the options. The hash length of opts must be set.
 func newCmdBuilder(
 	existingFilesMap map[string]map[string]bool,
-	execCmdCtx ExecCmdCtx,
-	tempDir string,
-	outputDir string,
-	tts *TTS,
-	audioFormat Format,
+	m *manifest,
+	opts Options,
 ) *cmdBuilder {
+	tts := opts.TTS
+	ttsClass := newCmdClass(ErrTTS, newLimiter(tts.MaxConcurrent, tts.RequestsPerSecond).limit(opts.ExecCmdCtx), opts.Retries.TTS)
 	return &cmdBuilder{
-		fileCacheBuilder: newFileCacheBuilder(existingFilesMap),
-		execCmdCtx:       execCmdCtx,
-		tempDir:          tempDir,
-		outputDir:        outputDir,
+		fileCacheBuilder: newFileCacheBuilder(existingFilesMap, m, opts.TempDir, opts.HashLength),
+		ttsClass:         ttsClass,
+		soxClass:         newCmdClass(ErrConversion, opts.ExecCmdCtx, opts.Retries.Sox),
+		convertClass:     newCmdClass(ErrConversion, opts.ExecCmdCtx, opts.Retries.Convert),
+		tempDir:          opts.TempDir,
+		outputDir:        opts.OutputDir,
 		tts:              tts,
-		audioFormat:      audioFormat,
+		audioFormat:      opts.Format,
+		bitrate:          cmp.Or(opts.Bitrate, opts.Format.defaultBitrate()),
+		sampleRate:       cmp.Or(opts.SampleRate, DefaultSampleRate),
+		channels:         cmp.Or(opts.Channels, 2),
+		replayGain:       opts.ReplayGain,
+		hashLen:          opts.HashLength,
+		fileHashes:       make(map[string]string),
+		ttsBatcher:       newTTSBatcher(ttsClass.execCmdCtx, opts.TempDir, opts.HashLength),
+	}
+}
+
+// fileHash returns the hash of the content of the file at path, so changed covers
+// or music files create the files again. It is cached because many files use the same input file.
+func (cb *cmdBuilder) fileHash(path string) (string, error) {
+	if h, ok := cb.fileHashes[path]; ok {
+		return h, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
 	}
+	h := digest("file", data)[:cb.hashLen]
+	cb.fileHashes[path] = h
+	return h, nil
 }
 
-func (cb *cmdBuilder) ttsCmd(text string) *fileCache {
-	switch cb.tts.TTSCmd {
+// ttsCmd returns the TTS command for text. A non-empty voice overrides the configured voice.
+func (cb *cmdBuilder) ttsCmd(text string, voice string) *fileCache {
+	tts := *cb.tts
+	if voice != "" {
+		tts.Voice = voice
+	}
+	cmdStr, args := ttsArgs(&tts)
+	switch tts.TTSCmd {
 	case Say:
-		return cb.fileCacheBuilder.cmd(
-			newCmd(
-				cb.execCmdCtx,
-				"say",
-				[]string{
-					// `--data-format=LEF32@22050` is needed for wav.
-					// https://stackoverflow.com/questions/9729153/error-on-say-when-output-format-is-wave
-					// The comments state that a sample rate higher than 22050 is not recommended.
-					"--data-format", "LEF32@22050",
-					"--voice", cb.tts.Voice,
-					"--output-file", filepath.Join(cb.tempDir, "say-<hash>.wav"),
-					text,
-				},
-			),
-		)
+		args = append(args, "--output-file", filepath.Join(cb.tempDir, "say-<hash>.wav"), text)
 	case EspeakNG:
-		return cb.fileCacheBuilder.cmd(
-			newCmd(
-				cb.execCmdCtx,
-				"espeak-ng",
-				[]string{
-					"-v", cb.tts.Voice,
-					"-out", filepath.Join(cb.tempDir, "espeak-ng-<hash>.wav"),
-					text,
-				},
-			),
-		)
+		args = append(args, "-out", filepath.Join(cb.tempDir, "espeak-ng-<hash>.wav"), text)
 	default:
+		return nil
+	}
+	c := newCmdWithDigest(cb.ttsClass, cmdStr, args, tts.digest(text), cb.hashLen)
+	if tts.batchable(text) {
+		return cb.fileCacheBuilder.fileCache(cb.ttsBatcher.add(c, tts, text))
+	}
+	return cb.fileCacheBuilder.cmd(c)
+}
+
+// ttsArgs returns the program of say or espeak-ng and the arguments without the output file and the text.
+func ttsArgs(tts *TTS) (string, []string) {
+	switch tts.TTSCmd {
+	case Say:
+		args := []string{
+			// `--data-format=LEF32@22050` is needed for wav.
+			// https://stackoverflow.com/questions/9729153/error-on-say-when-output-format-is-wave
+			// The comments state that a sample rate higher than 22050 is not recommended.
+			// The output is resampled if the pipeline uses another sample rate.
+			"--data-format", "LEF32@22050",
+			"--voice", tts.Voice,
+		}
+		if tts.Rate > 0 {
+			args = append(args, "--rate", strconv.Itoa(tts.Rate))
+		}
+		return "say", args
+	case EspeakNG:
+		voice := tts.Voice
+		if tts.ESpeakNG.Variant != "" {
+			voice += "+" + tts.ESpeakNG.Variant
+		}
+		args := []string{
+			"-v", voice,
+		}
+		if tts.Rate > 0 {
+			args = append(args, "-s", strconv.Itoa(tts.Rate))
+		}
+		if tts.ESpeakNG.Amplitude > 0 {
+			args = append(args, "-a", strconv.Itoa(tts.ESpeakNG.Amplitude))
+		}
+		if tts.ESpeakNG.WordGap > 0 {
+			args = append(args, "-g", strconv.Itoa(tts.ESpeakNG.WordGap))
+		}
+		return "espeak-ng", args
+	default:
+		return "", nil
 	}
-	return nil
 }
 
 type cmdErr struct {
@@ -81,135 +149,196 @@ func (c *cmdErr) CombinedOutput() ([]byte, error) {
 	return nil, c.err
 }
 
-func (cb *cmdBuilder) soxConcat(filenames []string) *fileCache {
-	for i := range filenames {
-		filenames[i] = filepath.Join(cb.tempDir, filenames[i])
-	}
+func (cb *cmdBuilder) concat(filenames []string) *fileCache {
+	return cb.fileCacheBuilder.concat(cb.tempDir, filenames)
+}
+
+func (cb *cmdBuilder) tone(tone *Tone) *fileCache {
+	return cb.fileCacheBuilder.tone(cb.tempDir, wav.Mono(cb.sampleRate), tone)
+}
+
+func (cb *cmdBuilder) silence(duration time.Duration) *fileCache {
+	return cb.fileCacheBuilder.silence(cb.tempDir, wav.Mono(cb.sampleRate), duration)
+}
+
+// soxResample converts TTS output with the default sample rate to the sample rate of the pipeline.
+func (cb *cmdBuilder) soxResample(inputFile string) *fileCache {
+	ext := filepath.Ext(inputFile)
+	nameNoExt := strings.TrimSuffix(inputFile, ext)
 	return cb.fileCacheBuilder.cmd(
 		newCmd(
-			cb.execCmdCtx,
+			cb.soxClass,
 			"sox_ng",
-			append(filenames, filepath.Join(cb.tempDir, "concat-<hash>.wav")),
+			[]string{
+				filepath.Join(cb.tempDir, inputFile),
+				"-r", strconv.Itoa(cb.sampleRate),
+				filepath.Join(cb.tempDir, fmt.Sprintf("%s_%dHz-<hash>%s", nameNoExt, cb.sampleRate, ext)),
+			},
+			cb.hashLen,
 		),
 	)
 }
 
-func (cb *cmdBuilder) soxSilence(duration time.Duration) *fileCache {
+// soxRecording converts a recorded wav file to the format of the TTS wav files.
+// The hash covers the file content so changed recordings are converted again.
+func (cb *cmdBuilder) soxRecording(path string) (*fileCache, error) {
+	return cb.soxImport(path, "recording")
+}
+
+// inPipelineFormat reports whether the wav file in the temp dir has the
+// sample rate and the channel count of the intermediate wav files.
+func (cb *cmdBuilder) inPipelineFormat(wavFile string) bool {
+	h, err := wav.FileHeader(filepath.Join(cb.tempDir, wavFile))
+	return err == nil && h.Format.SampleRate == cb.sampleRate && h.Format.Channels == 1
+}
+
+// soxSound converts a sound, e.g. a stereo 48 kHz file, to the format of the
+// intermediate wav files.
+func (cb *cmdBuilder) soxSound(path string) (*fileCache, error) {
+	return cb.soxImport(path, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+}
+
+// soxImport converts a wav file outside the temp dir to the pipeline format.
+// The hash covers the file content so changed files are converted again.
+func (cb *cmdBuilder) soxImport(path string, name string) (*fileCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
 	return cb.fileCacheBuilder.cmd(
-		newCmd(
-			func(ctx context.Context, name string, args ...string) Cmd {
-				if duration <= 0 {
-					return &cmdErr{errors.New("negative or zero duration for silence")}
-				}
-				return cb.execCmdCtx(ctx, name, args...)
+		newCmdWithDigest(
+			cb.soxClass,
+			"sox_ng",
+			[]string{
+				path,
+				"-r", strconv.Itoa(cb.sampleRate),
+				"-c", "1",
+				filepath.Join(cb.tempDir, name+"-<hash>.wav"),
 			},
+			digest(name, data),
+			cb.hashLen,
+		),
+	), nil
+}
+
+// soxTempo changes the tempo of speech without changing the pitch.
+func (cb *cmdBuilder) soxTempo(inputFile string, tempo float64) *fileCache {
+	ext := filepath.Ext(inputFile)
+	nameNoExt := strings.TrimSuffix(inputFile, ext)
+	return cb.fileCacheBuilder.cmd(
+		newCmd(
+			cb.soxClass,
 			"sox_ng",
 			[]string{
-				"-n",
-				"-r",
-				"22050",
-				filepath.Join(cb.tempDir, fmt.Sprintf("silence_%s-<hash>.wav", duration)),
-				"trim",
-				"0.0",
-				fmt.Sprintf("%.2f", duration.Seconds()),
+				filepath.Join(cb.tempDir, inputFile),
+				filepath.Join(cb.tempDir, fmt.Sprintf("%s_tempo-%g-<hash>%s", nameNoExt, tempo, ext)),
+				// -s optimizes the algorithm for speech.
+				"tempo", "-s", fmt.Sprintf("%g", tempo),
 			},
+			cb.hashLen,
 		),
 	)
 }
 
-type cmdNoop struct{}
-
-func (c *cmdNoop) CombinedOutput() ([]byte, error) {
-	return nil, nil
+// ffmpegLoudnorm normalizes the loudness according to EBU R128.
+// The true peak is limited to -1.5 dBTP to avoid clipping after lossy encoding.
+func (cb *cmdBuilder) ffmpegLoudnorm(inputFile string, target float64) *fileCache {
+	return cb.fileCacheBuilder.cmd(
+		newCmd(
+			cb.convertClass,
+			"ffmpeg",
+			[]string{
+				"-i", filepath.Join(cb.tempDir, inputFile),
+				"-af", fmt.Sprintf("loudnorm=I=%g:TP=-1.5:LRA=11", target),
+				// loudnorm upsamples to 192 kHz.
+				"-ar", strconv.Itoa(cb.sampleRate),
+				filepath.Join(cb.tempDir, "loudnorm-<hash>.wav"),
+			},
+			cb.hashLen,
+		),
+	)
 }
 
-// soxExtendLength needs a specific implementation.
-// If nothing is to do reading every file consumes time (~1s vs. instant).
-// This code is ugly and needs refactoring.
-func (cb *cmdBuilder) soxExtendLength(inputFile string, extendedLength time.Duration) *fileCache {
-	if extendedLength <= 0 {
-		return cb.fileCacheBuilder.noop(inputFile)
+// ffmpegMixMusic mixes looped music under the input file. The output has the length of the input file.
+func (cb *cmdBuilder) ffmpegMixMusic(inputFile string, music string, bgMusic *BackgroundMusic) (*fileCache, error) {
+	musicHash, err := cb.fileHash(music)
+	if err != nil {
+		return nil, fmt.Errorf("background music: %w", err)
 	}
-	ext := filepath.Ext(inputFile)
-	nameNoExt := strings.TrimSuffix(inputFile, ext)
-	filePadded := fmt.Sprintf("%s_extended-%s-<hash>%s", nameNoExt, extendedLength, ext)
-	filePaddedPath := filepath.Join(cb.tempDir, filePadded)
-	inputFilePath := filepath.Join(cb.tempDir, inputFile)
-	cmdStr := "sox_ng"
+	filter := fmt.Sprintf("[1:a]aresample=%d,aformat=channel_layouts=mono,volume=%g[music];", cb.sampleRate, bgMusic.Volume)
+	if bgMusic.Ducking {
+		filter += "[0:a]asplit=2[voice][sidechain];" +
+			"[music][sidechain]sidechaincompress=threshold=0.02:ratio=8:attack=20:release=400[ducked];" +
+			"[voice][ducked]"
+	} else {
+		filter += "[0:a][music]"
+	}
+	filter += "amix=inputs=2:duration=first:dropout_transition=0:normalize=0"
 	args := []string{
-		inputFilePath,
-		filePaddedPath,
-		// The calculated length argument is inserted as last.
-		"pad", "0",
+		"-i", filepath.Join(cb.tempDir, inputFile),
+		"-stream_loop", "-1",
+		"-i", music,
+		"-filter_complex", filter,
+		"-ar", strconv.Itoa(cb.sampleRate),
+		"-ac", "1",
+		filepath.Join(cb.tempDir, "music-<hash>.wav"),
 	}
-	args, outFile, hash := replaceHash(cmdStr, args)
-
-	filePaddedPath = filepath.Join(cb.tempDir, outFile)
-
 	return cb.fileCacheBuilder.cmd(
-		&cmd{
-			execCmdCtx: func(ctx context.Context, name string, args ...string) Cmd {
-				arguments := []string{"--i", "-D", inputFilePath}
-
-				slog.Debug("execute", "cmd", strings.Join(append([]string{cmdStr}, arguments...), " "))
-				out, err := cb.execCmdCtx(
-					ctx,
-					cmdStr,
-					arguments...,
-				).CombinedOutput()
-				if err != nil {
-					return &cmdErr{err: cmdError(cmdStr, arguments, out)}
-				}
-
-				var float float64
-				for l := range strings.Lines(string(out)) {
-					float, err = strconv.ParseFloat(strings.TrimSuffix(l, "\n"), 64)
-					if err == nil {
-						break
-					}
-				}
-				if err != nil {
-					return &cmdErr{err: fmt.Errorf("%w: no parsable float in\n%s", err, string(out))}
-				}
-
-				length := time.Duration(float * float64(time.Second))
-				addLength := extendedLength - length
-				if addLength <= 0 {
-					err = copyFile(inputFilePath, filePaddedPath)
-					if err != nil {
-						return &cmdErr{err: err}
-					}
-					return &cmdNoop{}
-				}
+		newCmdWithDigest(
+			cb.convertClass,
+			"ffmpeg",
+			args,
+			digest("ffmpeg", argsBasePath(args), musicHash),
+			cb.hashLen,
+		),
+	), nil
+}
 
-				args = append(args, fmt.Sprintf("%f", addLength.Seconds()))
-				slog.Debug("execute", "cmd", strings.Join(append([]string{cmdStr}, args...), " "))
+// extendLength extends the input file with silence to extendedLength.
+func (cb *cmdBuilder) extendLength(inputFile string, extendedLength time.Duration) *fileCache {
+	if extendedLength <= 0 {
+		return cb.fileCacheBuilder.noop(inputFile)
+	}
+	return cb.fileCacheBuilder.pad(cb.tempDir, inputFile, extendedLength, false)
+}
 
-				return cb.execCmdCtx(
-					ctx,
-					name,
-					args...,
-				)
-			},
-			cmdStr:  cmdStr,
-			args:    args,
-			outFile: outFile,
-			hash:    hash,
-		},
-	)
+// fitLength extends the input file with silence or cuts it to length.
+func (cb *cmdBuilder) fitLength(inputFile string, length time.Duration) *fileCache {
+	if length <= 0 {
+		return cb.fileCacheBuilder.noop(inputFile)
+	}
+	return cb.fileCacheBuilder.pad(cb.tempDir, inputFile, length, true)
 }
 
 func (cb *cmdBuilder) copy(srcPath string, dstPath string) (fileOperation, node, error) {
 	return cb.fileCacheBuilder.copy(srcPath, dstPath)
 }
 
-func (cb *cmdBuilder) convert(wavFile string, name string) (fileOperation, node, error) {
+func (cb *cmdBuilder) convert(wavFile string, name string, metadata Metadata, chapters []chapter) (fileOperation, node, error) {
 	switch cb.audioFormat {
 	case Wav:
-		return cb.fileCacheBuilder.copy(wavFile, name+".wav")
+		// The hash of the wav file name covers the content like the hash of the converted files.
+		return cb.fileCacheBuilder.copy(
+			filepath.Join(cb.tempDir, wavFile),
+			filepath.Join(cb.outputDir, name+"-"+digest("wav", wavFile)[:cb.hashLen]+".wav"),
+		)
 	case M4a:
+		if !afconvertM4a(runtime.GOOS, metadata, cb.replayGain) {
+			return cb.ffmpegConvert(
+				wavFile,
+				metadata.Cover,
+				[]string{
+					"-c:a", "aac", "-b:a", cb.bitrate, "-ar", cb.outputSampleRate(), "-ac", cb.channelsArg(),
+					// The edit list trims the AAC priming samples for gapless playback.
+					"-use_editlist", "1",
+					"-movflags", cb.movflags(),
+				},
+				metadata,
+				filepath.Join(cb.outputDir, name+"-<hash>.m4a"),
+			)
+		}
 		return cb.fileCacheBuilder.convert(
-			cb.execCmdCtx,
+			cb.convertClass,
 			"afconvert",
 			[]string{
 				// For macOS Music App (iTunes) compatibility use m4af
@@ -219,30 +348,321 @@ func (cb *cmdBuilder) convert(wavFile string, name string) (fileOperation, node,
 				"--data", "aac",
 				"--quality", "127",
 				"--strategy", "2",
+				"--channels", cb.channelsArg(),
 				filepath.Join(cb.tempDir, wavFile),
 				filepath.Join(cb.outputDir, name+"-<hash>.m4a"),
 			},
 		)
 	case Mp3:
-		return cb.fileCacheBuilder.convert(
-			cb.execCmdCtx,
-			"ffmpeg",
+		return cb.ffmpegConvert(
+			wavFile,
+			metadata.Cover,
 			[]string{
-				"-i",
-				filepath.Join(cb.tempDir, wavFile),
-				"-ab", "256k", "-ar", "44100", "-ac", "2",
-				filepath.Join(cb.outputDir, name+"-<hash>.mp3"),
+				"-ab", cb.bitrate, "-ar", cb.outputSampleRate(), "-ac", cb.channelsArg(),
+				"-id3v2_version", "3",
+				// The Xing/LAME header holds the encoder delay and padding for gapless playback.
+				"-write_xing", "1",
 			},
+			metadata,
+			filepath.Join(cb.outputDir, name+"-<hash>.mp3"),
+		)
+	case Opus:
+		return cb.ffmpegConvert(
+			wavFile,
+			// Cover art in Ogg containers is not supported by ffmpeg.
+			"",
+			[]string{
+				// libopus resamples to 48000 which is the only full band sample rate.
+				// Opus and Vorbis are gapless because Ogg stores the exact sample positions.
+				"-c:a", "libopus", "-b:a", cb.bitrate, "-application", "voip", "-ac", cb.channelsArg(),
+			},
+			metadata,
+			filepath.Join(cb.outputDir, name+"-<hash>.opus"),
+		)
+	case Ogg:
+		return cb.ffmpegConvert(
+			wavFile,
+			"",
+			[]string{
+				"-c:a", "libvorbis", "-b:a", cb.bitrate, "-ar", cb.outputSampleRate(), "-ac", cb.channelsArg(),
+			},
+			metadata,
+			filepath.Join(cb.outputDir, name+"-<hash>.ogg"),
+		)
+	case M4b:
+		return cb.ffmpegConvertM4b(
+			wavFile,
+			metadata,
+			chapters,
+			filepath.Join(cb.outputDir, name+"-<hash>.m4b"),
 		)
 	default:
 		return 0, nil, errors.New("unsupported audio format")
 	}
 }
 
-func cmdError(cmd string, args []string, out []byte) error {
-	return fmt.Errorf("err: %s %s\n%s",
-		cmd,
-		strings.Join(args, " "),
-		strings.SplitN(string(out), "\n", 1)[0],
+// afconvertM4a reports whether m4a files are converted with afconvert, which is only
+// available on macOS. afconvert writes no tags and no cover, so files with tags,
+// a cover or ReplayGain are converted with ffmpeg.
+func afconvertM4a(goos string, metadata Metadata, replayGain bool) bool {
+	return goos == "darwin" && metadata == (Metadata{}) && !replayGain
+}
+
+// outputSampleRate is at least 44100 for the compatibility with players.
+func (cb *cmdBuilder) outputSampleRate() string {
+	return strconv.Itoa(max(44100, cb.sampleRate))
+}
+
+// channelsArg is the channel count of the output files. The intermediate wav files are mono.
+func (cb *cmdBuilder) channelsArg() string {
+	return strconv.Itoa(cb.channels)
+}
+
+// chapter is a chapter marker in m4b files. The chapter lasts as long as its wav file.
+type chapter struct {
+	title   string
+	wavFile string
+}
+
+// ffmpegConvertM4b converts the wav file to an aac audiobook with chapter markers.
+// The chapter start times are only known after the creation of the chapter wav files.
+// Therefore, the ffmetadata file with the chapters is written right before ffmpeg runs.
+func (cb *cmdBuilder) ffmpegConvertM4b(
+	wavFile string,
+	metadata Metadata,
+	chapters []chapter,
+	outputFile string,
+) (fileOperation, node, error) {
+	var b strings.Builder
+	for _, c := range chapters {
+		b.WriteString(c.title + "\n" + c.wavFile + "\n")
+	}
+	chaptersFile := filepath.Join(cb.tempDir, "chapters-"+digest("chapters", b.String())[:cb.hashLen]+".txt")
+
+	args := []string{
+		"-i", filepath.Join(cb.tempDir, wavFile),
+		"-i", chaptersFile,
+	}
+	var inputHashes []string
+	if metadata.Cover != "" {
+		coverHash, err := cb.fileHash(metadata.Cover)
+		if err != nil {
+			return 0, nil, fmt.Errorf("cover: %w", err)
+		}
+		inputHashes = append(inputHashes, coverHash)
+		args = append(args,
+			"-i", metadata.Cover,
+			"-map", "0:a", "-map", "2:v",
+			"-c:v", "copy", "-disposition:v", "attached_pic",
+		)
+	}
+	args = append(args,
+		"-map_metadata", "1", "-map_chapters", "1",
+		"-c:a", "aac", "-b:a", cb.bitrate, "-ar", cb.outputSampleRate(), "-ac", cb.channelsArg(),
+		"-use_editlist", "1",
+		"-movflags", cb.movflags(),
+	)
+
+	convert := cb.withReplayGain(cb.convertClass, wavFile)
+	return cb.fileCacheBuilder.convert(
+		cmdClass{
+			execCmdCtx: func(ctx context.Context, name string, args ...string) Cmd {
+				err := cb.writeChapters(chaptersFile, chapters)
+				if err != nil {
+					return &cmdErr{err: err}
+				}
+				return convert.execCmdCtx(ctx, name, args...)
+			},
+			retry: convert.retry,
+		},
+		"ffmpeg",
+		slices.Concat(args, metadata.ffmpegArgs(), cb.replayGainArgs(), []string{outputFile}),
+		inputHashes...,
+	)
+}
+
+var ffmetadataEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"=", "\\=",
+	";", "\\;",
+	"#", "\\#",
+	"\n", "\\\n",
+)
+
+// writeChapters writes the chapters in the ffmetadata format.
+func (cb *cmdBuilder) writeChapters(path string, chapters []chapter) error {
+	lengths, err := cb.chapterLengths(chapters)
+	if err != nil {
+		return err
+	}
+	titles := make([]string, 0, len(chapters))
+	for _, c := range chapters {
+		titles = append(titles, c.title)
+	}
+	return os.WriteFile(path, []byte(ffmetadata(titles, lengths)), 0o600)
+}
+
+// chapterLengths returns the lengths of the chapter wav files.
+func (cb *cmdBuilder) chapterLengths(chapters []chapter) ([]time.Duration, error) {
+	lengths := make([]time.Duration, 0, len(chapters))
+	for _, c := range chapters {
+		length, err := wav.FileDuration(filepath.Join(cb.tempDir, c.wavFile))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.wavFile, err)
+		}
+		lengths = append(lengths, length)
+	}
+	return lengths, nil
+}
+
+// ffmetadata returns the chapters with the titles and lengths in the ffmetadata format.
+func ffmetadata(titles []string, lengths []time.Duration) string {
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	var start time.Duration
+	for i, title := range titles {
+		end := start + lengths[i]
+		fmt.Fprintf(&b, "[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			start.Milliseconds(),
+			end.Milliseconds(),
+			ffmetadataEscaper.Replace(title),
+		)
+		start = end
+	}
+	return b.String()
+}
+
+// ffmpegConvert converts the wav file with the codec arguments and writes the metadata.
+// A non-empty cover image is embedded as album art.
+func (cb *cmdBuilder) ffmpegConvert(
+	wavFile string,
+	cover string,
+	codecArgs []string,
+	metadata Metadata,
+	outputFile string,
+) (fileOperation, node, error) {
+	args := []string{"-i", filepath.Join(cb.tempDir, wavFile)}
+	var inputHashes []string
+	if cover != "" {
+		coverHash, err := cb.fileHash(cover)
+		if err != nil {
+			return 0, nil, fmt.Errorf("cover: %w", err)
+		}
+		inputHashes = append(inputHashes, coverHash)
+		args = append(args,
+			"-i", cover,
+			"-map", "0:a", "-map", "1:v",
+			"-c:v", "copy", "-disposition:v", "attached_pic",
+		)
+	}
+	return cb.fileCacheBuilder.convert(
+		cb.withReplayGain(cb.convertClass, wavFile),
+		"ffmpeg",
+		slices.Concat(args, codecArgs, metadata.ffmpegArgs(), cb.replayGainArgs(), []string{outputFile}),
+		inputHashes...,
 	)
 }
+
+// movflags moves the index to the beginning of mp4 files for faster playback start.
+// Tags unknown to the mp4 muxer, like ReplayGain, are only written with use_metadata_tags.
+func (cb *cmdBuilder) movflags() string {
+	if cb.replayGain {
+		return "+faststart+use_metadata_tags"
+	}
+	return "+faststart"
+}
+
+const (
+	replayGainGain = "<replaygain_gain>"
+	replayGainPeak = "<replaygain_peak>"
+)
+
+// replayGainArgs returns the ReplayGain tags with placeholders for the values.
+func (cb *cmdBuilder) replayGainArgs() []string {
+	if !cb.replayGain {
+		return nil
+	}
+	return []string{
+		"-metadata", "REPLAYGAIN_TRACK_GAIN=" + replayGainGain,
+		"-metadata", "REPLAYGAIN_TRACK_PEAK=" + replayGainPeak,
+	}
+}
+
+// withReplayGain replaces the ReplayGain placeholders with the values of the wav file.
+// The loudness is only known after the creation of the wav file.
+// The tags are removed for silent files.
+func (cb *cmdBuilder) withReplayGain(class cmdClass, wavFile string) cmdClass {
+	if !cb.replayGain {
+		return class
+	}
+	execCmdCtx := class.execCmdCtx
+	class.execCmdCtx = func(ctx context.Context, name string, args ...string) Cmd {
+		l, err := wav.FileLoudness(filepath.Join(cb.tempDir, wavFile))
+		if err != nil {
+			return &cmdErr{err: fmt.Errorf("%s: %w", wavFile, err)}
+		}
+		replaced := make([]string, 0, len(args))
+		for _, arg := range args {
+			if strings.Contains(arg, replayGainGain) || strings.Contains(arg, replayGainPeak) {
+				if math.IsInf(l.Integrated, -1) {
+					// Remove the preceding -metadata.
+					replaced = replaced[:len(replaced)-1]
+					continue
+				}
+				arg = strings.ReplaceAll(arg, replayGainGain, fmt.Sprintf("%.2f dB", replayGainReference-l.Integrated))
+				arg = strings.ReplaceAll(arg, replayGainPeak, fmt.Sprintf("%.6f", l.Peak))
+			}
+			replaced = append(replaced, arg)
+		}
+		return execCmdCtx(ctx, name, replaced...)
+	}
+	return class
+}
+
+// replayGainReference is the loudness in LUFS of ReplayGain 2.0.
+const replayGainReference = -18
+
+// ffprobeDuration returns the duration of any audio file ffmpeg can read.
+func (cb *cmdBuilder) ffprobeDuration(ctx context.Context, path string) (time.Duration, error) {
+	args := []string{
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	}
+	out, err := cb.convertClass.execCmdCtx(ctx, "ffprobe", args...).CombinedOutput()
+	if err != nil {
+		return 0, cmdError("ffprobe", args, out, err)
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe %s: %w", path, err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// cmdError returns the error of a failed command with its output.
+// It wraps err, e.g. to detect a missing program.
+func cmdError(cmd string, args []string, out []byte, err error) error {
+	return &execError{
+		msg: fmt.Sprintf("err: %s %s\n%s",
+			cmd,
+			strings.Join(args, " "),
+			strings.SplitN(string(out), "\n", 1)[0],
+		),
+		err: err,
+	}
+}
+
+type execError struct {
+	msg string
+	err error
+}
+
+func (e *execError) Error() string {
+	return e.msg
+}
+
+func (e *execError) Unwrap() error {
+	return e.err
+}
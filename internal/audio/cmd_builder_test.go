@@ -0,0 +1,183 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCmdBuilder_ttsCmdHash(t *testing.T) {
+	base := TTS{TTSCmd: EspeakNG, Voice: "en-gb"}
+	tests := []struct {
+		name     string
+		tts      TTS
+		text     string
+		voice    string
+		wantSame bool
+	}{
+		{
+			name:     "same settings",
+			tts:      base,
+			text:     "text",
+			wantSame: true,
+		},
+		{
+			name: "different voice",
+			tts:  TTS{TTSCmd: EspeakNG, Voice: "de"},
+			text: "text",
+		},
+		{
+			name:     "same voice override",
+			tts:      base,
+			text:     "text",
+			voice:    "en-gb",
+			wantSame: true,
+		},
+		{
+			name:  "different voice override",
+			tts:   base,
+			text:  "text",
+			voice: "de",
+		},
+		{
+			name: "different engine",
+			tts:  TTS{TTSCmd: Say, Voice: "en-gb"},
+			text: "text",
+		},
+		{
+			name: "different rate",
+			tts:  TTS{TTSCmd: EspeakNG, Voice: "en-gb", Rate: 200},
+			text: "text",
+		},
+		{
+			name: "different text",
+			tts:  base,
+			text: "other text",
+		},
+	}
+	want := newCmdBuilder(nil, nil, Options{TTS: &base, Format: Wav, TempDir: tempDir, OutputDir: outputDir, HashLength: DefaultHashLength}).ttsCmd("text", "").Hash()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newCmdBuilder(nil, nil, Options{TTS: &tt.tts, Format: Wav, TempDir: tempDir, OutputDir: outputDir, HashLength: DefaultHashLength}).ttsCmd(tt.text, tt.voice).Hash()
+			if (got == want) != tt.wantSame {
+				t.Fatalf("ttsCmd().Hash() = %s, base hash %s, want same: %v", got, want, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestCmdBuilder_convertOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.jpg")
+	otherCover := filepath.Join(dir, "other", "cover.jpg")
+	err := os.MkdirAll(filepath.Dir(otherCover), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(cover, []byte("cover"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(otherCover, []byte("other cover"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tts := &TTS{TTSCmd: EspeakNG, Voice: "en-gb"}
+	tests := []struct {
+		name     string
+		format   Format
+		bitrate  string
+		wavFile  string
+		cover    string
+		wantSame bool
+	}{
+		{
+			name:     "same settings",
+			format:   Mp3,
+			bitrate:  "256k",
+			wavFile:  "concat-1234567.wav",
+			cover:    cover,
+			wantSame: true,
+		},
+		{
+			name:    "different format",
+			format:  Ogg,
+			bitrate: "256k",
+			wavFile: "concat-1234567.wav",
+			cover:   cover,
+		},
+		{
+			name:    "different bitrate",
+			format:  Mp3,
+			bitrate: "128k",
+			wavFile: "concat-1234567.wav",
+			cover:   cover,
+		},
+		{
+			name:    "different wav file",
+			format:  Mp3,
+			bitrate: "256k",
+			wavFile: "concat-7654321.wav",
+			cover:   cover,
+		},
+		{
+			name:    "different cover content with the same file name",
+			format:  Mp3,
+			bitrate: "256k",
+			wavFile: "concat-1234567.wav",
+			cover:   otherCover,
+		},
+	}
+	outputFile := func(format Format, bitrate string, wavFile string, cover string) string {
+		cb := newCmdBuilder(nil, nil, Options{TTS: tts, Format: format, Bitrate: bitrate, TempDir: tempDir, OutputDir: outputDir, HashLength: DefaultHashLength})
+		cb.fileCacheBuilder.dryRun = true
+		_, n, err := cb.convert(wavFile, "file", Metadata{Cover: cover}, nil)
+		if err != nil {
+			t.Fatalf("convert(): %v", err)
+		}
+		return n.outputFile()
+	}
+	want := outputFile(Mp3, "256k", "concat-1234567.wav", cover)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := outputFile(tt.format, tt.bitrate, tt.wavFile, tt.cover)
+			if (got == want) != tt.wantSame {
+				t.Fatalf("convert() output file = %s, base %s, want same: %v", got, want, tt.wantSame)
+			}
+		})
+	}
+
+	wavFiles := make(map[string]bool)
+	for _, wavFile := range []string{"concat-1234567.wav", "concat-7654321.wav"} {
+		got := outputFile(Wav, "", wavFile, "")
+		if !hashSuffixReg.MatchString(strings.TrimSuffix(got, ".wav")) || wavFiles[got] {
+			t.Fatalf("convert() of wav output file = %s, want a different hash per wav file", got)
+		}
+		wavFiles[got] = true
+	}
+}
+
+func TestAfconvertM4a(t *testing.T) {
+	tests := []struct {
+		name       string
+		goos       string
+		metadata   Metadata
+		replayGain bool
+		want       bool
+	}{
+		{name: "macOS", goos: "darwin", want: true},
+		{name: "linux", goos: "linux"},
+		{name: "macOS with title", goos: "darwin", metadata: Metadata{Title: "Title"}},
+		{name: "macOS with cover", goos: "darwin", metadata: Metadata{Cover: "cover.jpg"}},
+		{name: "macOS with replay gain", goos: "darwin", replayGain: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := afconvertM4a(tt.goos, tt.metadata, tt.replayGain)
+			if got != tt.want {
+				t.Errorf("afconvertM4a() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTTS is wrapped by the errors of failed TTS commands.
+	ErrTTS = errors.New("tts command failed")
+	// ErrConversion is wrapped by the errors of failed sox and ffmpeg commands.
+	ErrConversion = errors.New("conversion command failed")
+)
+
+// classify wraps execCmdCtx so that the errors of the commands wrap class.
+func classify(class error, execCmdCtx ExecCmdCtx) ExecCmdCtx {
+	return func(ctx context.Context, name string, args ...string) Cmd {
+		return &classifiedCmd{
+			class: class,
+			cmd:   execCmdCtx(ctx, name, args...),
+		}
+	}
+}
+
+type classifiedCmd struct {
+	class error
+	cmd   Cmd
+}
+
+func (c *classifiedCmd) CombinedOutput() ([]byte, error) {
+	out, err := c.cmd.CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("%w: %w", c.class, err)
+	}
+	return out, nil
+}
+
+// cmdClass holds how the commands of a type, e.g. the TTS commands, are executed and retried.
+type cmdClass struct {
+	execCmdCtx ExecCmdCtx
+	retry      Retry
+}
+
+// newCmdClass classifies the errors of the commands with class. Only these errors are
+// retried, so e.g. a failed rename of the output file is not.
+func newCmdClass(class error, execCmdCtx ExecCmdCtx, retry Retry) cmdClass {
+	retry.Retryable = func(err error) bool {
+		return errors.Is(err, class)
+	}
+	return cmdClass{
+		execCmdCtx: classify(class, execCmdCtx),
+		retry:      retry,
+	}
+}
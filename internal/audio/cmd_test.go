@@ -0,0 +1,121 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/wav"
+)
+
+type partialFileCmd struct {
+	path string
+}
+
+func (c partialFileCmd) CombinedOutput() ([]byte, error) {
+	err := os.WriteFile(c.path, []byte("partial"), 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return []byte("interrupted"), errors.New("signal: killed")
+}
+
+func TestCmd_RunRemovesPartialFile(t *testing.T) {
+	dir := t.TempDir()
+	c := newCmd(
+		cmdClass{execCmdCtx: func(_ context.Context, _ string, args ...string) Cmd {
+			return partialFileCmd{path: args[len(args)-1]}
+		}},
+		"sox_ng",
+		[]string{"in.wav", filepath.Join(dir, "out-<hash>.wav")},
+		DefaultHashLength,
+	)
+	_, err := c.Run(t.Context(), nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err = os.Stat(filepath.Join(dir, c.outputFile())); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("partial file not removed: %v", err)
+	}
+	if _, err = os.Stat(TempPath(filepath.Join(dir, c.outputFile()))); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("temporary file not removed: %v", err)
+	}
+}
+
+type writeFileCmd struct {
+	path string
+}
+
+func (c writeFileCmd) CombinedOutput() ([]byte, error) {
+	return nil, os.WriteFile(c.path, []byte("complete"), 0o600)
+}
+
+func TestCmd_RunRenamesTempFile(t *testing.T) {
+	dir := t.TempDir()
+	var written string
+	c := newCmd(
+		cmdClass{execCmdCtx: func(_ context.Context, _ string, args ...string) Cmd {
+			written = args[len(args)-1]
+			return writeFileCmd{path: written}
+		}},
+		"sox_ng",
+		[]string{"in.wav", filepath.Join(dir, "out-<hash>.wav")},
+		DefaultHashLength,
+	)
+	_, err := c.Run(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	path := filepath.Join(dir, c.outputFile())
+	if written == path {
+		t.Fatalf("command wrote the output file %s directly", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "complete" {
+		t.Fatalf("output file = %q, %v, want complete", data, err)
+	}
+	if _, err = os.Stat(written); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("temporary file not renamed: %v", err)
+	}
+}
+
+func TestPadNode_Run(t *testing.T) {
+	dir := t.TempDir()
+	err := createFile(filepath.Join(dir, "in.wav"), func(w io.Writer) error {
+		return wav.WriteSilence(w, wav.Mono22050, time.Second)
+	})
+	if err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	tests := []struct {
+		name   string
+		length time.Duration
+		want   time.Duration
+	}{
+		{"extended", 3 * time.Second, 3 * time.Second},
+		{"longer than length", 500 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &padNode{dir: dir, inputFile: "in.wav", length: tt.length, hashLen: DefaultHashLength}
+			op, err := n.Run(t.Context(), nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if op != created {
+				t.Fatalf("Run() = %v, want %v", op, created)
+			}
+			got, err := wav.FileDuration(filepath.Join(dir, n.outputFile()))
+			if err != nil {
+				t.Fatalf("FileDuration() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("duration = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
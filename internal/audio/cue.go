@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cueFramesPerSecond is the count of CD frames per second used by the INDEX times.
+const cueFramesPerSecond = 75
+
+// cueQuoteReplacer replaces the double quotes which end a value of a CUE sheet.
+var cueQuoteReplacer = strings.NewReplacer(`"`, "'")
+
+// cuePath returns the path of the CUE sheet of the output file.
+func cuePath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".cue"
+}
+
+// writeCueSheet writes the CUE sheet of the output file with a track per chapter,
+// so players can seek between the exercises of a single output file.
+// The track start times are only known after the creation of the chapter wav files.
+func (cb *cmdBuilder) writeCueSheet(outputPath string, metadata Metadata, chapters []chapter) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "PERFORMER \"%s\"\n", cueQuoteReplacer.Replace(metadata.Artist))
+	fmt.Fprintf(&b, "TITLE \"%s\"\n", cueQuoteReplacer.Replace(metadata.Album))
+	fmt.Fprintf(&b, "FILE \"%s\" %s\n", cueQuoteReplacer.Replace(filepath.Base(outputPath)), cb.cueFileType())
+	lengths, err := cb.chapterLengths(chapters)
+	if err != nil {
+		return err
+	}
+	var start time.Duration
+	for i, c := range chapters {
+		fmt.Fprintf(&b, "  TRACK %02d AUDIO\n    TITLE \"%s\"\n    INDEX 01 %s\n",
+			i+1,
+			cueQuoteReplacer.Replace(c.title),
+			cueTime(start),
+		)
+		start += lengths[i]
+	}
+	return os.WriteFile(cuePath(outputPath), []byte(b.String()), 0o644)
+}
+
+// cueFileType is the file type of the FILE command. Players accept WAVE for any audio file.
+func (cb *cmdBuilder) cueFileType() string {
+	if cb.audioFormat == Mp3 {
+		return "MP3"
+	}
+	return "WAVE"
+}
+
+// cueTime formats the duration as mm:ss:ff with ff in CD frames.
+func cueTime(d time.Duration) string {
+	frames := d.Milliseconds() * cueFramesPerSecond / 1000
+	return fmt.Sprintf("%02d:%02d:%02d",
+		frames/(60*cueFramesPerSecond),
+		frames/cueFramesPerSecond%60,
+		frames%cueFramesPerSecond,
+	)
+}
@@ -0,0 +1,72 @@
+package audio
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+)
+
+// digestVersion is the first byte of every digested input. Increase it if the
+// encoding changes, so files created with the old encoding are not reused.
+const digestVersion = 1
+
+// Type tags of the encoded values.
+const (
+	tagString  = 's'
+	tagBytes   = 'b'
+	tagStrings = 'l'
+	tagInt     = 'i'
+	tagFloat   = 'f'
+)
+
+// digest returns the SHA-256 digest of str and data in hex characters.
+// File names contain its first characters as hash.
+//
+// The input is encoded independent of the Go version: the version byte, then
+// str and every value of data in order as type tag followed by the value.
+// Strings and bytes are prefixed with their length as uvarint, string slices
+// with their count, ints are varints and floats the IEEE 754 bits in big endian.
+// Other types panic.
+func digest(str string, data ...any) string {
+	h := sha256.New()
+	h.Write([]byte{digestVersion})
+	writeString(h, str)
+	for _, d := range data {
+		switch v := d.(type) {
+		case string:
+			writeString(h, v)
+		case []byte:
+			h.Write([]byte{tagBytes})
+			writeUvarint(h, uint64(len(v)))
+			h.Write(v)
+		case []string:
+			h.Write([]byte{tagStrings})
+			writeUvarint(h, uint64(len(v)))
+			for _, s := range v {
+				writeString(h, s)
+			}
+		case int:
+			h.Write([]byte{tagInt})
+			h.Write(binary.AppendVarint(nil, int64(v)))
+		case float64:
+			h.Write([]byte{tagFloat})
+			h.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v)))
+		default:
+			panic(fmt.Sprintf("digest: unsupported type %T", d))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeString(h hash.Hash, s string) {
+	h.Write([]byte{tagString})
+	writeUvarint(h, uint64(len(s)))
+	h.Write([]byte(s))
+}
+
+func writeUvarint(h hash.Hash, x uint64) {
+	h.Write(binary.AppendUvarint(nil, x))
+}
@@ -0,0 +1,29 @@
+package audio
+
+import "testing"
+
+func TestDigest(t *testing.T) {
+	// The digest must not change across Go versions, otherwise all cached files are created again.
+	got := digest("tts", "say", "Anna", 180, []string{"a", "b"}, []byte{1, 2}, 440.5)
+	want := "a1e46dc29ad9ad06c7607f00504a69d2158f2cf4e438ec421bc709db1e2bf050"
+	if got != want {
+		t.Fatalf("digest() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name string
+		a    string
+		b    string
+	}{
+		{"boundaries", digest("a", "bc"), digest("ab", "c")},
+		{"types", digest("a", "1"), digest("a", 1)},
+		{"slices", digest("a", []string{"b", "c"}), digest("a", "b", "c")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.a == tt.b {
+				t.Fatalf("digest() = %v for different inputs", tt.a)
+			}
+		})
+	}
+}
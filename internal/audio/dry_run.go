@@ -0,0 +1,155 @@
+package audio
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mrclmr/w2a/internal/dag"
+)
+
+// DryRun lists what BatchCreate and RemoveOtherFiles would do.
+type DryRun struct {
+	// Created are the output files which would be created.
+	Created []string
+	// Copied are the output files which would be copied from a file with the same hash.
+	Copied []string
+	// Existing are the output files which already exist.
+	Existing []string
+	// Removed are the files of the output dir which RemoveOtherFiles would remove.
+	Removed []string
+	// Commands counts the commands which would run.
+	Commands int
+}
+
+func (d *DryRun) String() string {
+	b := strings.Builder{}
+	for _, group := range []struct {
+		op    string
+		paths []string
+	}{
+		{"create", d.Created},
+		{"copy", d.Copied},
+		{"exists", d.Existing},
+		{"remove", d.Removed},
+	} {
+		for _, p := range group.paths {
+			b.WriteString(fmt.Sprintf("%-7s %s\n", group.op, p))
+		}
+	}
+	b.WriteString(fmt.Sprintf("%d commands would run", d.Commands))
+	return b.String()
+}
+
+// hashSuffixReg matches the hash at the end of an output file name without extension.
+var hashSuffixReg = regexp.MustCompile(`-[0-9a-f]{7}$`)
+
+// Diff lists the output files which would be rebuilt, copied, skipped and deleted.
+// A rebuilt file replacing a deleted file with the same name but another hash,
+// e.g. after a text changed, is listed once.
+func (d *DryRun) Diff() string {
+	removedByName := make(map[string]string)
+	for _, p := range d.Removed {
+		removedByName[nameWithoutHash(p)] = p
+	}
+	replaced := make(map[string]bool)
+
+	b := strings.Builder{}
+	for _, p := range d.Created {
+		old, ok := removedByName[nameWithoutHash(p)]
+		if ok {
+			replaced[old] = true
+			b.WriteString(fmt.Sprintf("rebuild %s (replaces %s)\n", p, filepath.Base(old)))
+			continue
+		}
+		b.WriteString(fmt.Sprintf("rebuild %s\n", p))
+	}
+	for _, p := range d.Copied {
+		b.WriteString(fmt.Sprintf("copy    %s\n", p))
+	}
+	for _, p := range d.Existing {
+		b.WriteString(fmt.Sprintf("skip    %s\n", p))
+	}
+	for _, p := range d.Removed {
+		if !replaced[p] {
+			b.WriteString(fmt.Sprintf("delete  %s\n", p))
+		}
+	}
+	b.WriteString(fmt.Sprintf("%d rebuilt, %d copied, %d skipped, %d deleted, %d commands would run",
+		len(d.Created), len(d.Copied), len(d.Existing), len(d.Removed)-len(replaced), d.Commands))
+	return b.String()
+}
+
+// nameWithoutHash returns the path without the hash of the file name.
+func nameWithoutHash(path string) string {
+	ext := filepath.Ext(path)
+	return hashSuffixReg.ReplaceAllString(strings.TrimSuffix(path, ext), "") + ext
+}
+
+// DryRun adds the nodes to create the files and returns what would be done
+// without running any command or copying existing files.
+func (f *FileCreator) DryRun(files []File) (*DryRun, error) {
+	f.cmdBuilder.fileCacheBuilder.dryRun = true
+
+	d := &DryRun{}
+	nodesToRun := make([]dag.Node[fileOperation], 0)
+	keep := map[string]bool{
+		filepath.Join(f.outputDir, outputsFile):  true,
+		filepath.Join(f.outputDir, timelineFile): true,
+	}
+	for _, name := range f.playlistNames(files) {
+		keep[f.playlistPath(name)] = true
+	}
+	for i, file := range files {
+		op, convertCmd, _, err := f.textToAudioFile(file, i)
+		if err != nil {
+			return nil, err
+		}
+		convertCmd, err = f.addCopyNodeIfConvertExists(convertCmd)
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(f.outputDir, convertCmd.outputFile())
+		keep[path] = true
+		if f.hasCueSheet(i) {
+			keep[cuePath(path)] = true
+		}
+		for _, p := range f.captions.paths(path) {
+			keep[p] = true
+		}
+		switch op {
+		case exists:
+			d.Existing = append(d.Existing, path)
+		case copied:
+			d.Copied = append(d.Copied, path)
+		default:
+			d.Created = append(d.Created, path)
+			nodesToRun = append(nodesToRun, convertCmd)
+		}
+	}
+
+	err := f.cmdBuilder.fileCacheBuilder.collision()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodesToRun) > 0 {
+		steps, err := f.dag.Plan(nodesToRun...)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range steps {
+			if !s.Skipped {
+				d.Commands++
+			}
+		}
+	}
+
+	d.Removed, err = otherFiles(f.outputDir, keep)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,24 @@
+package audio
+
+import (
+	"testing"
+)
+
+func TestDryRun_Diff(t *testing.T) {
+	d := &DryRun{
+		Created:  []string{"out/01-Squats-1234567.mp3", "out/02-Plank-89abcde.mp3"},
+		Copied:   []string{"out/03-Squats-7654321.mp3"},
+		Existing: []string{"out/00-Before_Workout-fedcba9.mp3"},
+		Removed:  []string{"out/01-Squats-abcdef0.mp3", "out/04-Lunges-0000000.mp3"},
+		Commands: 5,
+	}
+	want := `rebuild out/01-Squats-1234567.mp3 (replaces 01-Squats-abcdef0.mp3)
+rebuild out/02-Plank-89abcde.mp3
+copy    out/03-Squats-7654321.mp3
+skip    out/00-Before_Workout-fedcba9.mp3
+delete  out/04-Lunges-0000000.mp3
+2 rebuilt, 1 copied, 1 skipped, 1 deleted, 5 commands would run`
+	if got := d.Diff(); got != want {
+		t.Fatalf("Diff() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -2,37 +2,73 @@ package audio
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"path/filepath"
+	"time"
 
 	"github.com/mrclmr/w2a/internal/dag"
+	"github.com/mrclmr/w2a/internal/wav"
 	"golang.org/x/text/unicode/norm"
 )
 
 type node interface {
 	dag.Node[fileOperation]
 	outputFile() string
+	// digest is the full digest the hash is shortened from. It is empty if unknown.
+	digest() string
 }
 
 type fileCacheBuilder struct {
 	existingFiles map[string]map[string]bool
+	manifest      *manifest
+	// dir is where the cached nodes write their output file.
+	dir string
+	// dryRun disables copying existing files while nodes are added.
+	dryRun bool
+	// hashLen is the number of hex characters of the hashes in file names.
+	hashLen int
+	// digests are the digests of the added nodes by hash.
+	digests map[string]string
+	// collisions are the added nodes whose hash is used by a node with another digest.
+	collisions []error
+	// programs are the programs of the added commands by hash.
+	programs map[string]string
 }
 
-func (f *fileCacheBuilder) cmd(
-	cmd *cmd,
-) *fileCache {
+// fileCache returns the cached node and marks its output file as used.
+func (f *fileCacheBuilder) fileCache(n node) *fileCache {
+	f.plan(n)
+	f.manifest.use(filepath.Join(f.dir, n.outputFile()))
 	return &fileCache{
-		node:          cmd,
+		node:          n,
 		existingFiles: f.existingFiles,
+		manifest:      f.manifest,
+		dir:           f.dir,
 	}
 }
 
+func (f *fileCacheBuilder) cmd(
+	cmd *cmd,
+) *fileCache {
+	return f.fileCache(cmd)
+}
+
+// convert hashes the content hashes of input files outside the temp dir, e.g. the cover,
+// in addition to the command and its arguments.
 func (f *fileCacheBuilder) convert(
-	execCmdCtx ExecCmdCtx,
+	class cmdClass,
 	cmdStr string,
 	args []string,
+	inputHashes ...string,
 ) (fileOperation, node, error) {
-	n := newCmd(execCmdCtx, cmdStr, args)
-	op, err := useExistingFile(f.existingFiles, n.outputFile())
+	data := []any{argsBasePath(args)}
+	for _, h := range inputHashes {
+		data = append(data, h)
+	}
+	n := newCmdWithDigest(class, cmdStr, args, digest(cmdStr, data...), f.hashLen)
+	f.plan(n)
+	op, err := f.existingOrCopy(n.outputFile(), n.digest())
 	if err != nil {
 		return 0, nil, err
 	}
@@ -42,12 +78,59 @@ func (f *fileCacheBuilder) convert(
 func (f *fileCacheBuilder) noop(
 	outFile string,
 ) *fileCache {
-	return &fileCache{
-		node: &noopNode{
-			outFile: outFile,
-		},
-		existingFiles: f.existingFiles,
-	}
+	return f.fileCache(newNoopNode(outFile, f.hashLen))
+}
+
+func (f *fileCacheBuilder) silence(
+	dir string,
+	format wav.Format,
+	duration time.Duration,
+) *fileCache {
+	return f.fileCache(&silenceNode{
+		dir:      dir,
+		format:   format,
+		duration: duration,
+		hashLen:  f.hashLen,
+	})
+}
+
+func (f *fileCacheBuilder) tone(
+	dir string,
+	format wav.Format,
+	tone *Tone,
+) *fileCache {
+	return f.fileCache(&toneNode{
+		dir:     dir,
+		format:  format,
+		tone:    tone,
+		hashLen: f.hashLen,
+	})
+}
+
+func (f *fileCacheBuilder) pad(
+	dir string,
+	inputFile string,
+	length time.Duration,
+	cut bool,
+) *fileCache {
+	return f.fileCache(&padNode{
+		dir:       dir,
+		inputFile: inputFile,
+		length:    length,
+		cut:       cut,
+		hashLen:   f.hashLen,
+	})
+}
+
+func (f *fileCacheBuilder) concat(
+	dir string,
+	inputFiles []string,
+) *fileCache {
+	return f.fileCache(&concatNode{
+		dir:        dir,
+		inputFiles: inputFiles,
+		hashLen:    f.hashLen,
+	})
 }
 
 func (f *fileCacheBuilder) copy(
@@ -57,25 +140,70 @@ func (f *fileCacheBuilder) copy(
 	cpNode := &copyNode{
 		srcPath: srcPath,
 		dstPath: dstPath,
+		hashLen: f.hashLen,
 	}
-	op, err := useExistingFile(f.existingFiles, cpNode.outputFile())
+	op, err := f.existingOrCopy(cpNode.outputFile(), cpNode.digest())
 	if err != nil {
 		return 0, nil, err
 	}
 	return op, cpNode, nil
 }
 
+// existingOrCopy is like the package-level function useExistingFile but copies nothing in dry run mode.
+func (f *fileCacheBuilder) existingOrCopy(filename string, digest string) (fileOperation, error) {
+	if f.dryRun {
+		op, _, err := existingFile(f.existingFiles, f.manifest, filename, digest)
+		return op, err
+	}
+	return useExistingFile(f.existingFiles, f.manifest, filename, digest)
+}
+
+// plan records the program of n and the digest of n to detect nodes whose shortened hashes collide.
+// Nodes with an unknown digest are not checked.
+func (f *fileCacheBuilder) plan(n node) {
+	if c, ok := n.(interface{ program() string }); ok {
+		f.programs[n.Hash()] = c.program()
+	}
+	d := n.digest()
+	if d == "" {
+		return
+	}
+	planned, ok := f.digests[n.Hash()]
+	if !ok {
+		f.digests[n.Hash()] = d
+		return
+	}
+	if planned != d {
+		f.collisions = append(f.collisions, fmt.Errorf("%w: %s", ErrHashCollision, n.Name()))
+	}
+}
+
+// collision returns the collisions of the added nodes joined.
+func (f *fileCacheBuilder) collision() error {
+	return errors.Join(f.collisions...)
+}
+
 func newFileCacheBuilder(
 	existingFiles map[string]map[string]bool,
+	m *manifest,
+	dir string,
+	hashLen int,
 ) *fileCacheBuilder {
 	return &fileCacheBuilder{
 		existingFiles: existingFiles,
+		manifest:      m,
+		dir:           dir,
+		hashLen:       hashLen,
+		digests:       make(map[string]string),
+		programs:      make(map[string]string),
 	}
 }
 
 type fileCache struct {
 	node          node
 	existingFiles map[string]map[string]bool
+	manifest      *manifest
+	dir           string
 }
 
 func (f *fileCache) outputFile() string {
@@ -90,42 +218,74 @@ func (f *fileCache) Name() string {
 	return f.node.Name()
 }
 
+func (f *fileCache) digest() string {
+	return f.node.digest()
+}
+
 func (f *fileCache) Run(ctx context.Context, _ []fileOperation) (fileOperation, error) {
-	op, err := useExistingFile(f.existingFiles, f.node.outputFile())
+	op, err := useExistingFile(f.existingFiles, f.manifest, f.node.outputFile(), f.node.digest())
 	if err != nil {
 		return 0, err
 	}
 	if op >= exists {
 		return op, nil
 	}
-	return f.node.Run(ctx, nil)
+	op, err = f.node.Run(ctx, nil)
+	if err != nil {
+		removePartialFile(filepath.Join(f.dir, f.node.outputFile()))
+		return 0, err
+	}
+	if op == created {
+		f.manifest.record(filepath.Join(f.dir, f.node.outputFile()), f.node.Name(), f.node.digest())
+	}
+	return op, nil
+}
+
+// Retry returns the retry of the cached node. Nodes without one are not retried.
+func (f *fileCache) Retry() dag.Retry {
+	if r, ok := f.node.(dag.Retrier); ok {
+		return r.Retry()
+	}
+	return dag.Retry{}
 }
 
-func useExistingFile(existingFiles map[string]map[string]bool, filename string) (fileOperation, error) {
+// Skip reports whether the output file exists or can be copied from a file with the same hash.
+func (f *fileCache) Skip() bool {
+	op, _, err := existingFile(f.existingFiles, f.manifest, f.node.outputFile(), f.node.digest())
+	return err == nil && op >= exists
+}
+
+// existingFile returns the operation of useExistingFile without copying and the path of the existing file.
+// It fails if the existing file was created from other inputs with the same hash.
+func existingFile(existingFiles map[string]map[string]bool, m *manifest, filename string, digest string) (fileOperation, string, error) {
 	for _, paths := range existingFiles {
 		for p := range paths {
 			if norm.NFC.String(filepath.Base(p)) == filename {
-				return exists, nil
+				return exists, p, m.checkDigest(p, digest)
 			}
 		}
 	}
+	for p := range existingFiles[extractHash(filename)] {
+		return copied, p, m.checkDigest(p, digest)
+	}
+	return created, "", nil
+}
 
-	hash := extractHash(filename)
-	paths, ok := existingFiles[hash]
-	if ok {
-		var path string
-		for p := range paths {
-			path = p
-			break
-		}
+func useExistingFile(existingFiles map[string]map[string]bool, m *manifest, filename string, digest string) (fileOperation, error) {
+	op, path, err := existingFile(existingFiles, m, filename, digest)
+	if err != nil {
+		return 0, err
+	}
+	if op == copied {
 		copiedPath := filepath.Join(filepath.Dir(path), filename)
 		// TODO: rename file?
-		err := copyFile(path, copiedPath)
+		err := CopyFile(path, copiedPath)
 		if err != nil {
 			return 0, err
 		}
+		m.record(copiedPath, "copy "+path, digest)
 		return copied, nil
 	}
 	// created means in this context "needs to be created"
-	return created, nil
+	return op, nil
 }
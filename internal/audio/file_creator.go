@@ -1,6 +1,7 @@
 package audio
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -10,6 +11,8 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +20,7 @@ import (
 
 	"github.com/mrclmr/w2a/internal/dag"
 	"github.com/mrclmr/w2a/internal/m3u"
+	"github.com/mrclmr/w2a/internal/wav"
 )
 
 type ExecCmdCtx = func(ctx context.Context, name string, args ...string) Cmd
@@ -42,9 +46,55 @@ func ToCreatePlaylistFunc[wc io.WriteCloser](fn func(string) (wc, error)) Create
 	}
 }
 
+// DefaultSampleRate is the sample rate of the intermediate wav files if none is set.
+// It is the output sample rate of say and espeak-ng.
+const DefaultSampleRate = 22050
+
 type TTS struct {
 	TTSCmd TTSCmd
 	Voice  string
+	// Rate is the speech rate in words per minute. Zero uses the engine default.
+	Rate int
+	// Tempo speeds up or slows down the speech without changing the pitch.
+	// Zero or one keeps the tempo of the engine.
+	Tempo float64
+	// ESpeakNG holds options only used by espeak-ng.
+	ESpeakNG ESpeakNGOptions
+	// Batch speaks many texts per call of say or espeak-ng.
+	Batch bool
+	// MaxConcurrent caps parallel TTS commands. Zero means no limit.
+	MaxConcurrent int
+	// RequestsPerSecond caps started TTS commands per second. Zero means no limit.
+	RequestsPerSecond float64
+}
+
+// ESpeakNGOptions are passed to espeak-ng. Zero values use the espeak-ng defaults.
+type ESpeakNGOptions struct {
+	// Variant is appended to the voice, e.g. 'f3' results in 'en-gb+f3'.
+	Variant   string
+	Amplitude int
+	WordGap   int
+}
+
+// digest covers everything that changes the spoken audio of the text.
+// Changing the engine, voice or rate regenerates the audio. Batched texts
+// have trimmed silence and are not mixed up with texts spoken alone.
+func (t *TTS) digest(text string) string {
+	data := []any{t.TTSCmd.String(), t.Voice, t.Rate, t.ESpeakNG.Variant, t.ESpeakNG.Amplitude, t.ESpeakNG.WordGap, text}
+	if t.batchable(text) {
+		data = append(data, "batch")
+	}
+	return digest("tts", data...)
+}
+
+// BackgroundMusic is mixed under every output file.
+type BackgroundMusic struct {
+	// Paths are used in turn for the output files.
+	Paths []string
+	// Volume between 0 and 1.
+	Volume float64
+	// Ducking lowers the music volume while speaking.
+	Ducking bool
 }
 
 type FileCreator struct {
@@ -53,20 +103,81 @@ type FileCreator struct {
 
 	outputFilesToKeep map[string]bool
 	existingFilePaths map[string]map[string]bool
+	manifest          *manifest
+
+	// recordings maps texts to wav files which are used instead of TTS.
+	recordings map[string]string
+
+	// soundsDir is searched for sounds before the embedded sounds.
+	soundsDir string
+
+	captions  Captions
+	playlists Playlists
+	// sounds maps the names of the embedded sounds to their files in the temp dir.
+	sounds map[string]string
+
+	// loudnessTarget is the integrated loudness in LUFS. Zero disables normalization.
+	loudnessTarget float64
+
+	backgroundMusic *BackgroundMusic
+
+	// continueOnError creates all files which do not depend on a failed command.
+	continueOnError bool
+
+	// chapters are the chapters of the files by index with chapters.
+	chapters map[int][]chapter
+	// segmentWavs are the wav files of the segments.
+	segmentWavs map[Segment]string
+
+	// created is the creation time. Intermediate files used since then are kept by CollectGarbage.
+	created time.Time
 
 	convertNodes map[string]node
+	metrics      *metricsCollector
+	onProgress   func(Progress)
+	onFile       func(FileStatus)
+	onPrograms   func([]string) error
 	dag          *dag.Dag[fileOperation]
 	cmdBuilder   *cmdBuilder
 }
 
-func NewFileCreator(
-	execCmdCtx ExecCmdCtx,
-	tts *TTS,
-	audioFormat Format,
-	tempDir string,
-	outputDir string,
-	createPaylistFunc CreatePlaylistFunc,
-) (*FileCreator, error) {
+// Options configure a FileCreator. Zero values use the defaults.
+type Options struct {
+	ExecCmdCtx ExecCmdCtx
+	TTS        *TTS
+	Format     Format
+	Bitrate    string
+	// SampleRate is the sample rate of the intermediate wav files.
+	SampleRate int
+	// Channels is the channel count of the output files.
+	Channels   int
+	ReplayGain bool
+	TempDir    string
+	OutputDir  string
+	// CreatePlaylistFunc creates the playlist files.
+	CreatePlaylistFunc CreatePlaylistFunc
+	Retries            Retries
+	// Timeout is the timeout of a single command. Zero means no timeout.
+	Timeout         time.Duration
+	ContinueOnError bool
+	// MaxParallel caps the commands running at the same time. Zero means no limit.
+	MaxParallel int
+	// Force creates all files again.
+	Force bool
+	// Recordings are the paths of recordings used instead of TTS by text.
+	Recordings      map[string]string
+	LoudnessTarget  float64
+	BackgroundMusic *BackgroundMusic
+	SoundsDir       string
+	Captions        Captions
+	Playlists       Playlists
+	// HashLength is the number of hex characters of the hashes in file names.
+	HashLength int
+}
+
+func NewFileCreator(opts Options) (*FileCreator, error) {
+	tempDir := opts.TempDir
+	outputDir := opts.OutputDir
 	if err := mkdirAllIfNotExists(outputDir); err != nil {
 		return nil, err
 	}
@@ -74,54 +185,275 @@ func NewFileCreator(
 		return nil, err
 	}
 
-	if err := initSounds(tempDir); err != nil {
+	opts.HashLength = cmp.Or(opts.HashLength, DefaultHashLength)
+	hashLen := opts.HashLength
+	if hashLen < DefaultHashLength || hashLen > MaxHashLength {
+		return nil, fmt.Errorf("hash length must be between %d and %d", DefaultHashLength, MaxHashLength)
+	}
+
+	sounds, err := initSounds(tempDir, hashLen)
+	if err != nil {
 		return nil, err
 	}
 
-	existingFilePaths, err := allFilePaths(tempDir, outputDir)
+	m, err := loadManifest(tempDir, outputDir)
 	if err != nil {
 		return nil, err
 	}
+	existingFilePaths := m.existingFiles()
+	if opts.Force {
+		// All files are created again and overwrite the existing files.
+		existingFilePaths = make(map[string]map[string]bool)
+	}
 
-	return &FileCreator{
+	f := &FileCreator{
 		outputDir:          outputDir,
-		createPlaylistFunc: createPaylistFunc,
+		createPlaylistFunc: opts.CreatePlaylistFunc,
 
 		outputFilesToKeep: make(map[string]bool),
 		existingFilePaths: existingFilePaths,
-
+		manifest:          m,
+
+		recordings:      opts.Recordings,
+		soundsDir:       opts.SoundsDir,
+		captions:        opts.Captions,
+		playlists:       opts.Playlists,
+		sounds:          sounds,
+		loudnessTarget:  opts.LoudnessTarget,
+		backgroundMusic: opts.BackgroundMusic,
+		continueOnError: opts.ContinueOnError,
+
+		chapters:     make(map[int][]chapter),
+		segmentWavs:  make(map[Segment]string),
+		created:      time.Now(),
 		convertNodes: make(map[string]node),
-		dag:          dag.New[fileOperation](),
-		cmdBuilder:   newCmdBuilder(existingFilePaths, execCmdCtx, tempDir, outputDir, tts, audioFormat),
-	}, nil
+		metrics:      &metricsCollector{},
+		cmdBuilder:   newCmdBuilder(existingFilePaths, m, opts),
+	}
+
+	dagOpts := []dag.Option{
+		dag.WithProgress(f.progress),
+		dag.WithHooks(dag.Hooks{OnNodeError: logNodeError}),
+		dag.WithTimeout(opts.Timeout),
+	}
+	if opts.ContinueOnError {
+		dagOpts = append(dagOpts, dag.WithContinueOnError())
+	}
+	if opts.MaxParallel > 0 {
+		dagOpts = append(dagOpts, dag.WithMaxParallel(opts.MaxParallel))
+	}
+	f.dag = dag.New[fileOperation](dagOpts...)
+	return f, nil
+}
+
+// Progress is reported while BatchCreate runs the commands.
+type Progress struct {
+	// Completed counts the finished commands.
+	Completed int
+	// Total counts all commands to run.
+	Total int
+	// Running are the names of the commands running right now.
+	Running []string
+	// Cached counts the finished commands whose file already existed.
+	Cached int
+}
+
+// FileStatus is reported by BatchCreate for every output file.
+type FileStatus struct {
+	Path string
+	// State is 'queued' before the commands of the file run and
+	// 'created', 'exists', 'copied' or 'failed' afterwards.
+	State string
+	// Err is the error of a failed file.
+	Err error
+}
+
+// OnFile sets fn which is called by BatchCreate when the state of an output file changes.
+// fn is called sequentially.
+func (f *FileCreator) OnFile(fn func(FileStatus)) {
+	f.onFile = fn
+}
+
+// OnPrograms sets fn which is called by BatchCreate with the programs of the commands to run,
+// e.g. to check that they are installed. It is called before any command runs.
+// BatchCreate fails with the error of fn.
+func (f *FileCreator) OnPrograms(fn func(programs []string) error) {
+	f.onPrograms = fn
+}
+
+// checkPrograms calls the function set by OnPrograms with the sorted programs
+// of the steps which are not skipped.
+func (f *FileCreator) checkPrograms(steps []dag.Step) error {
+	if f.onPrograms == nil || len(steps) == 0 {
+		return nil
+	}
+	var programs []string
+	for _, s := range steps {
+		program, ok := f.cmdBuilder.fileCacheBuilder.programs[s.Hash]
+		if ok && !s.Skipped && !slices.Contains(programs, program) {
+			programs = append(programs, program)
+		}
+	}
+	slices.Sort(programs)
+	return f.onPrograms(programs)
+}
+
+func (f *FileCreator) fileStatus(path string, state string, err error) {
+	if f.onFile != nil {
+		f.onFile(FileStatus{Path: path, State: state, Err: err})
+	}
+}
+
+// OnProgress sets fn which is called when a command starts and when it finishes.
+// fn is called sequentially.
+func (f *FileCreator) OnProgress(fn func(Progress)) {
+	f.onProgress = fn
+}
+
+func (f *FileCreator) progress(e dag.Event) {
+	logProgress(e)
+	f.metrics.add(e)
+	if f.onProgress != nil {
+		f.onProgress(Progress{Completed: e.Completed, Total: e.Total, Running: e.Running, Cached: f.metrics.cachedNodes()})
+	}
+}
+
+// logProgress logs every finished command with the count of all commands.
+func logProgress(e dag.Event) {
+	if e.Finished {
+		slog.Debug("progress", "completed", e.Completed, "total", e.Total, "name", e.Name)
+	}
+}
+
+// logNodeError logs every failed command. Without continue on error
+// BatchCreate only returns the first error.
+func logNodeError(name string, err error) {
+	slog.Warn("failed\t", "name", name, "err", err)
+}
+
+// Metrics returns the run times of the nodes run so far.
+func (f *FileCreator) Metrics() *Metrics {
+	return f.metrics.metrics()
 }
 
+// RemoveOtherFiles removes the files of the output dir which BatchCreate did not create,
+// also the playlists it did not write, e.g. after playlist_name changed.
+// Subdirectories, e.g. of other workouts, and hidden files are kept.
 func (f *FileCreator) RemoveOtherFiles() error {
 	return removeOtherFiles(f.outputDir, f.outputFilesToKeep)
 }
 
 type File struct {
 	Name     string
+	Metadata Metadata
 	Segments []Segment
+	// Chapters are the titles of the segments. They are written as
+	// chapter markers into m4b files.
+	Chapters []string
+	// Playlists are the names of additional playlists with the file.
+	Playlists []string
 }
 
-func (f *FileCreator) BatchCreate(ctx context.Context, files []File) error {
-	playlistPath := filepath.Join(f.outputDir, "playlist.m3u")
-	f.outputFilesToKeep[playlistPath] = true
-	playlistFile, err := f.createPlaylistFunc(playlistPath)
-	if err != nil {
-		return err
+// Playlists defines the playlists written by BatchCreate.
+type Playlists struct {
+	// Name of the playlist of all files without the extension .m3u. Default is 'playlist'.
+	Name  string
+	Paths m3u.PathStyle
+}
+
+// MergeFiles returns one file that plays the segments of all files in order.
+func MergeFiles(name string, metadata Metadata, files []File) File {
+	segments := make([]Segment, 0, len(files))
+	chapters := make([]string, 0, len(files))
+	for _, file := range files {
+		segments = append(segments, &Group{Segments: file.Segments})
+		chapters = append(chapters, cmp.Or(file.Metadata.Title, file.Name))
+	}
+	return File{
+		Name:     name,
+		Metadata: metadata,
+		Segments: segments,
+		Chapters: chapters,
+	}
+}
+
+// Metadata is written as tags into the output file. Empty values are omitted.
+type Metadata struct {
+	Title      string
+	Album      string
+	Artist     string
+	Comment    string
+	Track      int
+	TrackTotal int
+	// Cover is the path to an image embedded as album art in mp3 and m4a files.
+	Cover string
+}
+
+// ffmpegArgs returns the ffmpeg arguments to write the tags.
+func (m Metadata) ffmpegArgs() []string {
+	var args []string
+	add := func(key, value string) {
+		if value != "" {
+			args = append(args, "-metadata", key+"="+value)
+		}
+	}
+	add("title", m.Title)
+	add("album", m.Album)
+	add("artist", m.Artist)
+	add("comment", m.Comment)
+	if m.Track > 0 {
+		track := strconv.Itoa(m.Track)
+		if m.TrackTotal > 0 {
+			track += "/" + strconv.Itoa(m.TrackTotal)
+		}
+		add("track", track)
+	}
+	return args
+}
+
+// BatchCreate creates the files and the playlist.
+// The created files are recorded in the manifest even if a file fails.
+// If the FileCreator continues on error, the playlist is written without the failed files
+// and the errors of all failed files are returned.
+func (f *FileCreator) BatchCreate(ctx context.Context, files []File) (err error) {
+	defer func() {
+		err = errors.Join(err, f.manifest.save())
+	}()
+
+	outputsPath := filepath.Join(f.outputDir, outputsFile)
+	f.outputFilesToKeep[outputsPath] = true
+	timelinePath := filepath.Join(f.outputDir, timelineFile)
+	f.outputFilesToKeep[timelinePath] = true
+	playlistNames := f.playlistNames(files)
+	playlists := make(map[string]*m3u.Playlist, len(playlistNames))
+	for _, name := range playlistNames {
+		path := f.playlistPath(name)
+		f.outputFilesToKeep[path] = true
+		playlistFile, err := f.createPlaylistFunc(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = errors.Join(err, playlistFile.Close())
+		}()
+		playlists[name] = m3u.NewPlaylist(playlistFile, f.playlists.Paths)
 	}
-	playlist := m3u.NewPlaylist(playlistFile)
 
 	nodesToRun := make([]dag.Node[fileOperation], 0)
 	paths := make([]string, 0)
-
-	for _, file := range files {
-		op, convertCmd, err := f.textToAudioFile(file.Segments, file.Name)
+	names := make([]string, 0)
+	digests := make([]string, 0)
+	fileIdxs := make([]int, 0)
+	absPaths := make([]string, len(files))
+	wavFiles := make([]string, len(files))
+	ops := make([]string, len(files))
+
+	for i, file := range files {
+		op, convertCmd, wavFile, err := f.textToAudioFile(file, i)
 		if err != nil {
 			return err
 		}
+		wavFiles[i] = wavFile
 		convertCmd, err = f.addCopyNodeIfConvertExists(convertCmd)
 		if err != nil {
 			return err
@@ -129,38 +461,187 @@ func (f *FileCreator) BatchCreate(ctx context.Context, files []File) error {
 
 		path := filepath.Join(f.outputDir, convertCmd.outputFile())
 		f.outputFilesToKeep[path] = true
+		if f.hasCueSheet(i) {
+			f.outputFilesToKeep[cuePath(path)] = true
+		}
+		for _, p := range f.captions.paths(path) {
+			f.outputFilesToKeep[p] = true
+		}
 
-		abs, err := filepath.Abs(path)
+		absPaths[i], err = filepath.Abs(path)
 		if err != nil {
 			return err
 		}
-		// TODO: Add correct duration.
-		playlist.Add(abs, 1*time.Second)
 
 		if op >= exists {
 			slog.Info(op.String()+"\t", "path", path)
+			f.fileStatus(path, op.String(), nil)
+			ops[i] = op.String()
 		} else {
+			f.fileStatus(path, "queued", nil)
 			nodesToRun = append(nodesToRun, convertCmd)
 			paths = append(paths, path)
+			names = append(names, convertCmd.Name())
+			digests = append(digests, convertCmd.digest())
+			fileIdxs = append(fileIdxs, i)
+		}
+	}
+
+	err = f.cmdBuilder.fileCacheBuilder.collision()
+	if err != nil {
+		return err
+	}
+	var steps []dag.Step
+	if len(nodesToRun) > 0 {
+		steps, err = f.dag.Plan(nodesToRun...)
+		if err != nil {
+			return err
 		}
 	}
+	err = f.checkPrograms(steps)
+	if err != nil {
+		return err
+	}
+	f.cmdBuilder.ttsBatcher.plan(steps)
 
+	var errs []error
+	failed := make(map[int]bool)
 	idx := 0
 	for op, err := range f.dag.RunNodes(ctx, nodesToRun) {
 		if err != nil {
-			return err
+			f.fileStatus(paths[idx], "failed", err)
+			if !f.continueOnError {
+				return err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", paths[idx], err))
+			failed[fileIdxs[idx]] = true
+			idx++
+			continue
 		}
 
+		f.manifest.record(paths[idx], names[idx], digests[idx])
 		slog.Info(op.String()+"\t", "path", paths[idx])
+		f.fileStatus(paths[idx], op.String(), nil)
+		ops[fileIdxs[idx]] = op.String()
 		idx++
 	}
 
-	err = playlist.Write()
+	outputs := make([]outputEntry, 0, len(files))
+	tl := &timeline{}
+	for i, file := range files {
+		if failed[i] {
+			outputs = append(outputs, newOutputEntry(absPaths[i], file, 0, "failed"))
+			continue
+		}
+		duration := f.fileDuration(ctx, wavFiles[i], absPaths[i], file)
+		outputs = append(outputs, newOutputEntry(absPaths[i], file, duration, ops[i]))
+		playlists[playlistNames[0]].Add(absPaths[i], duration)
+		for _, name := range file.Playlists {
+			playlists[name].Add(absPaths[i], duration)
+		}
+		tl.add(f.cmdBuilder, file, f.chapters[i], duration)
+		if f.hasCueSheet(i) {
+			err = f.cmdBuilder.writeCueSheet(absPaths[i], file.Metadata, f.chapters[i])
+			warnIfMissing("cue sheet", cuePath(absPaths[i]), err)
+		}
+		err = f.writeCaptions(absPaths[i], file, duration)
+		for _, p := range f.captions.paths(absPaths[i]) {
+			warnIfMissing("caption file", p, err)
+		}
+	}
+	for _, name := range playlistNames {
+		err = playlists[name].Write()
+		if err != nil {
+			return err
+		}
+	}
+	err = writeOutputs(outputsPath, outputs)
+	if err != nil {
+		return err
+	}
+	err = tl.write(timelinePath)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// Graph adds the nodes to create the files without running them or copying existing files.
+// It returns the graph as Graphviz DOT or, if mermaid is true, as Mermaid flowchart.
+func (f *FileCreator) Graph(files []File, mermaid bool) (string, error) {
+	f.cmdBuilder.fileCacheBuilder.dryRun = true
+	for i, file := range files {
+		_, convertCmd, _, err := f.textToAudioFile(file, i)
+		if err != nil {
+			return "", err
+		}
+		_, err = f.addCopyNodeIfConvertExists(convertCmd)
+		if err != nil {
+			return "", err
+		}
+	}
+	if mermaid {
+		return f.dag.Mermaid(), nil
+	}
+	return f.dag.String(), nil
+}
+
+// fileDuration reads the duration of the wav file the output file is converted from.
+// If the wav file is not available because the output file already existed,
+// the duration of the output file is probed with ffprobe.
+// If both fail, the sum of the segment lengths is used.
+func (f *FileCreator) fileDuration(ctx context.Context, wavFile string, outputPath string, file File) time.Duration {
+	d, err := wav.FileDuration(filepath.Join(f.cmdBuilder.tempDir, wavFile))
+	if err == nil {
+		return d
+	}
+	d, err = f.cmdBuilder.ffprobeDuration(ctx, outputPath)
+	if err == nil {
+		return d
+	}
+	slog.Debug("duration of segments used", "path", outputPath, "err", err)
+	var sum time.Duration
+	for _, s := range file.Segments {
+		sum += s.len()
+	}
+	return sum
+}
+
+// warnIfMissing logs a warning if err is not nil and the file at path does not exist.
+// The wav files needed to write the file are not available if the output file already existed.
+func warnIfMissing(name string, path string, err error) {
+	if err == nil {
+		return
+	}
+	if _, statErr := os.Stat(path); statErr != nil {
+		slog.Warn("failed to write "+name+"\t", "path", path, "err", err)
+	}
+}
+
+// playlistNames returns the name of the playlist of all files
+// followed by the names of the additional playlists of the files.
+func (f *FileCreator) playlistNames(files []File) []string {
+	names := []string{cmp.Or(f.playlists.Name, "playlist")}
+	for _, file := range files {
+		for _, name := range file.Playlists {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func (f *FileCreator) playlistPath(name string) string {
+	return filepath.Join(f.outputDir, name+".m3u")
+}
+
+// hasCueSheet reports whether a CUE sheet is written for the file at position idx.
+// m4b files contain the chapters.
+func (f *FileCreator) hasCueSheet(idx int) bool {
+	_, ok := f.chapters[idx]
+	return ok && f.cmdBuilder.audioFormat != M4b
 }
 
 // addCopyNodeIfConvertExists adds a copy node if the convert node already exists.
@@ -182,30 +663,69 @@ func (f *FileCreator) addCopyNodeIfConvertExists(convertNode node) (node, error)
 	return cpNode, nil
 }
 
-func (f *FileCreator) textToAudioFile(segments []Segment, name string) (fileOperation, node, error) {
-	concatCmd, err := f.toWavConcatenated(segments)
+// textToAudioFile creates the nodes for the file at position idx of all files.
+// textToAudioFile returns the convert node and the wav file which is converted.
+func (f *FileCreator) textToAudioFile(file File, idx int) (fileOperation, node, string, error) {
+	wavCmds, err := f.toWavs(file.Segments)
+	if err != nil {
+		return 0, nil, "", err
+	}
+	concatCmd, err := f.concatWavs(wavCmds)
 	if err != nil {
-		return 0, nil, err
+		return 0, nil, "", err
+	}
+	var chapters []chapter
+	if len(file.Chapters) == len(wavCmds) {
+		for i, title := range file.Chapters {
+			chapters = append(chapters, chapter{title: title, wavFile: wavCmds[i].outputFile()})
+		}
+	}
+	if len(chapters) > 0 {
+		f.chapters[idx] = chapters
+	}
+	if f.backgroundMusic != nil && len(f.backgroundMusic.Paths) > 0 {
+		music := f.backgroundMusic.Paths[idx%len(f.backgroundMusic.Paths)]
+		mixCmd, err := f.cmdBuilder.ffmpegMixMusic(concatCmd.outputFile(), music, f.backgroundMusic)
+		if err != nil {
+			return 0, nil, "", err
+		}
+		err = f.dag.AddEdge(mixCmd, concatCmd)
+		if err != nil {
+			return 0, nil, "", err
+		}
+		concatCmd = mixCmd
+	}
+	if f.loudnessTarget != 0 {
+		normCmd := f.cmdBuilder.ffmpegLoudnorm(concatCmd.outputFile(), f.loudnessTarget)
+		err = f.dag.AddEdge(normCmd, concatCmd)
+		if err != nil {
+			return 0, nil, "", err
+		}
+		concatCmd = normCmd
 	}
-	op, convertCmd, err := f.cmdBuilder.convert(concatCmd.outputFile(), name)
+	op, convertCmd, err := f.cmdBuilder.convert(concatCmd.outputFile(), file.Name, file.Metadata, chapters)
 	if err != nil {
-		return 0, nil, err
+		return 0, nil, "", err
 	}
 	if op >= exists {
-		return op, convertCmd, nil
+		return op, convertCmd, concatCmd.outputFile(), nil
 	}
 	err = f.dag.AddEdge(convertCmd, concatCmd)
 	if err != nil {
-		return 0, nil, err
+		return 0, nil, "", err
 	}
-	return op, convertCmd, err
+	return op, convertCmd, concatCmd.outputFile(), err
 }
 
 func (f *FileCreator) toWavConcatenated(segments []Segment) (*fileCache, error) {
-	if len(segments) == 1 {
-		return f.toWav(segments[0])
+	cmdWavs, err := f.toWavs(segments)
+	if err != nil {
+		return nil, err
 	}
-	wavFiles := make([]string, len(segments))
+	return f.concatWavs(cmdWavs)
+}
+
+func (f *FileCreator) toWavs(segments []Segment) ([]*fileCache, error) {
 	cmdWavs := make([]*fileCache, len(segments))
 	for i, s := range segments {
 		cmdWav, err := f.toWav(s)
@@ -213,10 +733,21 @@ func (f *FileCreator) toWavConcatenated(segments []Segment) (*fileCache, error)
 			return nil, err
 		}
 		cmdWavs[i] = cmdWav
+		f.segmentWavs[s] = cmdWav.outputFile()
+	}
+	return cmdWavs, nil
+}
+
+func (f *FileCreator) concatWavs(cmdWavs []*fileCache) (*fileCache, error) {
+	if len(cmdWavs) == 1 {
+		return cmdWavs[0], nil
+	}
+	wavFiles := make([]string, len(cmdWavs))
+	for i, cmdWav := range cmdWavs {
 		wavFiles[i] = cmdWav.outputFile()
 	}
 
-	concatCmd := f.cmdBuilder.soxConcat(wavFiles)
+	concatCmd := f.cmdBuilder.concat(wavFiles)
 	for _, cmdWav := range cmdWavs {
 		err := f.dag.AddEdge(concatCmd, cmdWav)
 		if err != nil {
@@ -229,24 +760,31 @@ func (f *FileCreator) toWavConcatenated(segments []Segment) (*fileCache, error)
 func (f *FileCreator) toWav(s Segment) (*fileCache, error) {
 	switch v := s.(type) {
 	case *Sound:
-		return f.cmdBuilder.soxExtendLength(v.value(), v.len()), nil
+		return f.soundToWav(v)
 	case *Text:
 		return f.textToWav(v)
 	case *Silence:
-		return f.cmdBuilder.soxSilence(v.len()), nil
+		return f.cmdBuilder.silence(v.len()), nil
+	case *Tone:
+		return f.cmdBuilder.tone(v), nil
 	case *Group:
 		values := v.values()
 		if len(values) == 0 {
-			return f.cmdBuilder.soxSilence(v.len()), nil
+			return f.cmdBuilder.silence(v.len()), nil
 		}
 		concatCmd, err := f.toWavConcatenated(values)
 		if err != nil {
 			return nil, err
 		}
-
-		extLenCmd := f.cmdBuilder.soxExtendLength(concatCmd.outputFile(), v.len())
 		if v.len() == 0 {
-			return extLenCmd, nil
+			return concatCmd, nil
+		}
+
+		var extLenCmd *fileCache
+		if v.Trim {
+			extLenCmd = f.cmdBuilder.fitLength(concatCmd.outputFile(), v.len())
+		} else {
+			extLenCmd = f.cmdBuilder.extendLength(concatCmd.outputFile(), v.len())
 		}
 		err = f.dag.AddEdge(extLenCmd, concatCmd)
 		if err != nil {
@@ -258,18 +796,66 @@ func (f *FileCreator) toWav(s Segment) (*fileCache, error) {
 	}
 }
 
+// soundToWav uses the sound of the sounds directory if it exists, otherwise the embedded sound.
+func (f *FileCreator) soundToWav(s *Sound) (*fileCache, error) {
+	var soundCmd *fileCache
+	path := filepath.Join(f.soundsDir, s.value())
+	if _, err := os.Stat(path); f.soundsDir != "" && err == nil {
+		soundCmd, err = f.cmdBuilder.soxSound(path)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		filename, ok := f.sounds[s.value()]
+		if !ok {
+			return nil, fmt.Errorf("sound '%s' not found", s.value())
+		}
+		if f.cmdBuilder.inPipelineFormat(filename) {
+			return f.cmdBuilder.extendLength(filename, s.len()), nil
+		}
+		var err error
+		soundCmd, err = f.cmdBuilder.soxSound(filepath.Join(f.cmdBuilder.tempDir, filename))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.len() <= 0 {
+		return soundCmd, nil
+	}
+	extLenCmd := f.cmdBuilder.extendLength(soundCmd.outputFile(), s.len())
+	err := f.dag.AddEdge(extLenCmd, soundCmd)
+	if err != nil {
+		return nil, err
+	}
+	return extLenCmd, nil
+}
+
 func (f *FileCreator) textToWav(t *Text) (*fileCache, error) {
 	if t.value() == "" {
 		if t.len() > 0 {
-			silCmd := f.cmdBuilder.soxSilence(t.len())
+			silCmd := f.cmdBuilder.silence(t.len())
 			return silCmd, nil
 		}
 		return nil, fmt.Errorf("text is empty and length is zero")
 	}
 
-	ttsCmd := f.cmdBuilder.ttsCmd(t.value())
+	var ttsCmd *fileCache
+	// A recording replaces the TTS command, so no TTS node is added for the text.
+	if path, ok := f.recordings[recordingKey(t.value())]; ok {
+		recCmd, err := f.cmdBuilder.soxRecording(path)
+		if err != nil {
+			return nil, err
+		}
+		ttsCmd = recCmd
+	} else {
+		var err error
+		ttsCmd, err = f.speak(t)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if t.len() > 0 {
-		extLenCmd := f.cmdBuilder.soxExtendLength(ttsCmd.outputFile(), t.len())
+		extLenCmd := f.cmdBuilder.extendLength(ttsCmd.outputFile(), t.len())
 		err := f.dag.AddEdge(extLenCmd, ttsCmd)
 		if err != nil {
 			return nil, err
@@ -279,6 +865,34 @@ func (f *FileCreator) textToWav(t *Text) (*fileCache, error) {
 	return ttsCmd, nil
 }
 
+// speak returns the TTS command of the text resampled and with the tempo of the pipeline.
+func (f *FileCreator) speak(t *Text) (*fileCache, error) {
+	ttsCmd := f.cmdBuilder.ttsCmd(t.value(), t.Voice)
+	if f.cmdBuilder.sampleRate != DefaultSampleRate {
+		resampleCmd := f.cmdBuilder.soxResample(ttsCmd.outputFile())
+		err := f.dag.AddEdge(resampleCmd, ttsCmd)
+		if err != nil {
+			return nil, err
+		}
+		ttsCmd = resampleCmd
+	}
+	if tempo := f.cmdBuilder.tts.Tempo; tempo != 0 && tempo != 1 {
+		tempoCmd := f.cmdBuilder.soxTempo(ttsCmd.outputFile(), tempo)
+		err := f.dag.AddEdge(tempoCmd, ttsCmd)
+		if err != nil {
+			return nil, err
+		}
+		ttsCmd = tempoCmd
+	}
+	return ttsCmd, nil
+}
+
+// recordingKey ignores surrounding whitespace and trailing commas
+// that are added to texts for pauses.
+func recordingKey(text string) string {
+	return strings.TrimRight(strings.TrimSpace(text), ", ")
+}
+
 func mkdirAllIfNotExists(path string) error {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return os.MkdirAll(path, os.ModePerm)
@@ -287,23 +901,36 @@ func mkdirAllIfNotExists(path string) error {
 }
 
 func removeOtherFiles(dir string, excludedFiles map[string]bool) error {
-	filePaths, err := listFilePaths(dir)
+	paths, err := otherFiles(dir, excludedFiles)
 	if err != nil {
 		return err
 	}
+	for _, path := range paths {
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+		slog.Info("removed\t", "path", norm.NFC.String(path))
+	}
+	return nil
+}
+
+// otherFiles returns the sorted paths of the files in dir which are not excluded.
+func otherFiles(dir string, excludedFiles map[string]bool) ([]string, error) {
+	filePaths, err := listFilePaths(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
 	for path := range filePaths {
 		// For filenames afconvert uses a different Unicode Normalization Form (NFC, NFD, NFKC, or NFKD).
 		// The Go formed string is in the map. Actual filenames have different Unicode Normalization Form.
-		normPath := norm.NFC.String(path)
-		if !excludedFiles[normPath] {
-			err = os.Remove(path)
-			if err != nil {
-				return err
-			}
-			slog.Info("removed\t", "path", normPath)
+		if !excludedFiles[norm.NFC.String(path)] {
+			paths = append(paths, path)
 		}
 	}
-	return nil
+	slices.Sort(paths)
+	return paths, nil
 }
 
 func allFilePaths(tempDir string, outputDir string) (map[string]map[string]bool, error) {
@@ -353,11 +980,7 @@ func listFilePaths(dir string) (map[string]bool, error) {
 			return nil
 		}
 
-		if filepath.Base(path) == filepath.Base(dir) {
-			return nil
-		}
-
-		if filepath.Ext(name) == ".m3u" {
+		if path == dir {
 			return nil
 		}
 
@@ -375,7 +998,10 @@ func listFilePaths(dir string) (map[string]bool, error) {
 	return files, nil
 }
 
+// extractHash returns the hash after the last '-' of the file name.
+// The hashes have the configured hash length.
 func extractHash(filename string) string {
-	str := strings.TrimSuffix(filename, filepath.Ext(filename))
-	return str[len(str)-7:]
+	name := filepath.Base(filename)
+	str := strings.TrimSuffix(name, filepath.Ext(name))
+	return str[strings.LastIndex(str, "-")+1:]
 }
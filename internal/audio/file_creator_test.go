@@ -3,26 +3,39 @@ package audio
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"maps"
+	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/mrclmr/w2a/internal/m3u"
+	"github.com/mrclmr/w2a/internal/wav"
 )
 
 func newDummyCmdExec(buf *bytes.Buffer) func(context.Context, string, ...string) dummyCmd {
 	return func(_ context.Context, cmd string, args ...string) dummyCmd {
 		buf.WriteString(strings.Join(append([]string{cmd}, args...), " ") + "\n")
+		if cmd == "ffprobe" {
+			return dummyCmd{Buffer: buf, out: []byte("1.500000\n")}
+		}
 		return dummyCmd{Buffer: buf}
 	}
 }
 
 type dummyCmd struct {
 	*bytes.Buffer
+	out []byte
 }
 
 func (c dummyCmd) CombinedOutput() ([]byte, error) {
-	return nil, nil
+	return c.out, nil
 }
 
 const (
@@ -38,11 +51,45 @@ func (d *dummyPlaylist) Close() error {
 	return nil
 }
 
+// testOptions returns the options of the tests with the dummy commands
+// logging to log and the temp and output dir in dir.
+func testOptions(dir string, log *bytes.Buffer) Options {
+	return Options{
+		ExecCmdCtx: ToExecCmdCtx(newDummyCmdExec(log)),
+		TTS: &TTS{
+			TTSCmd: EspeakNG,
+			Voice:  "en-GB",
+		},
+		Format:    Mp3,
+		TempDir:   filepath.Join(dir, tempDir),
+		OutputDir: filepath.Join(dir, outputDir),
+		CreatePlaylistFunc: func(name string) (io.WriteCloser, error) {
+			return &dummyPlaylist{&bytes.Buffer{}}, nil
+		},
+	}
+}
+
 func TestFileCreator_BatchCreate(t *testing.T) {
 	dir := t.TempDir()
+	for _, name := range []string{"track.mp3", "cover.jpg"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
 	tests := []struct {
-		name         string
-		files        []File
+		name            string
+		files           []File
+		loudnessTarget  float64
+		backgroundMusic *BackgroundMusic
+		tempo           float64
+		sampleRate      int
+		channels        int
+		replayGain      bool
+		// options overrides the other options.
+		options func(*Options)
+		// skipOn is the GOOS the test is skipped on.
+		skipOn       string
 		wantPlaylist string
 		wantLog      string
 		wantErr      bool
@@ -55,29 +102,221 @@ func TestFileCreator_BatchCreate(t *testing.T) {
 				},
 			},
 			wantPlaylist: `#EXTM3U
-#EXTINF:1,my-file-f4a826f.mp3
-file://` + filepath.Join(dir, "output-dir", "my-file-f4a826f.mp3") + "\n",
-			wantLog: `sox_ng -n -r 22050 ` + filepath.Join(dir, "temp-dir", "silence_1s-c8c9dd8.wav") + ` trim 0.0 1.00
-ffmpeg -i ` + filepath.Join(dir, "temp-dir", "silence_1s-c8c9dd8.wav") + ` -ab 256k -ar 44100 -ac 2 ` + filepath.Join(dir, "output-dir", "my-file-f4a826f.mp3") + "\n",
+#EXTINF:1,my-file-f37ffc9.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "my-file-f37ffc9.mp3")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "silence_1s-25d89a0.wav") + ` -ab 256k -ar 44100 -ac 2 -id3v2_version 3 -write_xing 1 ` + filepath.Join(dir, "output-dir", "my-file-f37ffc9.tmp.mp3") + "\n",
+		},
+		{
+			name: "loudness normalization",
+			files: []File{
+				{
+					Name:     "my-file",
+					Segments: []Segment{&Silence{Length: 2 * time.Second}},
+				},
+			},
+			loudnessTarget: -16,
+			wantPlaylist: `#EXTM3U
+#EXTINF:1,my-file-11fe4fb.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "my-file-11fe4fb.mp3")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "silence_2s-ed862cd.wav") + ` -af loudnorm=I=-16:TP=-1.5:LRA=11 -ar 22050 ` + filepath.Join(dir, "temp-dir", "loudnorm-ea7febf.tmp.wav") + `
+ffmpeg -i ` + filepath.Join(dir, "temp-dir", "loudnorm-ea7febf.wav") + ` -ab 256k -ar 44100 -ac 2 -id3v2_version 3 -write_xing 1 ` + filepath.Join(dir, "output-dir", "my-file-11fe4fb.tmp.mp3") + `
+ffprobe -v error -show_entries format=duration -of default=noprint_wrappers=1:nokey=1 ` + filepath.Join(dir, "output-dir", "my-file-11fe4fb.mp3") + "\n",
+		},
+		{
+			name: "background music",
+			files: []File{
+				{
+					Name:     "my-file",
+					Segments: []Segment{&Silence{Length: 3 * time.Second}},
+				},
+			},
+			backgroundMusic: &BackgroundMusic{Paths: []string{filepath.Join(dir, "track.mp3")}, Volume: 0.2},
+			wantPlaylist: `#EXTM3U
+#EXTINF:1,my-file-b5b6ae1.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "my-file-b5b6ae1.mp3")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "silence_3s-f98f176.wav") + ` -stream_loop -1 -i ` + filepath.Join(dir, "track.mp3") + ` -filter_complex [1:a]aresample=22050,aformat=channel_layouts=mono,volume=0.2[music];[0:a][music]amix=inputs=2:duration=first:dropout_transition=0:normalize=0 -ar 22050 -ac 1 ` + filepath.Join(dir, "temp-dir", "music-02f50c3.tmp.wav") + `
+ffmpeg -i ` + filepath.Join(dir, "temp-dir", "music-02f50c3.wav") + ` -ab 256k -ar 44100 -ac 2 -id3v2_version 3 -write_xing 1 ` + filepath.Join(dir, "output-dir", "my-file-b5b6ae1.tmp.mp3") + `
+ffprobe -v error -show_entries format=duration -of default=noprint_wrappers=1:nokey=1 ` + filepath.Join(dir, "output-dir", "my-file-b5b6ae1.mp3") + "\n",
+		},
+		{
+			name: "tone",
+			files: []File{
+				{
+					Name:     "my-file",
+					Segments: []Segment{&Tone{Frequency: 880, Length: 200 * time.Millisecond}},
+				},
+			},
+			wantPlaylist: `#EXTM3U
+#EXTINF:0,my-file-ceefb5a.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "my-file-ceefb5a.mp3")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "tone_880Hz_200ms-35a07f5.wav") + ` -ab 256k -ar 44100 -ac 2 -id3v2_version 3 -write_xing 1 ` + filepath.Join(dir, "output-dir", "my-file-ceefb5a.tmp.mp3") + "\n",
+		},
+		{
+			name: "cover",
+			files: []File{
+				{
+					Name:     "my-file",
+					Metadata: Metadata{Cover: filepath.Join(dir, "cover.jpg")},
+					Segments: []Segment{&Silence{Length: 4 * time.Second}},
+				},
+			},
+			wantPlaylist: `#EXTM3U
+#EXTINF:4,my-file-fedc418.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "my-file-fedc418.mp3")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "silence_4s-6e8bc2e.wav") + ` -i ` + filepath.Join(dir, "cover.jpg") + ` -map 0:a -map 1:v -c:v copy -disposition:v attached_pic -ab 256k -ar 44100 -ac 2 -id3v2_version 3 -write_xing 1 ` + filepath.Join(dir, "output-dir", "my-file-fedc418.tmp.mp3") + "\n",
+		},
+		{
+			name: "merged files",
+			files: []File{
+				MergeFiles("workout", Metadata{Title: "Workout"}, []File{
+					{
+						Name:     "pause",
+						Segments: []Segment{&Silence{Length: 2 * time.Second}},
+					},
+					{
+						Name: "exercise",
+						Segments: []Segment{
+							&Tone{Frequency: 880, Length: 200 * time.Millisecond},
+							&Silence{Length: 4 * time.Second},
+						},
+					},
+				}),
+			},
+			wantPlaylist: `#EXTM3U
+#EXTINF:6,workout-767e628.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "workout-767e628.mp3")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "concat-71a422e.wav") + ` -ab 256k -ar 44100 -ac 2 -id3v2_version 3 -write_xing 1 -metadata title=Workout ` + filepath.Join(dir, "output-dir", "workout-767e628.tmp.mp3") + "\n",
+		},
+		{
+			name: "tts tempo",
+			files: []File{
+				{
+					Name:     "my-file",
+					Segments: []Segment{&Text{Value: "Push-Ups"}},
+				},
+			},
+			tempo: 1.2,
+			wantPlaylist: `#EXTM3U
+#EXTINF:1,my-file-067bc21.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "my-file-067bc21.mp3")) + "\n",
+			wantLog: `espeak-ng -v en-GB -out ` + filepath.Join(dir, "temp-dir", "espeak-ng-7ce39e7.tmp.wav") + ` Push-Ups
+sox_ng ` + filepath.Join(dir, "temp-dir", "espeak-ng-7ce39e7.wav") + ` ` + filepath.Join(dir, "temp-dir", "espeak-ng-7ce39e7_tempo-1.2-31b8086.tmp.wav") + ` tempo -s 1.2
+ffmpeg -i ` + filepath.Join(dir, "temp-dir", "espeak-ng-7ce39e7_tempo-1.2-31b8086.wav") + ` -ab 256k -ar 44100 -ac 2 -id3v2_version 3 -write_xing 1 ` + filepath.Join(dir, "output-dir", "my-file-067bc21.tmp.mp3") + `
+ffprobe -v error -show_entries format=duration -of default=noprint_wrappers=1:nokey=1 ` + filepath.Join(dir, "output-dir", "my-file-067bc21.mp3") + "\n",
+		},
+		{
+			name: "sample rate",
+			files: []File{
+				{
+					Name:     "my-file",
+					Segments: []Segment{&Text{Value: "Push-Ups"}},
+				},
+			},
+			sampleRate: 48000,
+			wantPlaylist: `#EXTM3U
+#EXTINF:1,my-file-89e8500.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "my-file-89e8500.mp3")) + "\n",
+			wantLog: `espeak-ng -v en-GB -out ` + filepath.Join(dir, "temp-dir", "espeak-ng-7ce39e7.tmp.wav") + ` Push-Ups
+sox_ng ` + filepath.Join(dir, "temp-dir", "espeak-ng-7ce39e7.wav") + ` -r 48000 ` + filepath.Join(dir, "temp-dir", "espeak-ng-7ce39e7_48000Hz-4bb9e73.tmp.wav") + `
+ffmpeg -i ` + filepath.Join(dir, "temp-dir", "espeak-ng-7ce39e7_48000Hz-4bb9e73.wav") + ` -ab 256k -ar 48000 -ac 2 -id3v2_version 3 -write_xing 1 ` + filepath.Join(dir, "output-dir", "my-file-89e8500.tmp.mp3") + `
+ffprobe -v error -show_entries format=duration -of default=noprint_wrappers=1:nokey=1 ` + filepath.Join(dir, "output-dir", "my-file-89e8500.mp3") + "\n",
+		},
+		{
+			name: "mono",
+			files: []File{
+				{
+					Name:     "my-file",
+					Segments: []Segment{&Silence{Length: 1 * time.Second}},
+				},
+			},
+			channels: 1,
+			wantPlaylist: `#EXTM3U
+#EXTINF:1,my-file-f291ff1.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "my-file-f291ff1.mp3")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "silence_1s-25d89a0.wav") + ` -ab 256k -ar 44100 -ac 1 -id3v2_version 3 -write_xing 1 ` + filepath.Join(dir, "output-dir", "my-file-f291ff1.tmp.mp3") + "\n",
+		},
+		{
+			name: "mono m4a",
+			files: []File{
+				{
+					Name:     "my-file",
+					Segments: []Segment{&Silence{Length: 1 * time.Second}},
+				},
+			},
+			channels: 1,
+			options:  func(o *Options) { o.Format = M4a },
+			// afconvert converts m4a on macOS.
+			skipOn: "darwin",
+			wantPlaylist: `#EXTM3U
+#EXTINF:1,my-file-84119ac.m4a
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "my-file-84119ac.m4a")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "silence_1s-25d89a0.wav") + ` -c:a aac -b:a 256k -ar 44100 -ac 1 -use_editlist 1 -movflags +faststart ` + filepath.Join(dir, "output-dir", "my-file-84119ac.tmp.m4a") + "\n",
+		},
+		{
+			name: "mono m4b",
+			files: []File{
+				{
+					Name:     "my-file",
+					Segments: []Segment{&Silence{Length: 1 * time.Second}},
+				},
+			},
+			channels: 1,
+			options:  func(o *Options) { o.Format = M4b },
+			wantPlaylist: `#EXTM3U
+#EXTINF:1,my-file-e70dd7f.m4b
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "my-file-e70dd7f.m4b")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "silence_1s-25d89a0.wav") + ` -i ` + filepath.Join(dir, "temp-dir", "chapters-8197359.txt") + ` -map_metadata 1 -map_chapters 1 -c:a aac -b:a 256k -ar 44100 -ac 1 -use_editlist 1 -movflags +faststart ` + filepath.Join(dir, "output-dir", "my-file-e70dd7f.tmp.m4b") + "\n",
+		},
+		{
+			name: "replay gain",
+			files: []File{
+				{
+					Name:     "tone",
+					Segments: []Segment{&Tone{Frequency: 1000, Length: 1 * time.Second}},
+				},
+			},
+			replayGain: true,
+			wantPlaylist: `#EXTM3U
+#EXTINF:1,tone-17c268c.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "tone-17c268c.mp3")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "tone_1000Hz_1s-78883ef.wav") + ` -ab 256k -ar 44100 -ac 2 -id3v2_version 3 -write_xing 1 -metadata REPLAYGAIN_TRACK_GAIN=-8.99 dB -metadata REPLAYGAIN_TRACK_PEAK=0.499969 ` + filepath.Join(dir, "output-dir", "tone-17c268c.tmp.mp3") + "\n",
+		},
+		{
+			name: "replay gain of silence",
+			files: []File{
+				{
+					Name:     "silence",
+					Segments: []Segment{&Silence{Length: 1 * time.Second}},
+				},
+			},
+			replayGain: true,
+			wantPlaylist: `#EXTM3U
+#EXTINF:1,silence-abfb28f.mp3
+` + m3u.FileURI(filepath.Join(dir, "output-dir", "silence-abfb28f.mp3")) + "\n",
+			wantLog: `ffmpeg -i ` + filepath.Join(dir, "temp-dir", "silence_1s-25d89a0.wav") + ` -ab 256k -ar 44100 -ac 2 -id3v2_version 3 -write_xing 1 ` + filepath.Join(dir, "output-dir", "silence-abfb28f.tmp.mp3") + "\n",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if tt.skipOn == runtime.GOOS {
+				t.Skip("not supported on " + runtime.GOOS)
+			}
 			buf := &bytes.Buffer{}
 			bufPlaylist := &dummyPlaylist{&bytes.Buffer{}}
-			creator, err := NewFileCreator(
-				ToExecCmdCtx(newDummyCmdExec(buf)),
-				&TTS{
-					TTSCmd: EspeakNG,
-					Voice:  "en-GB",
-				},
-				Mp3,
-				filepath.Join(dir, tempDir),
-				filepath.Join(dir, outputDir),
-				func(name string) (io.WriteCloser, error) {
-					return bufPlaylist, nil
-				},
-			)
+			opts := testOptions(dir, buf)
+			opts.TTS.Tempo = tt.tempo
+			opts.CreatePlaylistFunc = func(name string) (io.WriteCloser, error) {
+				return bufPlaylist, nil
+			}
+			opts.SampleRate = tt.sampleRate
+			opts.Channels = tt.channels
+			opts.ReplayGain = tt.replayGain
+			opts.LoudnessTarget = tt.loudnessTarget
+			opts.BackgroundMusic = tt.backgroundMusic
+			if tt.options != nil {
+				tt.options(&opts)
+			}
+			creator, err := NewFileCreator(opts)
 			if err != nil {
 				t.Fatalf("failed to create audio creator: %v", err)
 			}
@@ -96,3 +335,705 @@ ffmpeg -i ` + filepath.Join(dir, "temp-dir", "silence_1s-c8c9dd8.wav") + ` -ab 2
 		})
 	}
 }
+
+func TestFileCreator_Recording(t *testing.T) {
+	dir := t.TempDir()
+	recording := filepath.Join(dir, "shoulder-roll.wav")
+	err := os.WriteFile(recording, []byte("recording"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write recording: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	opts := testOptions(dir, buf)
+	opts.Recordings = map[string]string{"Shoulder Roll": recording}
+	// The tempo adds a node after the TTS command, which must not be added for a recording.
+	opts.TTS.Tempo = 1.5
+	creator, err := NewFileCreator(opts)
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	files := []File{
+		{
+			Name:     "my-file",
+			Segments: []Segment{&Text{Value: "Shoulder Roll, "}},
+		},
+	}
+	graphCreator, err := NewFileCreator(opts)
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	graph, err := graphCreator.Graph(files, false)
+	if err != nil {
+		t.Fatalf("failed to create graph: %v", err)
+	}
+	if strings.Contains(graph, "espeak-ng") {
+		t.Fatalf("TTS node added for recording:\n%s", graph)
+	}
+	err = creator.BatchCreate(t.Context(), files)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	gotLog := buf.String()
+	if strings.Contains(gotLog, "espeak-ng") {
+		t.Fatalf("TTS used instead of recording:\n%s", gotLog)
+	}
+	if !strings.Contains(gotLog, "sox_ng "+recording+" -r 22050 -c 1 ") {
+		t.Fatalf("recording not converted:\n%s", gotLog)
+	}
+}
+
+func TestFileCreator_Graph(t *testing.T) {
+	dir := t.TempDir()
+	buf := &bytes.Buffer{}
+	creator, err := NewFileCreator(testOptions(dir, buf))
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	files := []File{
+		{
+			Name:     "my-file",
+			Segments: []Segment{&Text{Value: "Shoulder Roll, "}},
+		},
+	}
+	got, err := creator.Graph(files, true)
+	if err != nil {
+		t.Fatalf("failed to create graph: %v", err)
+	}
+	if buf.Len() > 0 {
+		t.Fatalf("commands executed:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(got, "flowchart TD\n") || !strings.Contains(got, "espeak-ng") || !strings.Contains(got, " --> ") {
+		t.Fatalf("unexpected graph:\n%s", got)
+	}
+}
+
+func TestFileCreator_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, outputDir, "stale-1234567.mp3")
+	err := os.MkdirAll(filepath.Dir(stale), 0o700)
+	if err != nil {
+		t.Fatalf("failed to create output dir: %v", err)
+	}
+	err = os.WriteFile(stale, []byte("stale"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	creator, err := NewFileCreator(testOptions(dir, buf))
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	got, err := creator.DryRun([]File{
+		{
+			Name:     "my-file",
+			Segments: []Segment{&Text{Value: "Shoulder Roll, "}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to dry run: %v", err)
+	}
+	if buf.Len() > 0 {
+		t.Fatalf("commands executed:\n%s", buf.String())
+	}
+	if len(got.Created) != 1 || !strings.HasPrefix(filepath.Base(got.Created[0]), "my-file-") {
+		t.Fatalf("created: got %v", got.Created)
+	}
+	if len(got.Removed) != 1 || got.Removed[0] != stale {
+		t.Fatalf("removed: want %v, got %v", stale, got.Removed)
+	}
+	if got.Commands == 0 {
+		t.Fatalf("commands: want more than 0, got %d", got.Commands)
+	}
+}
+
+func TestFileCreator_Force(t *testing.T) {
+	dir := t.TempDir()
+	newCreator := func(force bool) *FileCreator {
+		opts := testOptions(dir, &bytes.Buffer{})
+		opts.Force = force
+		creator, err := NewFileCreator(opts)
+		if err != nil {
+			t.Fatalf("failed to create audio creator: %v", err)
+		}
+		return creator
+	}
+	files := []File{
+		{
+			Name:     "my-file",
+			Segments: []Segment{&Text{Value: "Shoulder Roll, "}},
+		},
+	}
+
+	d, err := newCreator(false).DryRun(files)
+	if err != nil {
+		t.Fatalf("failed to dry run: %v", err)
+	}
+	err = os.WriteFile(d.Created[0], []byte("existing"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	d, err = newCreator(false).DryRun(files)
+	if err != nil {
+		t.Fatalf("failed to dry run: %v", err)
+	}
+	if len(d.Existing) != 1 {
+		t.Fatalf("existing: want 1 file, got %v", d.Existing)
+	}
+	d, err = newCreator(true).DryRun(files)
+	if err != nil {
+		t.Fatalf("failed to dry run: %v", err)
+	}
+	if len(d.Created) != 1 || len(d.Existing) != 0 {
+		t.Fatalf("forced: want 1 created file, got %+v", d)
+	}
+}
+
+func TestFileCreator_OnFile(t *testing.T) {
+	dir := t.TempDir()
+	creator, err := NewFileCreator(testOptions(dir, &bytes.Buffer{}))
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	var states []string
+	creator.OnFile(func(s FileStatus) {
+		states = append(states, s.State)
+	})
+	err = creator.BatchCreate(t.Context(), []File{
+		{
+			Name:     "my-file",
+			Segments: []Segment{&Text{Value: "Shoulder Roll, "}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	want := []string{"queued", "created"}
+	if !slices.Equal(states, want) {
+		t.Fatalf("want %v, got %v", want, states)
+	}
+}
+
+func TestFileCreator_Outputs(t *testing.T) {
+	dir := t.TempDir()
+	creator, err := NewFileCreator(testOptions(dir, &bytes.Buffer{}))
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	err = creator.BatchCreate(t.Context(), []File{
+		{
+			Name:     "01-pause",
+			Metadata: Metadata{Title: "Pause"},
+			Segments: []Segment{&Silence{Length: 2 * time.Second}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, outputDir, outputsFile))
+	if err != nil {
+		t.Fatalf("failed to read outputs: %v", err)
+	}
+	var got outputsJSON
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("failed to unmarshal outputs: %v", err)
+	}
+	if len(got.Files) != 1 {
+		t.Fatalf("want 1 file, got %v", got.Files)
+	}
+	entry := got.Files[0]
+	want := outputEntry{
+		File:      "01-pause-" + entry.Hash + ".mp3",
+		Name:      "01-pause",
+		Title:     "Pause",
+		Duration:  2,
+		Hash:      entry.Hash,
+		Operation: "created",
+	}
+	if len(entry.Hash) != 7 || entry != want {
+		t.Fatalf("want %+v, got %+v", want, entry)
+	}
+}
+
+func TestFileCreator_Playlists(t *testing.T) {
+	dir := t.TempDir()
+	playlists := make(map[string]*bytes.Buffer)
+	opts := testOptions(dir, &bytes.Buffer{})
+	opts.CreatePlaylistFunc = func(name string) (io.WriteCloser, error) {
+		buf := &bytes.Buffer{}
+		playlists[filepath.Base(name)] = buf
+		return &dummyPlaylist{buf}, nil
+	}
+	opts.Playlists = Playlists{Name: "legs"}
+	creator, err := NewFileCreator(opts)
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	err = creator.BatchCreate(t.Context(), []File{
+		{
+			Name:     "01-pause",
+			Segments: []Segment{&Silence{Length: 2 * time.Second}},
+		},
+		{
+			Name:      "02-squats",
+			Segments:  []Segment{&Silence{Length: 3 * time.Second}},
+			Playlists: []string{"legs-exercises"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	if len(playlists) != 2 {
+		t.Fatalf("want playlists legs.m3u and legs-exercises.m3u, got %v", slices.Collect(maps.Keys(playlists)))
+	}
+	all := playlists["legs.m3u"].String()
+	if !strings.Contains(all, "01-pause-") || !strings.Contains(all, "02-squats-") {
+		t.Fatalf("want both files in legs.m3u, got:\n%s", all)
+	}
+	exercises := playlists["legs-exercises.m3u"].String()
+	if strings.Contains(exercises, "01-pause-") || !strings.Contains(exercises, "02-squats-") {
+		t.Fatalf("want only 02-squats in legs-exercises.m3u, got:\n%s", exercises)
+	}
+}
+
+func TestFileCreator_SoundsDir(t *testing.T) {
+	dir := t.TempDir()
+	soundsDir := filepath.Join(dir, "sounds")
+	err := os.Mkdir(soundsDir, 0o700)
+	if err != nil {
+		t.Fatalf("failed to create sounds dir: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(soundsDir, "start.wav"), []byte("start"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write sound: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	opts := testOptions(dir, buf)
+	opts.SoundsDir = soundsDir
+	creator, err := NewFileCreator(opts)
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	err = creator.BatchCreate(t.Context(), []File{
+		{
+			Name:     "start",
+			Segments: []Segment{&Sound{Filename: "start.wav"}},
+		},
+		{
+			Name:     "success",
+			Segments: []Segment{&Sound{Filename: "success.wav"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create files: %v", err)
+	}
+	gotLog := buf.String()
+	if !strings.Contains(gotLog, "sox_ng "+filepath.Join(soundsDir, "start.wav")+" -r 22050 -c 1 ") {
+		t.Fatalf("user sound not converted:\n%s", gotLog)
+	}
+	if !strings.Contains(gotLog, "ffmpeg -i "+filepath.Join(dir, tempDir, "success-2b4a331.wav")+" ") {
+		t.Fatalf("embedded sound not used:\n%s", gotLog)
+	}
+}
+
+func TestFileCreator_SoundFormat(t *testing.T) {
+	dir := t.TempDir()
+	buf := &bytes.Buffer{}
+	opts := testOptions(dir, buf)
+	opts.SampleRate = 48000
+	creator, err := NewFileCreator(opts)
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	err = creator.BatchCreate(t.Context(), []File{
+		{
+			Name:     "success",
+			Segments: []Segment{&Sound{Filename: "success.wav"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	gotLog := buf.String()
+	if !strings.Contains(gotLog, "sox_ng "+filepath.Join(dir, tempDir, "success-2b4a331.wav")+" -r 48000 -c 1 ") {
+		t.Fatalf("sound not converted to the pipeline format:\n%s", gotLog)
+	}
+}
+
+func TestFileCreator_M4bChapters(t *testing.T) {
+	dir := t.TempDir()
+	buf := &bytes.Buffer{}
+	opts := testOptions(dir, buf)
+	opts.Format = M4b
+	creator, err := NewFileCreator(opts)
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	err = creator.BatchCreate(t.Context(), []File{
+		MergeFiles("workout", Metadata{}, []File{
+			{
+				Name:     "pause",
+				Metadata: Metadata{Title: "Pause = Rest"},
+				Segments: []Segment{&Silence{Length: 2 * time.Second}},
+			},
+			{
+				Name:     "exercise",
+				Segments: []Segment{&Silence{Length: 1500 * time.Millisecond}},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if !strings.Contains(buf.String(), " -map_metadata 1 -map_chapters 1 -c:a aac ") {
+		t.Fatalf("chapters not mapped:\n%s", buf.String())
+	}
+
+	chapterFiles, err := filepath.Glob(filepath.Join(dir, tempDir, "chapters-*.txt"))
+	if err != nil || len(chapterFiles) != 1 {
+		t.Fatalf("chapters file not found: %v %v", chapterFiles, err)
+	}
+	got, err := os.ReadFile(chapterFiles[0])
+	if err != nil {
+		t.Fatalf("failed to read chapters: %v", err)
+	}
+	want := `;FFMETADATA1
+[CHAPTER]
+TIMEBASE=1/1000
+START=0
+END=2000
+title=Pause \= Rest
+[CHAPTER]
+TIMEBASE=1/1000
+START=2000
+END=3500
+title=exercise
+`
+	if string(got) != want {
+		t.Fatalf("\ngot\n%s\nwant\n%s\n", got, want)
+	}
+}
+
+func TestFileCreator_CueSheetAndTimeline(t *testing.T) {
+	dir := t.TempDir()
+	creator, err := NewFileCreator(testOptions(dir, &bytes.Buffer{}))
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	err = creator.BatchCreate(t.Context(), []File{
+		MergeFiles("workout", Metadata{Album: "My \"Workout\"", Artist: "w2a"}, []File{
+			{
+				Name:     "pause",
+				Metadata: Metadata{Title: "Pause"},
+				Segments: []Segment{&Silence{Length: 2 * time.Second}},
+			},
+			{
+				Name:     "exercise",
+				Segments: []Segment{&Silence{Length: 61500 * time.Millisecond}},
+			},
+			{
+				Name:     "end",
+				Segments: []Segment{&Silence{Length: time.Second}},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	cueFiles, err := filepath.Glob(filepath.Join(dir, outputDir, "workout-*.cue"))
+	if err != nil || len(cueFiles) != 1 {
+		t.Fatalf("cue sheet not found: %v %v", cueFiles, err)
+	}
+	got, err := os.ReadFile(cueFiles[0])
+	if err != nil {
+		t.Fatalf("failed to read cue sheet: %v", err)
+	}
+	want := `PERFORMER "w2a"
+TITLE "My 'Workout'"
+FILE "` + strings.TrimSuffix(filepath.Base(cueFiles[0]), ".cue") + `.mp3" MP3
+  TRACK 01 AUDIO
+    TITLE "Pause"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "exercise"
+    INDEX 01 00:02:00
+  TRACK 03 AUDIO
+    TITLE "end"
+    INDEX 01 01:03:37
+`
+	if string(got) != want {
+		t.Fatalf("\ngot\n%s\nwant\n%s\n", got, want)
+	}
+
+	got, err = os.ReadFile(filepath.Join(dir, outputDir, timelineFile))
+	if err != nil {
+		t.Fatalf("failed to read timeline: %v", err)
+	}
+	want = `;FFMETADATA1
+[CHAPTER]
+TIMEBASE=1/1000
+START=0
+END=2000
+title=Pause
+[CHAPTER]
+TIMEBASE=1/1000
+START=2000
+END=63500
+title=exercise
+[CHAPTER]
+TIMEBASE=1/1000
+START=63500
+END=64500
+title=end
+`
+	if string(got) != want {
+		t.Fatalf("\ngot\n%s\nwant\n%s\n", got, want)
+	}
+}
+
+func TestFileCreator_Captions(t *testing.T) {
+	dir := t.TempDir()
+	// espeak-ng writes a wav file of 1.5s for every text.
+	execCmd := func(ctx context.Context, cmd string, args ...string) dummyCmd {
+		if i := slices.Index(args, "-out"); cmd == "espeak-ng" && i >= 0 {
+			f, err := os.Create(args[i+1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			err = wav.WriteSilence(f, wav.Mono(DefaultSampleRate), 1500*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return newDummyCmdExec(&bytes.Buffer{})(ctx, cmd, args...)
+	}
+	opts := testOptions(dir, &bytes.Buffer{})
+	opts.ExecCmdCtx = ToExecCmdCtx(execCmd)
+	opts.Captions = Captions{LRC: true, VTT: true}
+	creator, err := NewFileCreator(opts)
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	err = creator.BatchCreate(t.Context(), []File{
+		{
+			Name:     "01-squats",
+			Metadata: Metadata{Title: "Squats", Album: "[Legs]", Artist: "w2a"},
+			Segments: []Segment{
+				&Text{Value: "Squats"},
+				&Silence{Length: 2 * time.Second},
+				&Group{Segments: []Segment{
+					&Text{Value: "Keep your back straight, "},
+					&Silence{Length: time.Second},
+					&Text{Value: "Breathe, "},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	lrcFiles, err := filepath.Glob(filepath.Join(dir, outputDir, "01-squats-*.lrc"))
+	if err != nil || len(lrcFiles) != 1 {
+		t.Fatalf("lrc file not found: %v %v", lrcFiles, err)
+	}
+	got, err := os.ReadFile(lrcFiles[0])
+	if err != nil {
+		t.Fatalf("failed to read lrc file: %v", err)
+	}
+	want := `[ti:Squats]
+[ar:w2a]
+[al:[Legs)]
+[length:00:07]
+[00:00.00]Squats
+[00:03.50]Keep your back straight
+[00:06.00]Breathe
+`
+	if string(got) != want {
+		t.Fatalf("\ngot\n%s\nwant\n%s\n", got, want)
+	}
+
+	got, err = os.ReadFile(strings.TrimSuffix(lrcFiles[0], ".lrc") + ".vtt")
+	if err != nil {
+		t.Fatalf("failed to read vtt file: %v", err)
+	}
+	want = `WEBVTT
+
+00:00:00.000 --> 00:00:01.500
+Squats
+
+00:00:03.500 --> 00:00:05.000
+Keep your back straight
+
+00:00:06.000 --> 00:00:07.500
+Breathe
+`
+	if string(got) != want {
+		t.Fatalf("\ngot\n%s\nwant\n%s\n", got, want)
+	}
+}
+
+func TestFileCreator_GroupTrim(t *testing.T) {
+	// espeak-ng writes a wav file of 1.5s for every text.
+	execCmd := func(ctx context.Context, cmd string, args ...string) dummyCmd {
+		if i := slices.Index(args, "-out"); cmd == "espeak-ng" && i >= 0 {
+			f, err := os.Create(args[i+1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			err = wav.WriteSilence(f, wav.Mono(DefaultSampleRate), 1500*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return newDummyCmdExec(&bytes.Buffer{})(ctx, cmd, args...)
+	}
+	tests := []struct {
+		name string
+		trim bool
+		want time.Duration
+	}{
+		{name: "trimmed", trim: true, want: 3 * time.Second},
+		// The beep and the number are longer than the group.
+		{name: "padded", want: 3 * 1700 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := testOptions(dir, &bytes.Buffer{})
+			opts.ExecCmdCtx = ToExecCmdCtx(execCmd)
+			opts.Format = Wav
+			creator, err := NewFileCreator(opts)
+			if err != nil {
+				t.Fatalf("failed to create audio creator: %v", err)
+			}
+			var countdown []Segment
+			for _, number := range []string{"3", "2", "1"} {
+				countdown = append(countdown, &Group{
+					Segments: []Segment{&Tone{Frequency: 880, Length: 200 * time.Millisecond}, &Text{Value: number}},
+					Length:   time.Second,
+					Trim:     tt.trim,
+				})
+			}
+			err = creator.BatchCreate(t.Context(), []File{{Name: "countdown", Segments: countdown}})
+			if err != nil {
+				t.Fatalf("failed to create file: %v", err)
+			}
+
+			wavFiles, err := filepath.Glob(filepath.Join(dir, outputDir, "countdown-*.wav"))
+			if err != nil || len(wavFiles) != 1 {
+				t.Fatalf("wav file not found: %v %v", wavFiles, err)
+			}
+			got, err := wav.FileDuration(wavFiles[0])
+			if err != nil {
+				t.Fatalf("failed to read duration: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("duration: want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRemoveOtherFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	for _, name := range []string{"keep.mp3", "old.mp3", "out", "keep.m3u", "old.m3u", ".hidden", "workout/01-squats.mp3"} {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, nil, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := removeOtherFiles(dir, map[string]bool{
+		filepath.Join(dir, "keep.mp3"): true,
+		filepath.Join(dir, "keep.m3u"): true,
+	})
+	if err != nil {
+		t.Fatalf("removeOtherFiles(): %v", err)
+	}
+	for name, want := range map[string]bool{
+		"keep.mp3":              true,
+		"old.mp3":               false,
+		"out":                   false,
+		"keep.m3u":              true,
+		"old.m3u":               false,
+		".hidden":               true,
+		"workout/01-squats.mp3": true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMetadata_ffmpegArgs(t *testing.T) {
+	m := Metadata{
+		Title:      "Push-Ups",
+		Album:      "Morning Workout",
+		Artist:     "w2a",
+		Comment:    "12 exercises",
+		Track:      3,
+		TrackTotal: 30,
+	}
+	want := []string{
+		"-metadata", "title=Push-Ups",
+		"-metadata", "album=Morning Workout",
+		"-metadata", "artist=w2a",
+		"-metadata", "comment=12 exercises",
+		"-metadata", "track=3/30",
+	}
+	if got := m.ffmpegArgs(); !slices.Equal(got, want) {
+		t.Fatalf("ffmpegArgs() = %v, want %v", got, want)
+	}
+	if got := (Metadata{}).ffmpegArgs(); len(got) != 0 {
+		t.Fatalf("ffmpegArgs() of empty metadata = %v, want none", got)
+	}
+}
+
+func TestFileCreator_OnPrograms(t *testing.T) {
+	dir := t.TempDir()
+	var log bytes.Buffer
+	opts := testOptions(dir, &log)
+	// The tempo is changed by sox_ng.
+	opts.TTS.Tempo = 1.2
+	creator, err := NewFileCreator(opts)
+	if err != nil {
+		t.Fatalf("failed to create audio creator: %v", err)
+	}
+	var got []string
+	errMissing := errors.New("sox_ng missing")
+	creator.OnPrograms(func(programs []string) error {
+		got = programs
+		return errMissing
+	})
+	err = creator.BatchCreate(t.Context(), []File{
+		{
+			Name:     "my-file",
+			Segments: []Segment{&Text{Value: "Squats", Length: 3 * time.Second}},
+		},
+	})
+	if !errors.Is(err, errMissing) {
+		t.Fatalf("BatchCreate() error = %v, want %v", err, errMissing)
+	}
+	want := []string{"espeak-ng", "ffmpeg", "sox_ng"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("programs = %v, want %v", got, want)
+	}
+	if log.Len() > 0 {
+		t.Fatalf("commands ran before the programs were checked:\n%s", log.String())
+	}
+}
@@ -14,20 +14,41 @@ const (
 	M4a Format = iota
 	Mp3
 	Wav
+	Opus
+	Ogg
+	M4b
 	Unknown
 )
 
+// defaultBitrate is used for lossy formats encoded with ffmpeg if no bitrate is set.
+func (a Format) defaultBitrate() string {
+	switch a {
+	case Opus:
+		// Opus is transparent for speech at low bitrates.
+		return "64k"
+	case Ogg:
+		return "128k"
+	default:
+		return "256k"
+	}
+}
+
 func (a *Format) UnmarshalYAML(node *yaml.Node) error {
 	var y string
 	err := node.Decode(&y)
 	if err != nil {
 		return err
 	}
+	*a, err = ParseFormat(y)
+	return err
+}
+
+// ParseFormat returns the format with the case-insensitive name, e.g. 'mp3'.
+func ParseFormat(name string) (Format, error) {
 	for i := range Unknown {
-		if strings.EqualFold(i.String(), y) {
-			*a = i
-			return nil
+		if strings.EqualFold(i.String(), name) {
+			return i, nil
 		}
 	}
-	return fmt.Errorf("unknown audio format '%s'", y)
+	return Unknown, fmt.Errorf("unknown audio format '%s'", name)
 }
@@ -11,11 +11,15 @@ func _() {
 	_ = x[M4a-0]
 	_ = x[Mp3-1]
 	_ = x[Wav-2]
+	_ = x[Opus-3]
+	_ = x[Ogg-4]
+	_ = x[M4b-5]
+	_ = x[Unknown-6]
 }
 
-const _Format_name = "M4aMp3Wav"
+const _Format_name = "M4aMp3WavOpusOggM4bUnknown"
 
-var _Format_index = [...]uint8{0, 3, 6, 9}
+var _Format_index = [...]uint8{0, 3, 6, 9, 13, 16, 19, 26}
 
 func (i Format) String() string {
 	if i < 0 || i >= Format(len(_Format_index)-1) {
@@ -19,6 +19,9 @@ func TestFormat_Unmarshal(t *testing.T) {
 		{"format: m4a", M4a, false},
 		{"format: mp3", Mp3, false},
 		{"format: wav", Wav, false},
+		{"format: opus", Opus, false},
+		{"format: ogg", Ogg, false},
+		{"format: m4b", M4b, false},
 		{"format: flac", Unknown, true},
 	}
 
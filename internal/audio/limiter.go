@@ -0,0 +1,91 @@
+package audio
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter caps the number of concurrent commands and the commands started per second.
+type limiter struct {
+	sem      chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// newLimiter returns a limiter. Zero values mean no limit.
+func newLimiter(maxConcurrent int, requestsPerSecond float64) *limiter {
+	l := &limiter{}
+	if maxConcurrent > 0 {
+		l.sem = make(chan struct{}, maxConcurrent)
+	}
+	if requestsPerSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return l
+}
+
+// acquire blocks until a command is allowed to start. Call release after the command finished.
+func (l *limiter) acquire(ctx context.Context) (release func(), err error) {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		if l.sem != nil {
+			<-l.sem
+		}
+	}
+
+	if l.interval > 0 {
+		l.mu.Lock()
+		now := time.Now()
+		start := l.next
+		if start.Before(now) {
+			start = now
+		}
+		l.next = start.Add(l.interval)
+		l.mu.Unlock()
+
+		timer := time.NewTimer(start.Sub(now))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// limit wraps execCmdCtx so that every command waits for the limiter.
+func (l *limiter) limit(execCmdCtx ExecCmdCtx) ExecCmdCtx {
+	return func(ctx context.Context, name string, args ...string) Cmd {
+		return &limitedCmd{
+			ctx:     ctx,
+			limiter: l,
+			cmd:     execCmdCtx(ctx, name, args...),
+		}
+	}
+}
+
+type limitedCmd struct {
+	ctx     context.Context
+	limiter *limiter
+	cmd     Cmd
+}
+
+func (c *limitedCmd) CombinedOutput() ([]byte, error) {
+	release, err := c.limiter.acquire(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.cmd.CombinedOutput()
+}
@@ -0,0 +1,56 @@
+package audio
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiter_MaxConcurrent(t *testing.T) {
+	l := newLimiter(2, 0)
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			release, err := l.acquire(t.Context())
+			if err != nil {
+				t.Errorf("acquire(): %v", err)
+				return
+			}
+			defer release()
+			n := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+		})
+	}
+	wg.Wait()
+
+	if got := maxRunning.Load(); got > 2 {
+		t.Fatalf("max concurrent commands: want <= 2, got %d", got)
+	}
+}
+
+func TestLimiter_RequestsPerSecond(t *testing.T) {
+	l := newLimiter(0, 100)
+
+	start := time.Now()
+	for range 5 {
+		release, err := l.acquire(t.Context())
+		if err != nil {
+			t.Fatalf("acquire(): %v", err)
+		}
+		release()
+	}
+	// The first request starts immediately, the next four wait 10ms each.
+	if got := time.Since(start); got < 40*time.Millisecond {
+		t.Fatalf("requests per second not limited: 5 requests took %s", got)
+	}
+}
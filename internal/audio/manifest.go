@@ -0,0 +1,194 @@
+package audio
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrHashCollision is wrapped by the errors of files whose hash is the same as the hash of
+// a file with other inputs. A longer hash length avoids the collision.
+var ErrHashCollision = errors.New("hash collision, increase the hash length")
+
+// manifestFile is stored in the temp dir. The leading '.' excludes it from listFilePaths.
+const manifestFile = ".manifest.json"
+
+// checkpointInterval is the minimum time between two saves while files are recorded.
+// If a run crashes, only the files created since the last save are created again.
+const checkpointInterval = time.Second
+
+// manifest records the files created in the temp and output dir.
+// A file is only reused if it is recorded with its current modification time,
+// so files of an aborted run or files changed by hand are created again.
+type manifest struct {
+	path string
+	// saveLock serializes writing the manifest file.
+	saveLock sync.Mutex
+
+	lock    sync.Mutex
+	entries map[string]manifestEntry
+	// saved is the time of the last checkpoint.
+	saved time.Time
+}
+
+type manifestEntry struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+	// Command created the file. It contains the inputs, e.g. the TTS engine, voice and text.
+	// It is empty for files found by walking the directories.
+	Command string `json:"command,omitempty"`
+	// Digest is the full digest the hash is shortened from. It is empty if unknown.
+	Digest  string    `json:"digest,omitempty"`
+	ModTime time.Time `json:"mtime"`
+	// Used is the time of the last run which needed the file.
+	Used time.Time `json:"used,omitzero"`
+}
+
+type manifestJSON struct {
+	Files []manifestEntry `json:"files"`
+}
+
+// loadManifest reads the manifest of the temp dir. If there is none or it is unreadable,
+// the files of the temp and output dir are recorded instead.
+func loadManifest(tempDir string, outputDir string) (*manifest, error) {
+	m := &manifest{
+		path:    filepath.Join(tempDir, manifestFile),
+		entries: make(map[string]manifestEntry),
+	}
+	data, err := os.ReadFile(m.path)
+	if err == nil {
+		var j manifestJSON
+		err = json.Unmarshal(data, &j)
+		if err == nil {
+			for _, e := range j.Files {
+				m.entries[e.Path] = e
+			}
+			return m, nil
+		}
+		slog.Warn("manifest unreadable, directories are used\t", "path", m.path, "err", err)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	existing, err := allFilePaths(tempDir, outputDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, paths := range existing {
+		for p := range paths {
+			m.add(p, "", "")
+		}
+	}
+	return m, nil
+}
+
+// existingFiles returns the recorded files which are unchanged, grouped by hash.
+// Missing and changed files are removed from the manifest.
+func (m *manifest) existingFiles() map[string]map[string]bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	existing := make(map[string]map[string]bool)
+	for p, e := range m.entries {
+		info, err := os.Stat(p)
+		if err != nil || !info.ModTime().Equal(e.ModTime) {
+			delete(m.entries, p)
+			continue
+		}
+		if existing[e.Hash] == nil {
+			existing[e.Hash] = make(map[string]bool)
+		}
+		existing[e.Hash][p] = true
+	}
+	return existing
+}
+
+// record adds the file at path created by command and saves the manifest
+// if the last checkpoint is older than checkpointInterval. A nil manifest records nothing.
+func (m *manifest) record(path string, command string, digest string) {
+	if m == nil {
+		return
+	}
+	checkpoint := m.add(path, command, digest)
+	m.use(path)
+	if !checkpoint {
+		return
+	}
+	err := m.save()
+	if err != nil {
+		slog.Warn("manifest checkpoint failed\t", "path", m.path, "err", err)
+	}
+}
+
+// add adds the file at path created by command and reports whether a checkpoint is due.
+func (m *manifest) add(path string, command string, digest string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		slog.Debug("file not recorded in manifest", "path", path, "err", err)
+		return false
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.entries[path] = manifestEntry{
+		Path:    path,
+		Hash:    extractHash(filepath.Base(path)),
+		Command: command,
+		Digest:  digest,
+		ModTime: info.ModTime(),
+	}
+	if time.Since(m.saved) < checkpointInterval {
+		return false
+	}
+	m.saved = time.Now()
+	return true
+}
+
+// checkDigest fails if the file at path was created from other inputs than digest,
+// i.e. the shortened hashes collide. Files and nodes without digest are not checked.
+func (m *manifest) checkDigest(path string, digest string) error {
+	if m == nil || digest == "" {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	e, ok := m.entries[path]
+	if !ok || e.Digest == "" || e.Digest == digest {
+		return nil
+	}
+	return fmt.Errorf("%w: %s was created by %s", ErrHashCollision, path, cmp.Or(e.Command, "other inputs"))
+}
+
+// save writes the manifest sorted by path. A nil manifest is not written.
+func (m *manifest) save() error {
+	if m == nil {
+		return nil
+	}
+	m.saveLock.Lock()
+	defer m.saveLock.Unlock()
+
+	m.lock.Lock()
+	j := manifestJSON{Files: make([]manifestEntry, 0, len(m.entries))}
+	for _, p := range slices.Sorted(maps.Keys(m.entries)) {
+		j.Files = append(j.Files, m.entries[p])
+	}
+	m.lock.Unlock()
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := m.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0o600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, m.path)
+}
@@ -0,0 +1,151 @@
+package audio
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/wav"
+)
+
+func TestManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	outputDir := t.TempDir()
+	walked := filepath.Join(tempDir, "walked-1234567.wav")
+	created := filepath.Join(tempDir, "created-abcdef0.wav")
+	changed := filepath.Join(outputDir, "changed-7654321.mp3")
+	for _, p := range []string{walked, created, changed} {
+		err := os.WriteFile(p, []byte(p), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	// Without manifest the directories are walked.
+	m, err := loadManifest(tempDir, outputDir)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if got := m.existingFiles(); !got["1234567"][walked] || !got["7654321"][changed] {
+		t.Fatalf("walked files missing: %v", got)
+	}
+	m.record(created, "sox_ng created", "")
+	err = m.save()
+	if err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	// A file not created by a recorded command is not trusted.
+	unknown := filepath.Join(tempDir, "unknown-0000000.wav")
+	err = os.WriteFile(unknown, []byte("partial"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	later := time.Now().Add(time.Hour)
+	err = os.Chtimes(changed, later, later)
+	if err != nil {
+		t.Fatalf("failed to change mtime: %v", err)
+	}
+
+	m, err = loadManifest(tempDir, outputDir)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	got := m.existingFiles()
+	if !got["1234567"][walked] || !got["abcdef0"][created] {
+		t.Fatalf("recorded files missing: %v", got)
+	}
+	if got["0000000"] != nil {
+		t.Fatalf("unrecorded file used: %v", got)
+	}
+	if got["7654321"] != nil {
+		t.Fatalf("changed file used: %v", got)
+	}
+	if cmd := m.entries[created].Command; cmd != "sox_ng created" {
+		t.Fatalf("command: want %q, got %q", "sox_ng created", cmd)
+	}
+}
+
+func TestManifest_Checkpoint(t *testing.T) {
+	tempDir := t.TempDir()
+	created := filepath.Join(tempDir, "created-abcdef0.wav")
+
+	m, err := loadManifest(tempDir, t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	err = os.WriteFile(created, []byte("created"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	m.record(created, "sox_ng created", "")
+
+	// The recorded file is reused without save, e.g. after a crash.
+	m, err = loadManifest(tempDir, t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if got := m.existingFiles(); !got["abcdef0"][created] {
+		t.Fatalf("checkpointed file missing: %v", got)
+	}
+}
+
+func TestUseExistingFile_HashCollision(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "say-abcdef0.wav")
+	err := os.WriteFile(path, []byte("hello"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	m, err := loadManifest(tempDir, tempDir)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	m.record(path, "say hello", digest("tts", "hello"))
+	existing := m.existingFiles()
+
+	op, err := useExistingFile(existing, m, "say-abcdef0.wav", digest("tts", "hello"))
+	if err != nil || op != exists {
+		t.Fatalf("useExistingFile() = %v, %v, want exists", op, err)
+	}
+	// The file of another text with the same hash is not reused.
+	_, err = useExistingFile(existing, m, "say-abcdef0.wav", digest("tts", "goodbye"))
+	if !errors.Is(err, ErrHashCollision) {
+		t.Fatalf("useExistingFile() error = %v, want %v", err, ErrHashCollision)
+	}
+	_, err = useExistingFile(existing, m, "loudnorm-abcdef0.wav", digest("ffmpeg", "loudnorm"))
+	if !errors.Is(err, ErrHashCollision) {
+		t.Fatalf("useExistingFile() error = %v, want %v", err, ErrHashCollision)
+	}
+	if _, err = os.Stat(filepath.Join(tempDir, "loudnorm-abcdef0.wav")); err == nil {
+		t.Fatal("colliding file copied")
+	}
+}
+
+func TestFileCacheBuilder_HashCollision(t *testing.T) {
+	tests := []struct {
+		name    string
+		hashLen int
+		wantErr bool
+	}{
+		// 17 silences have at least two equal hashes with one hex character.
+		{name: "short hash", hashLen: 1, wantErr: true},
+		{name: "default hash", hashLen: DefaultHashLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFileCacheBuilder(nil, nil, t.TempDir(), tt.hashLen)
+			for i := range 17 {
+				f.silence(f.dir, wav.Mono(DefaultSampleRate), time.Duration(i+1)*time.Second)
+			}
+			// The same node twice is no collision.
+			f.silence(f.dir, wav.Mono(DefaultSampleRate), time.Second)
+			err := f.collision()
+			if errors.Is(err, ErrHashCollision) != tt.wantErr {
+				t.Fatalf("collision() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package audio
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/dag"
+)
+
+// slowestTTSLen is the count of TTS commands in Metrics.SlowestTTS.
+const slowestTTSLen = 5
+
+// Metrics summarizes the nodes run by BatchCreate.
+type Metrics struct {
+	// Commands holds the run time per command, e.g. sox_ng, slowest first.
+	// Nodes with an existing file are not included.
+	Commands []CommandMetrics
+	// SlowestTTS are the slowest TTS commands, slowest first.
+	SlowestTTS []NodeMetrics
+	// Cached counts the nodes whose file already existed.
+	Cached int
+	// Total counts all finished nodes.
+	Total int
+}
+
+type CommandMetrics struct {
+	Command  string
+	Count    int
+	Duration time.Duration
+}
+
+type NodeMetrics struct {
+	Name     string
+	Duration time.Duration
+}
+
+// CacheHitRatio returns the share of nodes whose file already existed.
+func (m *Metrics) CacheHitRatio() float64 {
+	if m.Total == 0 {
+		return 0
+	}
+	return float64(m.Cached) / float64(m.Total)
+}
+
+func (m *Metrics) String() string {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("cache hits: %d/%d (%.0f%%)\n", m.Cached, m.Total, m.CacheHitRatio()*100))
+	for _, c := range m.Commands {
+		b.WriteString(fmt.Sprintf("%-10s %4dx %10s\n", c.Command, c.Count, c.Duration.Round(time.Millisecond)))
+	}
+	if len(m.SlowestTTS) > 0 {
+		b.WriteString("slowest tts:\n")
+	}
+	for _, n := range m.SlowestTTS {
+		b.WriteString(fmt.Sprintf("%10s %s\n", n.Duration.Round(time.Millisecond), n.Name))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// metricsCollector collects the finished events of the dag.
+type metricsCollector struct {
+	lock   sync.Mutex
+	nodes  []NodeMetrics
+	cached int
+}
+
+func (c *metricsCollector) add(e dag.Event) {
+	if !e.Finished || e.Err != nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e.Skipped {
+		c.cached++
+		return
+	}
+	c.nodes = append(c.nodes, NodeMetrics{Name: e.Name, Duration: e.Duration})
+}
+
+// cachedNodes counts the finished nodes whose file already existed.
+func (c *metricsCollector) cachedNodes() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cached
+}
+
+func (c *metricsCollector) metrics() *Metrics {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	m := &Metrics{
+		Cached: c.cached,
+		Total:  c.cached + len(c.nodes),
+	}
+	byCommand := make(map[string]*CommandMetrics)
+	for _, n := range c.nodes {
+		command, _, _ := strings.Cut(n.Name, " ")
+		cm, ok := byCommand[command]
+		if !ok {
+			cm = &CommandMetrics{Command: command}
+			byCommand[command] = cm
+		}
+		cm.Count++
+		cm.Duration += n.Duration
+		if isTTSCommand(command) {
+			m.SlowestTTS = append(m.SlowestTTS, n)
+		}
+	}
+	for _, cm := range byCommand {
+		m.Commands = append(m.Commands, *cm)
+	}
+	slices.SortFunc(m.Commands, func(a, b CommandMetrics) int {
+		return cmp.Or(cmp.Compare(b.Duration, a.Duration), cmp.Compare(a.Command, b.Command))
+	})
+	slices.SortFunc(m.SlowestTTS, func(a, b NodeMetrics) int {
+		return cmp.Or(cmp.Compare(b.Duration, a.Duration), cmp.Compare(a.Name, b.Name))
+	})
+	m.SlowestTTS = m.SlowestTTS[:min(len(m.SlowestTTS), slowestTTSLen)]
+	return m
+}
+
+func isTTSCommand(command string) bool {
+	return command == "say" || command == "espeak-ng"
+}
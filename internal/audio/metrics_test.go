@@ -0,0 +1,45 @@
+package audio
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/dag"
+)
+
+func TestMetricsCollector(t *testing.T) {
+	c := &metricsCollector{}
+	events := []dag.Event{
+		{Name: "espeak-ng -v en-gb -out a.wav A"},
+		{Name: "espeak-ng -v en-gb -out a.wav A", Finished: true, Duration: 300 * time.Millisecond},
+		{Name: "espeak-ng -v en-gb -out b.wav B", Finished: true, Duration: 500 * time.Millisecond},
+		{Name: "espeak-ng -v en-gb -out c.wav C", Finished: true, Skipped: true},
+		{Name: "sox_ng a.wav b.wav", Finished: true, Duration: 100 * time.Millisecond},
+		{Name: "sox_ng c.wav d.wav", Finished: true, Duration: 200 * time.Millisecond},
+		{Name: "ffmpeg -i d.wav d.mp3", Finished: true, Err: errors.New("failed")},
+	}
+	for _, e := range events {
+		c.add(e)
+	}
+	got := c.metrics()
+
+	wantCommands := []CommandMetrics{
+		{Command: "espeak-ng", Count: 2, Duration: 800 * time.Millisecond},
+		{Command: "sox_ng", Count: 2, Duration: 300 * time.Millisecond},
+	}
+	if !slices.Equal(got.Commands, wantCommands) {
+		t.Fatalf("Commands: want %+v, got %+v", wantCommands, got.Commands)
+	}
+	wantTTS := []NodeMetrics{
+		{Name: "espeak-ng -v en-gb -out b.wav B", Duration: 500 * time.Millisecond},
+		{Name: "espeak-ng -v en-gb -out a.wav A", Duration: 300 * time.Millisecond},
+	}
+	if !slices.Equal(got.SlowestTTS, wantTTS) {
+		t.Fatalf("SlowestTTS: want %+v, got %+v", wantTTS, got.SlowestTTS)
+	}
+	if got.Cached != 1 || got.Total != 5 || got.CacheHitRatio() != 0.2 {
+		t.Fatalf("cache: want 1/5, got %d/%d", got.Cached, got.Total)
+	}
+}
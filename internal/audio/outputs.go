@@ -0,0 +1,116 @@
+package audio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// outputsFile lists the output files of the last run for other tools.
+// It is written next to the playlist.
+const outputsFile = "outputs.json"
+
+type outputEntry struct {
+	// File is the file name in the output dir.
+	File  string `json:"file"`
+	Name  string `json:"name"`
+	Title string `json:"title,omitempty"`
+	// Duration is zero for failed files.
+	Duration float64 `json:"duration_seconds"`
+	// Hash is the hash in the file name which changes with the content.
+	Hash string `json:"hash"`
+	// Operation is created, exists, copied or failed.
+	Operation string `json:"operation"`
+}
+
+type outputsJSON struct {
+	Files []outputEntry `json:"files"`
+}
+
+func newOutputEntry(path string, file File, duration time.Duration, operation string) outputEntry {
+	name := filepath.Base(path)
+	return outputEntry{
+		File:      name,
+		Name:      file.Name,
+		Title:     file.Metadata.Title,
+		Duration:  duration.Seconds(),
+		Hash:      strings.TrimPrefix(hashSuffixReg.FindString(strings.TrimSuffix(name, filepath.Ext(name))), "-"),
+		Operation: operation,
+	}
+}
+
+func writeOutputs(path string, entries []outputEntry) error {
+	data, err := json.MarshalIndent(outputsJSON{Files: entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Outputs returns the existing output files of the last run in outputDir in the order
+// of its outputs file. It fails with ErrNotW2aDir if outputDir has no outputs file.
+func Outputs(outputDir string) ([]string, error) {
+	outputsPath := filepath.Join(outputDir, outputsFile)
+	data, err := os.ReadFile(outputsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s has no %s", ErrNotW2aDir, outputDir, outputsFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var j outputsJSON
+	err = json.Unmarshal(data, &j)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", outputsPath, err)
+	}
+	var paths []string
+	for _, e := range j.Files {
+		// Only names written by w2a are in the file, a path could point outside of the dir.
+		if e.File == "" || e.File != filepath.Base(e.File) {
+			continue
+		}
+		path := filepath.Join(outputDir, e.File)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// OutputFiles returns the existing files the last run wrote to outputDir: sorted
+// the output files with their CUE sheets and captions and the playlists which list
+// only output files, then the timeline and the outputs file.
+// It fails with ErrNotW2aDir if outputDir has no outputs file.
+func OutputFiles(outputDir string) ([]string, error) {
+	outputs, err := Outputs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	absOutputs := make(map[string]bool, len(outputs))
+	var paths []string
+	for _, path := range outputs {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		absOutputs[absPath] = true
+		paths = append(paths, path, cuePath(path), lrcPath(path), vttPath(path))
+	}
+	playlists, err := outputPlaylists(outputDir, absOutputs)
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, playlists...)
+	slices.Sort(paths)
+	// The outputs file is last, so the files are still listed if removing them is interrupted.
+	paths = append(paths, filepath.Join(outputDir, timelineFile), filepath.Join(outputDir, outputsFile))
+	return slices.DeleteFunc(paths, func(p string) bool {
+		_, err := os.Stat(p)
+		return err != nil
+	}), nil
+}
@@ -0,0 +1,60 @@
+package audio
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOutputs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"02-squats-0000002.mp3": "mp3",
+		"01-push-0000001.mp3":   "mp3",
+		"01-push-0000001.vtt":   "vtt",
+		"playlist.m3u":          "#EXTM3U\n02-squats-0000002.mp3\n01-push-0000001.mp3\n",
+		"music.mp3":             "mp3",
+		outputsFile: `{"files": [
+			{"file": "02-squats-0000002.mp3"},
+			{"file": "01-push-0000001.mp3"},
+			{"file": "03-failed-0000003.mp3", "operation": "failed"}
+		]}`,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	join := func(names ...string) []string {
+		var paths []string
+		for _, name := range names {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+		return paths
+	}
+
+	got, err := Outputs(dir)
+	if err != nil {
+		t.Fatalf("Outputs(): %v", err)
+	}
+	if want := join("02-squats-0000002.mp3", "01-push-0000001.mp3"); !slices.Equal(got, want) {
+		t.Fatalf("Outputs() = %v, want %v", got, want)
+	}
+
+	got, err = OutputFiles(dir)
+	if err != nil {
+		t.Fatalf("OutputFiles(): %v", err)
+	}
+	want := join("01-push-0000001.mp3", "01-push-0000001.vtt", "02-squats-0000002.mp3", "playlist.m3u", outputsFile)
+	if !slices.Equal(got, want) {
+		t.Fatalf("OutputFiles() = %v, want %v", got, want)
+	}
+
+	_, err = Outputs(t.TempDir())
+	if !errors.Is(err, ErrNotW2aDir) {
+		t.Fatalf("got error %v, want %v", err, ErrNotW2aDir)
+	}
+}
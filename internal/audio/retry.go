@@ -0,0 +1,14 @@
+package audio
+
+import "github.com/mrclmr/w2a/internal/dag"
+
+// Retry defines how often a failed command is run again. The graph runs the
+// commands again, see dag.Retry.
+type Retry = dag.Retry
+
+// Retries holds a Retry per command type.
+type Retries struct {
+	TTS     Retry
+	Sox     Retry
+	Convert Retry
+}
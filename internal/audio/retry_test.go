@@ -0,0 +1,67 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrclmr/w2a/internal/dag"
+)
+
+type failingCmd struct {
+	attempts *int
+	failures int
+}
+
+func (c failingCmd) CombinedOutput() ([]byte, error) {
+	*c.attempts++
+	if *c.attempts <= c.failures {
+		return nil, errors.New("failed")
+	}
+	return nil, nil
+}
+
+func TestCmd_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		retry        Retry
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"no retry", Retry{}, 1, 1, true},
+		{"success after retries", Retry{Count: 2}, 2, 3, false},
+		{"retries exhausted", Retry{Count: 2}, 5, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			class := newCmdClass(ErrConversion, func(_ context.Context, _ string, _ ...string) Cmd {
+				return failingCmd{attempts: &attempts, failures: tt.failures}
+			}, tt.retry)
+			d := dag.New[fileOperation]()
+			d.AddNode(newCmd(class, "sox_ng", []string{filepath.Join(t.TempDir(), "out-<hash>.wav")}, DefaultHashLength))
+			var err error
+			for _, runErr := range d.RunRootNodes(t.Context()) {
+				err = runErr
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunRootNodes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("attempts: want %d, got %d", tt.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestNewCmdClass_Retryable(t *testing.T) {
+	class := newCmdClass(ErrTTS, nil, Retry{Count: 1})
+	if !class.retry.Retryable(cmdError("say", nil, nil, errors.Join(ErrTTS, errors.New("failed")))) {
+		t.Fatal("command error not retryable")
+	}
+	if class.retry.Retryable(errors.New("rename failed")) {
+		t.Fatal("other error retryable")
+	}
+}
@@ -46,6 +46,8 @@ func (s *Sound) len() time.Duration {
 type Text struct {
 	Value  string
 	Length time.Duration
+	// Voice overrides the TTS voice for this text if set.
+	Voice string
 }
 
 func (t *Text) values() []Segment {
@@ -60,9 +62,30 @@ func (t *Text) len() time.Duration {
 	return t.Length
 }
 
+// Tone is a synthesized beep.
+type Tone struct {
+	Frequency float64
+	Length    time.Duration
+	Waveform  Waveform
+}
+
+func (t *Tone) values() []Segment {
+	panic("Tone has no values")
+}
+
+func (t *Tone) value() string {
+	panic("Tone has no value")
+}
+
+func (t *Tone) len() time.Duration {
+	return t.Length
+}
+
 type Group struct {
 	Segments []Segment
 	Length   time.Duration
+	// Trim cuts the segments to Length if they are longer, so the group lasts exactly Length.
+	Trim bool
 }
 
 func (g *Group) values() []Segment {
@@ -76,3 +99,19 @@ func (g *Group) value() string {
 func (g *Group) len() time.Duration {
 	return g.Length
 }
+
+// Texts returns the non-empty text values of all segments in order.
+func Texts(segments []Segment) []string {
+	var texts []string
+	for _, s := range segments {
+		switch v := s.(type) {
+		case *Text:
+			if v.value() != "" {
+				texts = append(texts, v.value())
+			}
+		case *Group:
+			texts = append(texts, Texts(v.values())...)
+		}
+	}
+	return texts
+}
@@ -0,0 +1,27 @@
+package audio
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTexts(t *testing.T) {
+	segments := []Segment{
+		&Sound{Filename: "start.wav", Length: time.Second},
+		&Text{Value: "first"},
+		&Text{Length: time.Second},
+		&Group{
+			Segments: []Segment{
+				&Text{Value: "second"},
+				&Silence{Length: time.Second},
+			},
+		},
+		&Text{Value: "third"},
+	}
+	want := []string{"first", "second", "third"}
+	got := Texts(segments)
+	if !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %v, want %v", got, want)
+	}
+}
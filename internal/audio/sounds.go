@@ -3,40 +3,43 @@ package audio
 import (
 	"embed"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 // Allow standalone executable (go build) by embedding and calling initSounds().
-//
-// Use one channel:        sox_ng old.wav -c 1 new.wav
-// Use 22050 sample rate:  sox_ng old.wav -r 22050 new.wav
-//
-// A Sha256 hash is needed in the file name. Best is to use Sha256 of the file content.
-//
-//	my-sound-name-<SHA256SHORT>.wav
+// Sounds in another format than the intermediate wav files are converted with sox_ng.
 //
 //go:embed sounds
 var sounds embed.FS
 
-func initSounds(dstDir string) error {
+// initSounds writes the embedded sounds to dstDir. The file names get the hash
+// of the file content so changed sounds invalidate the cached files.
+// The returned map maps the sound names, e.g. 'start.wav', to the written file names.
+func initSounds(dstDir string, hashLen int) (map[string]string, error) {
 	entries, err := sounds.ReadDir("sounds")
 	if err != nil {
-		return err
+		return nil, err
 	}
+	names := make(map[string]string, len(entries))
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 
-		data, err := sounds.ReadFile(filepath.Join("sounds", entry.Name()))
+		data, err := sounds.ReadFile(path.Join("sounds", entry.Name()))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		err = os.WriteFile(filepath.Join(dstDir, entry.Name()), data, 0o600)
+		ext := filepath.Ext(entry.Name())
+		filename := strings.TrimSuffix(entry.Name(), ext) + "-" + digest("file", data)[:hashLen] + ext
+		err = os.WriteFile(filepath.Join(dstDir, filename), data, 0o600)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		names[entry.Name()] = filename
 	}
-	return nil
+	return names, nil
 }
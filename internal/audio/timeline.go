@@ -0,0 +1,37 @@
+package audio
+
+import (
+	"cmp"
+	"os"
+	"time"
+)
+
+// timelineFile holds the chapters of the whole workout in the ffmetadata format,
+// e.g. to post-process the output files into other containers with ffmpeg.
+const timelineFile = "chapters.ffmetadata"
+
+// timeline collects the chapters of the output files in the order of the workout.
+type timeline struct {
+	titles  []string
+	lengths []time.Duration
+}
+
+// add adds the chapters of a merged file or the file as one chapter.
+// If the lengths of the chapters are not available, e.g. because the output
+// file already existed, the merged file is added as one chapter as well.
+func (t *timeline) add(cb *cmdBuilder, file File, chapters []chapter, duration time.Duration) {
+	lengths, err := cb.chapterLengths(chapters)
+	if len(chapters) > 0 && err == nil {
+		for _, c := range chapters {
+			t.titles = append(t.titles, c.title)
+		}
+		t.lengths = append(t.lengths, lengths...)
+		return
+	}
+	t.titles = append(t.titles, cmp.Or(file.Metadata.Title, file.Name))
+	t.lengths = append(t.lengths, duration)
+}
+
+func (t *timeline) write(path string) error {
+	return os.WriteFile(path, []byte(ffmetadata(t.titles, t.lengths)), 0o644)
+}
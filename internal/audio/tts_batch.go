@@ -0,0 +1,195 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/dag"
+	"github.com/mrclmr/w2a/internal/wav"
+)
+
+const (
+	// ttsBatchSize is the maximum number of texts spoken by one call of the TTS engine.
+	ttsBatchSize = 25
+	// ttsBatchPause is the pause spoken between the texts of a batch.
+	ttsBatchPause = 3 * time.Second
+	// ttsBatchGap is the minimum silence the audio of a batch is split at.
+	// It is shorter than ttsBatchPause because engines shorten pauses at a fast rate.
+	ttsBatchGap = 1500 * time.Millisecond
+	// ttsBatchMargin is the silence kept before and after every text of a batch.
+	ttsBatchMargin = 200 * time.Millisecond
+)
+
+var (
+	// ssmlEscaper escapes texts for espeak-ng with SSML input.
+	ssmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	// sayEscaper escapes the embedded commands of say in texts.
+	sayEscaper = strings.NewReplacer("[[", "[ [")
+)
+
+// batchable reports whether text is spoken in a batch. Texts with trailing commas
+// are not batched because the commas add a pause which would be trimmed.
+func (t *TTS) batchable(text string) bool {
+	return t.Batch && (t.TTSCmd == Say || t.TTSCmd == EspeakNG) && recordingKey(text) == text
+}
+
+// ttsBatchNode is a TTS command whose text is spoken in a batch with other texts.
+// It runs the command alone if it has no batch or the batch fails.
+type ttsBatchNode struct {
+	*cmd
+	tts   TTS
+	text  string
+	batch *ttsBatch
+}
+
+func (n *ttsBatchNode) Run(ctx context.Context, ops []fileOperation) (fileOperation, error) {
+	if n.batch != nil && n.batch.run(ctx, n) {
+		return created, nil
+	}
+	return n.cmd.Run(ctx, ops)
+}
+
+// ttsBatcher groups the TTS commands to run into batches.
+type ttsBatcher struct {
+	execCmdCtx ExecCmdCtx
+	tempDir    string
+	hashLen    int
+	// nodes are the added nodes by hash.
+	nodes map[string]*ttsBatchNode
+}
+
+func newTTSBatcher(execCmdCtx ExecCmdCtx, tempDir string, hashLen int) *ttsBatcher {
+	return &ttsBatcher{
+		execCmdCtx: execCmdCtx,
+		tempDir:    tempDir,
+		hashLen:    hashLen,
+		nodes:      make(map[string]*ttsBatchNode),
+	}
+}
+
+// add returns the batch node of the TTS command c which speaks text with tts.
+// A node with the same hash is returned if it was added before.
+func (b *ttsBatcher) add(c *cmd, tts TTS, text string) *ttsBatchNode {
+	if n, ok := b.nodes[c.Hash()]; ok {
+		return n
+	}
+	n := &ttsBatchNode{cmd: c, tts: tts, text: text}
+	b.nodes[c.Hash()] = n
+	return n
+}
+
+// plan groups the added nodes of the steps which are not skipped into batches
+// of the same engine settings in the order of the steps. A node without
+// another node to batch with runs alone.
+func (b *ttsBatcher) plan(steps []dag.Step) {
+	open := make(map[TTS]*ttsBatch)
+	for _, s := range steps {
+		n, ok := b.nodes[s.Hash]
+		if !ok || s.Skipped || n.batch != nil {
+			continue
+		}
+		batch, ok := open[n.tts]
+		if !ok {
+			batch = &ttsBatch{batcher: b}
+			open[n.tts] = batch
+		}
+		batch.nodes = append(batch.nodes, n)
+		n.batch = batch
+		if len(batch.nodes) == ttsBatchSize {
+			delete(open, n.tts)
+		}
+	}
+	for _, batch := range open {
+		if len(batch.nodes) == 1 {
+			batch.nodes[0].batch = nil
+		}
+	}
+}
+
+// ttsBatch speaks the texts of its nodes with one call of the TTS engine.
+type ttsBatch struct {
+	batcher *ttsBatcher
+	nodes   []*ttsBatchNode
+	once    sync.Once
+	// created are the nodes whose output file was written by the batch.
+	created map[*ttsBatchNode]bool
+}
+
+// run speaks the batch on the first call and reports whether the output file of n was written.
+func (b *ttsBatch) run(ctx context.Context, n *ttsBatchNode) bool {
+	b.once.Do(func() {
+		var err error
+		b.created, err = b.create(ctx)
+		if err != nil {
+			slog.Warn("tts batch failed, speaking the texts alone\t", "err", err)
+		}
+	})
+	return b.created[n]
+}
+
+func (b *ttsBatch) create(ctx context.Context) (map[*ttsBatchNode]bool, error) {
+	tts := b.nodes[0].tts
+	cmdStr, args := ttsArgs(&tts)
+	texts := make([]string, len(b.nodes))
+	hashes := make([]string, len(b.nodes))
+	for i, n := range b.nodes {
+		texts[i] = n.text
+		hashes[i] = n.Hash()
+	}
+	path := filepath.Join(b.batcher.tempDir, fmt.Sprintf("%s-batch-%s.wav", cmdStr, digest("batch", hashes)[:b.batcher.hashLen]))
+	defer removePartialFile(path)
+
+	pause := ttsBatchPause.Milliseconds()
+	switch tts.TTSCmd {
+	case Say:
+		for i, t := range texts {
+			texts[i] = sayEscaper.Replace(t)
+		}
+		args = append(args, "--output-file", path, strings.Join(texts, fmt.Sprintf(" [[slnc %d]] ", pause)))
+	case EspeakNG:
+		for i, t := range texts {
+			texts[i] = ssmlEscaper.Replace(t)
+		}
+		args = append(args, "-m", "-out", path, strings.Join(texts, fmt.Sprintf(` <break time="%dms"/> `, pause)))
+	default:
+		return nil, fmt.Errorf("%s does not support batches", tts.TTSCmd)
+	}
+
+	out, err := b.batcher.execCmdCtx(ctx, cmdStr, args...).CombinedOutput()
+	if err != nil {
+		return nil, cmdError(cmdStr, args, out, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h, parts, err := wav.SplitAtSilence(f, ttsBatchGap, ttsBatchMargin)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) != len(b.nodes) {
+		return nil, fmt.Errorf("%s: %d texts spoken, but %d found", path, len(b.nodes), len(parts))
+	}
+	created := make(map[*ttsBatchNode]bool, len(b.nodes))
+	for i, n := range b.nodes {
+		err = createFile(n.outPath, func(w io.Writer) error {
+			return wav.WritePart(w, f, h, parts[i])
+		})
+		if err != nil {
+			return created, err
+		}
+		created[n] = true
+	}
+	return created, nil
+}
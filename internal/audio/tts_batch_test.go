@@ -0,0 +1,131 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/dag"
+	"github.com/mrclmr/w2a/internal/wav"
+)
+
+// speechCmd writes a wav file with 100ms of sound per text and the pauses of the SSML breaks.
+// With missing it writes the sound of one text less.
+type speechCmd struct {
+	path    string
+	texts   int
+	missing bool
+}
+
+func (c speechCmd) CombinedOutput() ([]byte, error) {
+	format := wav.Mono(DefaultSampleRate)
+	sound := make([]byte, 2*DefaultSampleRate/10)
+	for i := 0; i < len(sound); i += 2 {
+		binary.LittleEndian.PutUint16(sound[i:], 1000)
+	}
+	pause := make([]byte, 2*int(ttsBatchPause.Seconds()*DefaultSampleRate))
+	texts := c.texts
+	if c.missing {
+		texts--
+	}
+	var samples []byte
+	for i := range texts {
+		if i > 0 {
+			samples = append(samples, pause...)
+		}
+		samples = append(samples, sound...)
+	}
+	buf := &bytes.Buffer{}
+	err := wav.WriteHeader(buf, format, len(samples))
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(samples)
+	return nil, os.WriteFile(c.path, buf.Bytes(), 0o600)
+}
+
+func TestTTSBatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		missing   bool
+		wantCalls int
+	}{
+		{name: "batch", wantCalls: 2},
+		{name: "fallback", missing: true, wantCalls: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var lock sync.Mutex
+			var calls [][]string
+			execCmdCtx := func(_ context.Context, _ string, args ...string) Cmd {
+				lock.Lock()
+				calls = append(calls, args)
+				lock.Unlock()
+				text := args[len(args)-1]
+				path := args[slices.Index(args, "-out")+1]
+				if slices.Contains(args, "-m") {
+					return speechCmd{path: path, texts: strings.Count(text, "<break") + 1, missing: tt.missing}
+				}
+				return speechCmd{path: path, texts: 1}
+			}
+			tts := &TTS{TTSCmd: EspeakNG, Voice: "en", Batch: true}
+			cb := newCmdBuilder(nil, nil, Options{ExecCmdCtx: execCmdCtx, TTS: tts, Format: Wav, TempDir: dir, OutputDir: dir, HashLength: DefaultHashLength})
+
+			// The text with trailing commas is not batched.
+			texts := []string{"one", "a < b", "three", "pause,,"}
+			nodes := make([]*fileCache, len(texts))
+			steps := make([]dag.Step, len(texts))
+			for i, text := range texts {
+				nodes[i] = cb.ttsCmd(text, "")
+				steps[i] = dag.Step{Name: nodes[i].Name(), Hash: nodes[i].Hash()}
+			}
+			cb.ttsBatcher.plan(steps)
+
+			var wg sync.WaitGroup
+			for _, n := range nodes {
+				wg.Go(func() {
+					_, err := n.Run(t.Context(), nil)
+					if err != nil {
+						t.Errorf("Run() error = %v", err)
+					}
+				})
+			}
+			wg.Wait()
+
+			if len(calls) != tt.wantCalls {
+				t.Fatalf("calls: want %d, got %d: %v", tt.wantCalls, len(calls), calls)
+			}
+			if !slices.ContainsFunc(calls, func(args []string) bool {
+				return strings.HasSuffix(args[len(args)-1], `one <break time="3000ms"/> a &lt; b <break time="3000ms"/> three`)
+			}) {
+				t.Fatalf("batch not called: %v", calls)
+			}
+			for _, n := range nodes {
+				d, err := wav.FileDuration(filepath.Join(dir, n.outputFile()))
+				if err != nil {
+					t.Fatalf("FileDuration() error = %v", err)
+				}
+				if d > 100*time.Millisecond+2*ttsBatchMargin {
+					t.Fatalf("duration of %s: want at most %s, got %s", n.outputFile(), 100*time.Millisecond+2*ttsBatchMargin, d)
+				}
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("ReadDir() error = %v", err)
+			}
+			for _, e := range entries {
+				if strings.Contains(e.Name(), "-batch-") {
+					t.Fatalf("batch file not removed: %s", e.Name())
+				}
+			}
+		})
+	}
+}
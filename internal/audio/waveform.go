@@ -0,0 +1,28 @@
+package audio
+
+import (
+	"github.com/mrclmr/w2a/internal/wav"
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer@latest -type Waveform
+type Waveform int
+
+const (
+	Sine Waveform = iota
+	Square
+	Triangle
+	Sawtooth
+)
+
+func (w Waveform) oscillator() wav.Oscillator {
+	switch w {
+	case Square:
+		return wav.Square
+	case Triangle:
+		return wav.Triangle
+	case Sawtooth:
+		return wav.Sawtooth
+	default:
+		return wav.Sine
+	}
+}
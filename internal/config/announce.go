@@ -9,8 +9,8 @@ import (
 )
 
 type Announce struct {
-	Text     *audio.TextTmpl `yaml:"text"`
-	Duration time.Duration   `yaml:"duration"`
+	Text     *audio.TextTmpl `yaml:"text" doc:"Template of the spoken text" required:"true"`
+	Duration time.Duration   `yaml:"duration" doc:"Duration of the pause or of the interruption of the exercise"`
 }
 
 type announce Announce
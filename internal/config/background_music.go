@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+
+	"go.yaml.in/yaml/v3"
+)
+
+type BackgroundMusic struct {
+	Path    string  `yaml:"path" doc:"Music file or directory of files used in turn, relative to the yaml file" required:"true"`
+	Volume  float64 `yaml:"volume" doc:"Volume between 0 and 1" default:"0.2"`
+	Ducking *bool   `yaml:"ducking" doc:"Lower the music volume while speaking" default:"true"`
+}
+
+type backgroundMusic BackgroundMusic
+
+func (b *BackgroundMusic) UnmarshalYAML(node *yaml.Node) error {
+	var y backgroundMusic
+	err := node.Decode(&y)
+	if err != nil {
+		return err
+	}
+	if y.Path == "" {
+		return keyEmptyError("background_music.path")
+	}
+	if y.Volume < 0 || y.Volume > 1 {
+		return fmt.Errorf("background_music.volume must be between 0 and 1")
+	}
+	if y.Volume == 0 {
+		y.Volume = 0.2
+	}
+	if y.Ducking == nil {
+		ducking := true
+		y.Ducking = &ducking
+	}
+
+	b.Path = y.Path
+	b.Volume = y.Volume
+	b.Ducking = y.Ducking
+	return nil
+}
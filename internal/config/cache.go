@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/mrclmr/w2a/internal/audio"
+)
+
+type Cache struct {
+	MaxSize ByteSize `yaml:"max_size" doc:"Maximum size of the intermediate files, e.g. 2GB or 500MB" default:"no limit"`
+	MaxAge  Age      `yaml:"max_age" doc:"Intermediate files unused for longer are removed, e.g. 30d or 12h" default:"no limit"`
+}
+
+// Limits returns no limits for a nil Cache.
+func (c *Cache) Limits() audio.Cache {
+	if c == nil {
+		return audio.Cache{}
+	}
+	return audio.Cache{
+		MaxSize: int64(c.MaxSize),
+		MaxAge:  time.Duration(c.MaxAge),
+	}
+}
+
+// ByteSize is a size in bytes written with a unit, e.g. 2GB.
+type ByteSize int64
+
+// byteUnits are the units of ByteSize, longest suffixes first.
+var byteUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"TiB", 1 << 40},
+	{"KB", 1e3},
+	{"MB", 1e6},
+	{"GB", 1e9},
+	{"TB", 1e12},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size with one of the units B, KB, MB, GB, TB, KiB, MiB, GiB or TiB.
+func ParseByteSize(s string) (ByteSize, error) {
+	for _, u := range byteUnits {
+		num, ok := strings.CutSuffix(s, u.suffix)
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+		if err != nil || f < 0 {
+			break
+		}
+		return ByteSize(f * float64(u.bytes)), nil
+	}
+	return 0, fmt.Errorf("invalid size '%s': use a unit like 500MB or 2GB", s)
+}
+
+func (b *ByteSize) UnmarshalYAML(node *yaml.Node) error {
+	var y string
+	err := node.Decode(&y)
+	if err != nil {
+		return err
+	}
+	*b, err = ParseByteSize(y)
+	return err
+}
+
+// Age is a duration which additionally accepts days, e.g. 30d.
+type Age time.Duration
+
+// ParseAge parses a whole number of days like 30d or a duration like 12h.
+func ParseAge(s string) (Age, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age '%s': use days like 30d or a duration like 12h", s)
+		}
+		return Age(time.Duration(n) * 24 * time.Hour), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age '%s': use days like 30d or a duration like 12h", s)
+	}
+	return Age(d), nil
+}
+
+func (a *Age) UnmarshalYAML(node *yaml.Node) error {
+	var y string
+	err := node.Decode(&y)
+	if err != nil {
+		return err
+	}
+	*a, err = ParseAge(y)
+	return err
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
@@ -19,6 +20,28 @@ func Parse(r io.Reader) (*Workout, error) {
 	return &w, nil
 }
 
+// ParseAll parses every yaml document of r as workout, e.g. several workouts separated by '---'.
+func ParseAll(r io.Reader) ([]*Workout, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var workouts []*Workout
+	for {
+		var w Workout
+		err := decoder.Decode(&w)
+		if errors.Is(err, io.EOF) && len(workouts) > 0 {
+			return workouts, nil
+		}
+		if err != nil {
+			if len(workouts) > 0 {
+				return nil, fmt.Errorf("workout %d: %w", len(workouts)+1, err)
+			}
+			return nil, err
+		}
+		workouts = append(workouts, &w)
+	}
+}
+
 func keyEmptyError(key string) error {
 	return fmt.Errorf("key '%s' is missing or value is empty", key)
 }
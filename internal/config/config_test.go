@@ -1,8 +1,15 @@
 package config
 
 import (
+	"errors"
+	"os"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/audio"
+	"go.yaml.in/yaml/v3"
 )
 
 func TestParseExample(t *testing.T) {
@@ -15,3 +22,236 @@ func TestParseExample(t *testing.T) {
 		t.Fatalf("Parse(): %v", err)
 	}
 }
+
+func TestParseAll(t *testing.T) {
+	example, err := Example()
+	if err != nil {
+		t.Fatalf("Example(): %v", err)
+	}
+	workouts, err := ParseAll(strings.NewReader(example + "\n---\n" + example))
+	if err != nil {
+		t.Fatalf("ParseAll(): %v", err)
+	}
+	if len(workouts) != 2 {
+		t.Fatalf("want 2 workouts, got %d", len(workouts))
+	}
+	_, err = ParseAll(strings.NewReader(example + "\n---\nunknown: true\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "workout 2: ") {
+		t.Fatalf("want error of workout 2, got %v", err)
+	}
+	_, err = ParseAll(strings.NewReader(""))
+	if err == nil {
+		t.Fatal("want error of empty yaml")
+	}
+}
+
+func TestCue_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Cue
+		wantErr bool
+	}{
+		{"plain text", "'Shoulder Roll'", Cue{Text: "Shoulder Roll"}, false},
+		{"text with voice", "{text: 'Shoulder Roll', voice: 'en-us'}", Cue{Text: "Shoulder Roll", Voice: "en-us"}, false},
+		{"empty text", "''", Cue{}, true},
+		{"voice without text", "{voice: 'en-us'}", Cue{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Cue
+			err := yaml.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Unmarshal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTTSCmd_ESpeakNG(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    audio.TTS
+		wantErr bool
+	}{
+		{
+			name:  "voice with variant",
+			input: "espeak_ng: {voice: 'en-gb', variant: '+f3', amplitude: 120, word_gap: 2}",
+			want: audio.TTS{
+				TTSCmd:   audio.EspeakNG,
+				Voice:    "en-gb",
+				ESpeakNG: audio.ESpeakNGOptions{Variant: "f3", Amplitude: 120, WordGap: 2},
+			},
+		},
+		{
+			name:  "mbrola voice",
+			input: "espeak_ng: {mbrola: 'de1'}",
+			want:  audio.TTS{TTSCmd: audio.EspeakNG, Voice: "mb-de1"},
+		},
+		{
+			name:    "voice and mbrola",
+			input:   "espeak_ng: {voice: 'de', mbrola: 'de1'}",
+			wantErr: true,
+		},
+		{
+			name:    "espeak_ng and espeak_ng_voice",
+			input:   "{espeak_ng_voice: 'de', espeak_ng: {voice: 'de'}}",
+			wantErr: true,
+		},
+		{
+			name:    "amplitude too high",
+			input:   "espeak_ng: {voice: 'de', amplitude: 201}",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got TTSCmd
+			err := yaml.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got.TTS() != tt.want {
+				t.Fatalf("TTS() = %v, want %v", *got.TTS(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectTTS(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		language string
+		programs []string
+		want     *TTSCmd
+		wantErr  bool
+	}{
+		{"say on macOS", "darwin", "de", []string{"say", "espeak-ng"}, &TTSCmd{SayVoice: "Anna"}, false},
+		{"say without default voice", "darwin", "pl", []string{"say", "espeak-ng"}, &TTSCmd{ESpeakNGVoice: "pl"}, false},
+		{"espeak-ng on linux", "linux", "", []string{"say", "espeak-ng"}, &TTSCmd{ESpeakNGVoice: "en-gb"}, false},
+		{"espeak-ng with region", "linux", "en-US", []string{"espeak-ng"}, &TTSCmd{ESpeakNGVoice: "en-us"}, false},
+		{"nothing installed", "linux", "en", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookPath := func(file string) (string, error) {
+				if slices.Contains(tt.programs, file) {
+					return "/usr/bin/" + file, nil
+				}
+				return "", errors.New("not found")
+			}
+			got, err := DetectTTS(lookPath, tt.goos, tt.language)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectTTS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.SayVoice != tt.want.SayVoice || got.ESpeakNGVoice != tt.want.ESpeakNGVoice {
+				t.Fatalf("DetectTTS() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTTSCmd_SetVoice(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttsCmd  TTSCmd
+		want    string
+		wantErr bool
+	}{
+		{"espeak_ng_voice", TTSCmd{ESpeakNGVoice: "en-gb"}, "de", false},
+		{"espeak_ng mbrola", TTSCmd{ESpeakNG: &ESpeakNG{MBROLA: "de1"}}, "de", false},
+		{"custom_command", TTSCmd{CustomCommand: "tts"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ttsCmd.SetVoice("de")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetVoice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.ttsCmd.TTS().Voice; got != tt.want {
+				t.Fatalf("TTS().Voice = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReference(t *testing.T) {
+	for _, k := range Reference() {
+		if k.Description == "" {
+			t.Errorf("key %s has no doc tag", k.Path)
+		}
+	}
+	docs, err := os.ReadFile("../../docs/config.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(docs) != ReferenceMarkdown() {
+		t.Fatal("docs/config.md is outdated: run 'w2a docs > docs/config.md'")
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ByteSize
+		wantErr bool
+	}{
+		{input: "2GB", want: 2e9},
+		{input: "1.5 MB", want: 1.5e6},
+		{input: "1GiB", want: 1 << 30},
+		{input: "100B", want: 100},
+		{input: "2", wantErr: true},
+		{input: "-1GB", wantErr: true},
+		{input: "GB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseByteSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseByteSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseByteSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Age
+		wantErr bool
+	}{
+		{input: "30d", want: Age(30 * 24 * time.Hour)},
+		{input: "12h", want: Age(12 * time.Hour)},
+		{input: "1.5d", wantErr: true},
+		{input: "-1d", wantErr: true},
+		{input: "30", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAge(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseAge() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+
+	"go.yaml.in/yaml/v3"
+)
+
+type Countdown string
+
+const (
+	// CountdownSpoken speaks the numbers.
+	CountdownSpoken Countdown = "spoken"
+	// CountdownBeeps replaces the numbers with beeps and a distinct final beep.
+	CountdownBeeps Countdown = "beeps"
+	// CountdownBoth plays a beep before every spoken number.
+	CountdownBoth Countdown = "both"
+)
+
+func (c *Countdown) UnmarshalYAML(node *yaml.Node) error {
+	var y string
+	err := node.Decode(&y)
+	if err != nil {
+		return err
+	}
+	switch Countdown(y) {
+	case CountdownSpoken, CountdownBeeps, CountdownBoth:
+		*c = Countdown(y)
+		return nil
+	default:
+		return fmt.Errorf("unknown countdown '%s': use spoken, beeps or both", y)
+	}
+}
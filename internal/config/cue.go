@@ -0,0 +1,43 @@
+package config
+
+import (
+	"go.yaml.in/yaml/v3"
+)
+
+// Cue is a text spoken during an exercise. It is either a plain string
+// or a mapping with a voice that overrides tts voice for this text.
+type Cue struct {
+	Text  string `yaml:"text" doc:"Spoken text" required:"true"`
+	Voice string `yaml:"voice" doc:"Voice overriding the TTS voice for this text"`
+}
+
+type cue Cue
+
+func (c *Cue) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var text string
+		err := node.Decode(&text)
+		if err != nil {
+			return err
+		}
+		if text == "" {
+			return keyEmptyError("exercise.texts")
+		}
+		c.Text = text
+		c.Voice = ""
+		return nil
+	}
+
+	var y cue
+	err := node.Decode(&y)
+	if err != nil {
+		return err
+	}
+	if y.Text == "" {
+		return keyEmptyError("exercise.texts.text")
+	}
+
+	c.Text = y.Text
+	c.Voice = y.Voice
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+type ESpeakNG struct {
+	Voice     string `yaml:"voice" doc:"Voice, set only one of voice or mbrola"`
+	Variant   string `yaml:"variant" doc:"Voice variant"`
+	Amplitude int    `yaml:"amplitude" doc:"Amplitude between 0 and 200" default:"100"`
+	WordGap   int    `yaml:"word_gap" doc:"Additional pause between words in units of 10ms" default:"0"`
+	MBROLA    string `yaml:"mbrola" doc:"Installed MBROLA voice, e.g. en1"`
+}
+
+// voice returns the voice argument for espeak-ng.
+// MBROLA voices are prefixed with 'mb-'.
+func (e *ESpeakNG) voice() string {
+	if e.MBROLA != "" {
+		return "mb-" + strings.TrimPrefix(e.MBROLA, "mb-")
+	}
+	return e.Voice
+}
+
+type eSpeakNG ESpeakNG
+
+func (e *ESpeakNG) UnmarshalYAML(node *yaml.Node) error {
+	var y eSpeakNG
+	err := node.Decode(&y)
+	if err != nil {
+		return err
+	}
+	if (y.Voice == "") == (y.MBROLA == "") {
+		return fmt.Errorf("set only one: tts.espeak_ng.voice or tts.espeak_ng.mbrola")
+	}
+	if y.MBROLA != "" && y.Variant != "" {
+		return fmt.Errorf("tts.espeak_ng.variant is not available for MBROLA voices")
+	}
+	if y.Amplitude < 0 || y.Amplitude > 200 {
+		return fmt.Errorf("tts.espeak_ng.amplitude must be between 0 and 200")
+	}
+	if y.WordGap < 0 {
+		return fmt.Errorf("tts.espeak_ng.word_gap must not be negative")
+	}
+
+	e.Voice = y.Voice
+	e.Variant = strings.TrimPrefix(y.Variant, "+")
+	e.Amplitude = y.Amplitude
+	e.WordGap = y.WordGap
+	e.MBROLA = y.MBROLA
+	return nil
+}
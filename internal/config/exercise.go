@@ -7,11 +7,11 @@ import (
 )
 
 type Exercise struct {
-	Name                  string        `yaml:"name"`
-	Duration              time.Duration `yaml:"duration"`
-	Texts                 []string      `yaml:"texts"`
-	HalfTime              bool          `yaml:"half_time"`
-	PauseDurationOverride time.Duration `yaml:"pause_duration"`
+	Name                  string        `yaml:"name" doc:"Name of the exercise" required:"true"`
+	Duration              time.Duration `yaml:"duration" doc:"Duration of the exercise" required:"true"`
+	Texts                 []Cue         `yaml:"texts" doc:"Texts spoken during the exercise, a string or a mapping with text and voice"`
+	HalfTime              bool          `yaml:"half_time" doc:"Announce half_time in the middle of the exercise" default:"false"`
+	PauseDurationOverride time.Duration `yaml:"pause_duration" doc:"Duration of the pause before the exercise" default:"pause.duration"`
 }
 
 type exercise Exercise
@@ -8,9 +8,11 @@ import (
 )
 
 type I18n struct {
-	And    string `yaml:"and"`
-	Second *Word  `yaml:"second"`
-	Minute *Word  `yaml:"minute"`
+	// Language is used to choose a default voice if no TTS engine is configured.
+	Language string `yaml:"language" doc:"Language for the default voice if tts is not set" default:"en"`
+	And      string `yaml:"and" doc:"Word between minutes and seconds" required:"true"`
+	Second   *Word  `yaml:"second" doc:"Word for seconds" required:"true"`
+	Minute   *Word  `yaml:"minute" doc:"Word for minutes" required:"true"`
 }
 
 func (i *I18n) DurToText(d time.Duration) string {
@@ -58,6 +60,7 @@ func (i *I18n) UnmarshalYAML(node *yaml.Node) error {
 		return keyEmptyError("i18n.minute")
 	}
 
+	i.Language = y.Language
 	i.And = y.And
 	i.Second = y.Second
 	i.Minute = y.Minute
@@ -65,8 +68,8 @@ func (i *I18n) UnmarshalYAML(node *yaml.Node) error {
 }
 
 type Word struct {
-	Singular string `yaml:"singular"`
-	Plural   string `yaml:"plural"`
+	Singular string `yaml:"singular" doc:"Singular of the word" required:"true"`
+	Plural   string `yaml:"plural" doc:"Plural of the word" required:"true"`
 }
 
 type word Word
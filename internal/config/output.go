@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+
+	"go.yaml.in/yaml/v3"
+)
+
+type Output string
+
+const (
+	// OutputFiles creates one file per pause and exercise and a playlist.
+	OutputFiles Output = "files"
+	// OutputSingle concatenates the whole workout into one file.
+	OutputSingle Output = "single"
+)
+
+func (o *Output) UnmarshalYAML(node *yaml.Node) error {
+	var y string
+	err := node.Decode(&y)
+	if err != nil {
+		return err
+	}
+	switch Output(y) {
+	case OutputFiles, OutputSingle:
+		*o = Output(y)
+		return nil
+	default:
+		return fmt.Errorf("unknown output '%s': use files or single", y)
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/mrclmr/w2a/internal/m3u"
+	"go.yaml.in/yaml/v3"
+)
+
+type PlaylistPaths string
+
+const (
+	// PlaylistPathsURI writes percent-escaped absolute paths with the file:// scheme.
+	PlaylistPathsURI PlaylistPaths = "uri"
+	// PlaylistPathsAbsolute writes plain absolute paths.
+	PlaylistPathsAbsolute PlaylistPaths = "absolute"
+	// PlaylistPathsRelative writes the file names, e.g. for VLC on Android or Rockbox.
+	PlaylistPathsRelative PlaylistPaths = "relative"
+)
+
+func (p *PlaylistPaths) UnmarshalYAML(node *yaml.Node) error {
+	var y string
+	err := node.Decode(&y)
+	if err != nil {
+		return err
+	}
+	switch PlaylistPaths(y) {
+	case PlaylistPathsURI, PlaylistPathsAbsolute, PlaylistPathsRelative:
+		*p = PlaylistPaths(y)
+		return nil
+	default:
+		return fmt.Errorf("unknown playlist_paths '%s': use uri, absolute or relative", y)
+	}
+}
+
+func (p PlaylistPaths) PathStyle() m3u.PathStyle {
+	switch p {
+	case PlaylistPathsAbsolute:
+		return m3u.PathAbsolute
+	case PlaylistPathsRelative:
+		return m3u.PathRelative
+	default:
+		return m3u.PathURI
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mrclmr/w2a/internal/audio"
+)
+
+// Key is a yaml key of the workout described by the struct tags of the config structs.
+type Key struct {
+	// Path is the dot-separated path of the key. Elements of lists are marked with '[]'.
+	Path        string
+	Type        string
+	Required    bool
+	Default     string
+	Description string
+}
+
+// typeNames are the names of types which are unmarshalled from a string or differ from their kind.
+var typeNames = map[reflect.Type]string{
+	reflect.TypeFor[time.Duration]():  "duration",
+	reflect.TypeFor[slog.Level]():     "string",
+	reflect.TypeFor[audio.Format]():   "string",
+	reflect.TypeFor[audio.TextTmpl](): "template",
+	reflect.TypeFor[Cue]():            "string or object",
+	reflect.TypeFor[ByteSize]():       "size",
+	reflect.TypeFor[Age]():            "duration",
+}
+
+// Reference returns every yaml key of the workout in the order of the config structs.
+func Reference() []Key {
+	return structKeys(reflect.TypeFor[Workout](), "")
+}
+
+func structKeys(t reflect.Type, prefix string) []Key {
+	var keys []Key
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		path := prefix + name
+		keys = append(keys, Key{
+			Path:        path,
+			Type:        typeName(field.Type),
+			Required:    field.Tag.Get("required") == "true",
+			Default:     field.Tag.Get("default"),
+			Description: field.Tag.Get("doc"),
+		})
+
+		elem := field.Type
+		for elem.Kind() == reflect.Pointer || elem.Kind() == reflect.Slice {
+			if elem.Kind() == reflect.Slice {
+				path += "[]"
+			}
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Struct && elem.PkgPath() == t.PkgPath() {
+			keys = append(keys, structKeys(elem, path+".")...)
+		}
+	}
+	return keys
+}
+
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		return "list of " + typeName(t.Elem())
+	case reflect.Map:
+		return fmt.Sprintf("map of %s to %s", typeName(t.Key()), typeName(t.Elem()))
+	case reflect.Struct:
+		return "object"
+	case reflect.Int:
+		return "integer"
+	case reflect.Float64:
+		return "number"
+	default:
+		return t.Kind().String()
+	}
+}
+
+// ReferenceMarkdown returns the reference of every yaml key of the workout as markdown table.
+func ReferenceMarkdown() string {
+	b := strings.Builder{}
+	b.WriteString("# Workout yaml reference\n\n")
+	b.WriteString("Generated by `w2a docs`. See `w2a --example` for an example workout.\n\n")
+	b.WriteString("| Key | Type | Required | Default | Description |\n")
+	b.WriteString("|-----|------|----------|---------|-------------|\n")
+	for _, k := range Reference() {
+		required := ""
+		if k.Required {
+			required = "yes"
+		}
+		b.WriteString(fmt.Sprintf("| `%s` | %s | %s | %s | %s |\n",
+			k.Path, k.Type, required, escapeMarkdown(k.Default), escapeMarkdown(k.Description)))
+	}
+	return b.String()
+}
+
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/mrclmr/w2a/internal/audio"
+)
+
+type Retry struct {
+	TTS     *RetryPolicy `yaml:"tts" doc:"Retry of say, espeak-ng or custom_command"`
+	Sox     *RetryPolicy `yaml:"sox" doc:"Retry of sox_ng"`
+	Convert *RetryPolicy `yaml:"convert" doc:"Retry of ffmpeg or afconvert"`
+}
+
+// Retries returns no retries for a nil Retry or unset policies.
+func (r *Retry) Retries() audio.Retries {
+	if r == nil {
+		return audio.Retries{}
+	}
+	return audio.Retries{
+		TTS:     r.TTS.retry(),
+		Sox:     r.Sox.retry(),
+		Convert: r.Convert.retry(),
+	}
+}
+
+type RetryPolicy struct {
+	Count   int           `yaml:"count" doc:"Retries after the first attempt" default:"0"`
+	Backoff time.Duration `yaml:"backoff" doc:"Wait before the first retry, doubles after every failed attempt" default:"0s"`
+}
+
+func (r *RetryPolicy) retry() audio.Retry {
+	if r == nil {
+		return audio.Retry{}
+	}
+	return audio.Retry{
+		Count:   r.Count,
+		Backoff: r.Backoff,
+	}
+}
+
+type retryPolicy RetryPolicy
+
+func (r *RetryPolicy) UnmarshalYAML(node *yaml.Node) error {
+	var y retryPolicy
+	err := node.Decode(&y)
+	if err != nil {
+		return err
+	}
+	if y.Count < 0 {
+		return fmt.Errorf("retry.count must not be negative")
+	}
+	if y.Backoff < 0 {
+		return fmt.Errorf("retry.backoff must not be negative")
+	}
+
+	r.Count = y.Count
+	r.Backoff = y.Backoff
+	return nil
+}
@@ -0,0 +1,10 @@
+package config
+
+import "github.com/mrclmr/w2a/internal/audio"
+
+// Tags are templates of tag values with the same values as the announcements.
+type Tags struct {
+	Title   *audio.TextTmpl `yaml:"title" doc:"Template of the title tag of the exercise files" default:"exercise name"`
+	Album   *audio.TextTmpl `yaml:"album" doc:"Template of the album tag" default:"name"`
+	Comment *audio.TextTmpl `yaml:"comment" doc:"Template of the comment tag"`
+}
@@ -11,9 +11,16 @@ import (
 )
 
 type TTSCmd struct {
-	SayVoice      string `yaml:"say_voice"`
-	ESpeakNGVoice string `yaml:"espeak_ng_voice"`
-	CustomCommand string `yaml:"custom_command"`
+	SayVoice      string    `yaml:"say_voice" doc:"Voice of say, only on macOS"`
+	ESpeakNGVoice string    `yaml:"espeak_ng_voice" doc:"Voice of espeak-ng"`
+	ESpeakNG      *ESpeakNG `yaml:"espeak_ng" doc:"espeak-ng with additional options"`
+	CustomCommand string    `yaml:"custom_command" doc:"Command with the arguments %[1]s (path to wav file) and %[2]s (text)"`
+	Rate          int       `yaml:"rate" doc:"Speech rate in words per minute for say and espeak-ng" default:"engine default"`
+	Tempo         float64   `yaml:"tempo" doc:"Speed of the speech between 0.5 and 2 without changing the pitch" default:"1"`
+	Batch         bool      `yaml:"batch" doc:"Speak many texts per call of say or espeak-ng and split the audio at the pauses between them" default:"false"`
+
+	MaxConcurrent     int     `yaml:"max_concurrent" doc:"TTS commands running at the same time" default:"no limit"`
+	RequestsPerSecond float64 `yaml:"requests_per_second" doc:"TTS commands started per second" default:"no limit"`
 }
 
 func (t *TTSCmd) TTS() *audio.TTS {
@@ -21,20 +28,70 @@ func (t *TTSCmd) TTS() *audio.TTS {
 		return &audio.TTS{
 			TTSCmd: audio.Say,
 			Voice:  t.SayVoice,
+			Rate:   t.Rate,
+			Tempo:  t.Tempo,
+			Batch:  t.Batch,
+
+			MaxConcurrent:     t.MaxConcurrent,
+			RequestsPerSecond: t.RequestsPerSecond,
 		}
 	}
 	if t.ESpeakNGVoice != "" {
 		return &audio.TTS{
 			TTSCmd: audio.EspeakNG,
 			Voice:  t.ESpeakNGVoice,
+			Rate:   t.Rate,
+			Tempo:  t.Tempo,
+			Batch:  t.Batch,
+
+			MaxConcurrent:     t.MaxConcurrent,
+			RequestsPerSecond: t.RequestsPerSecond,
+		}
+	}
+	if t.ESpeakNG != nil {
+		return &audio.TTS{
+			TTSCmd: audio.EspeakNG,
+			Voice:  t.ESpeakNG.voice(),
+			Rate:   t.Rate,
+			Tempo:  t.Tempo,
+			Batch:  t.Batch,
+			ESpeakNG: audio.ESpeakNGOptions{
+				Variant:   t.ESpeakNG.Variant,
+				Amplitude: t.ESpeakNG.Amplitude,
+				WordGap:   t.ESpeakNG.WordGap,
+			},
+
+			MaxConcurrent:     t.MaxConcurrent,
+			RequestsPerSecond: t.RequestsPerSecond,
 		}
 	}
 	return &audio.TTS{
 		TTSCmd: audio.Custom,
 		Voice:  t.CustomCommand,
+		Tempo:  t.Tempo,
+
+		MaxConcurrent:     t.MaxConcurrent,
+		RequestsPerSecond: t.RequestsPerSecond,
 	}
 }
 
+// SetVoice replaces the voice of say or espeak-ng.
+// A MBROLA voice of espeak-ng is replaced as well.
+func (t *TTSCmd) SetVoice(voice string) error {
+	switch {
+	case t.SayVoice != "":
+		t.SayVoice = voice
+	case t.ESpeakNGVoice != "":
+		t.ESpeakNGVoice = voice
+	case t.ESpeakNG != nil:
+		t.ESpeakNG.Voice = voice
+		t.ESpeakNG.MBROLA = ""
+	default:
+		return fmt.Errorf("voice is not available for tts.custom_command")
+	}
+	return nil
+}
+
 type ttsCmd TTSCmd
 
 func (t *TTSCmd) UnmarshalYAML(node *yaml.Node) error {
@@ -48,22 +105,41 @@ func (t *TTSCmd) UnmarshalYAML(node *yaml.Node) error {
 		return fmt.Errorf("tts.say_voice is only available on macOS")
 	}
 
-	if err := checkOneSet(y.SayVoice, y.ESpeakNGVoice, y.CustomCommand); err != nil {
+	if err := checkOneSet(y.SayVoice != "", y.ESpeakNGVoice != "", y.ESpeakNG != nil, y.CustomCommand != ""); err != nil {
 		return err
 	}
 
+	if y.Rate < 0 {
+		return fmt.Errorf("tts.rate must not be negative")
+	}
+	if y.Tempo != 0 && (y.Tempo < 0.5 || y.Tempo > 2) {
+		return fmt.Errorf("tts.tempo must be between 0.5 and 2")
+	}
+	if y.MaxConcurrent < 0 {
+		return fmt.Errorf("tts.max_concurrent must not be negative")
+	}
+	if y.RequestsPerSecond < 0 {
+		return fmt.Errorf("tts.requests_per_second must not be negative")
+	}
+
 	t.SayVoice = y.SayVoice
 	t.ESpeakNGVoice = y.ESpeakNGVoice
+	t.ESpeakNG = y.ESpeakNG
 	t.CustomCommand = y.CustomCommand
+	t.Rate = y.Rate
+	t.Tempo = y.Tempo
+	t.Batch = y.Batch
+	t.MaxConcurrent = y.MaxConcurrent
+	t.RequestsPerSecond = y.RequestsPerSecond
 	return nil
 }
 
-func checkOneSet(args ...string) error {
-	args = slices.DeleteFunc(args, func(s string) bool {
-		return s == ""
+func checkOneSet(set ...bool) error {
+	set = slices.DeleteFunc(set, func(b bool) bool {
+		return !b
 	})
-	if len(args) != 1 {
-		return fmt.Errorf("set only one: tts.say_voice, tts.espeak_ng_voice or tts.custom_command")
+	if len(set) != 1 {
+		return fmt.Errorf("set only one: tts.say_voice, tts.espeak_ng_voice, tts.espeak_ng or tts.custom_command")
 	}
 	return nil
 }
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"strings"
+)
+
+// ErrNoTTS is returned if no TTS engine is installed.
+var ErrNoTTS = errors.New("no TTS engine found: install espeak-ng or set key 'tts'")
+
+// LookPath is exec.LookPath.
+type LookPath = func(file string) (string, error)
+
+// Default voices per language. Keys are lowercase ISO 639-1 codes.
+var (
+	sayVoices = map[string]string{
+		"de": "Anna",
+		"en": "Daniel",
+		"es": "Mónica",
+		"fr": "Thomas",
+		"it": "Alice",
+		"nl": "Xander",
+	}
+	eSpeakNGVoices = map[string]string{
+		"en": "en-gb",
+	}
+)
+
+// DetectTTS returns the best available TTS engine with a default voice for the language.
+// say is preferred over espeak-ng because it sounds more natural.
+func DetectTTS(lookPath LookPath, goos string, language string) (*TTSCmd, error) {
+	language = strings.ToLower(cmp.Or(language, "en"))
+	lang, _, _ := strings.Cut(language, "-")
+
+	if goos == "darwin" {
+		if _, err := lookPath("say"); err == nil {
+			voice, ok := sayVoices[lang]
+			if ok {
+				return &TTSCmd{SayVoice: voice}, nil
+			}
+		}
+	}
+	if _, err := lookPath("espeak-ng"); err == nil {
+		// espeak-ng voices are named by language.
+		voice := language
+		if v, ok := eSpeakNGVoices[language]; ok {
+			voice = v
+		}
+		return &TTSCmd{ESpeakNGVoice: voice}, nil
+	}
+	return nil, ErrNoTTS
+}
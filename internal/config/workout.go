@@ -1,23 +1,52 @@
 package config
 
 import (
+	"cmp"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/mrclmr/w2a/internal/audio"
 	"go.yaml.in/yaml/v3"
 )
 
 type Workout struct {
-	LogLevel          slog.Level      `yaml:"log_level"`
-	TTS               *TTSCmd         `yaml:"tts"`
-	AudioFormat       audio.Format    `yaml:"audio_format"`
-	I18n              *I18n           `yaml:"i18n"`
-	BeforeWorkoutText *audio.TextTmpl `yaml:"before_workout_announce"`
-	AfterWorkoutText  *audio.TextTmpl `yaml:"after_workout_announce"`
-	Pause             *Announce       `yaml:"pause"`
-	HalfTime          *Announce       `yaml:"half_time"`
-	ExerciseBeginning *audio.TextTmpl `yaml:"exercise_beginning"`
-	Exercises         []Exercise      `yaml:"exercises"`
+	Name               string            `yaml:"name" doc:"Album name in the tags of the output files" default:"yaml filename without extension"`
+	Cover              string            `yaml:"cover" doc:"Image (jpeg or png) embedded as album art, relative to the yaml file"`
+	Tags               *Tags             `yaml:"tags" doc:"Templates of the tags of the output files"`
+	LogLevel           slog.Level        `yaml:"log_level" doc:"Log level: debug, info, warn or error" default:"info"`
+	TTS                *TTSCmd           `yaml:"tts" doc:"TTS engine, set only one of its keys" default:"detected with a default voice for i18n.language"`
+	AudioFormat        audio.Format      `yaml:"audio_format" doc:"Audio format: m4a, mp3, wav, opus, ogg or m4b" default:"m4a"`
+	AudioBitrate       string            `yaml:"audio_bitrate" doc:"Bitrate for ffmpeg" default:"256k, opus 64k, ogg 128k"`
+	PipelineSampleRate int               `yaml:"pipeline_sample_rate" doc:"Sample rate of the intermediate wav files: 22050, 44100 or 48000" default:"22050"`
+	Channels           int               `yaml:"channels" doc:"Channels of the output files: 1 (mono) or 2 (stereo)" default:"2"`
+	ReplayGain         bool              `yaml:"replay_gain" doc:"Write ReplayGain tags" default:"false"`
+	I18n               *I18n             `yaml:"i18n" doc:"Words for the spoken durations" required:"true"`
+	BeforeWorkoutText  *audio.TextTmpl   `yaml:"before_workout_announce" doc:"Template spoken before the workout"`
+	AfterWorkoutText   *audio.TextTmpl   `yaml:"after_workout_announce" doc:"Template spoken after the workout"`
+	Pause              *Announce         `yaml:"pause" doc:"Pause before every exercise" required:"true"`
+	HalfTime           *Announce         `yaml:"half_time" doc:"Announcement in the middle of exercises with half_time" required:"true"`
+	ExerciseBeginning  *audio.TextTmpl   `yaml:"exercise_beginning" doc:"Template spoken after the start sound of an exercise" required:"true"`
+	Exercises          []Exercise        `yaml:"exercises" doc:"Exercises of the workout" required:"true"`
+	Retry              *Retry            `yaml:"retry" doc:"Retries of failed commands per command type" default:"no retries"`
+	Cache              *Cache            `yaml:"cache" doc:"Limits of the intermediate files, the least recently used files are removed after a run"`
+	HashLength         int               `yaml:"hash_length" doc:"Hex characters of the hashes in the file names between 7 and 64, longer hashes avoid collisions in large exercise libraries" default:"7"`
+	Timeout            time.Duration     `yaml:"timeout" doc:"Cancel a command that runs longer" default:"no timeout"`
+	Recordings         map[string]string `yaml:"recordings" doc:"wav files used instead of TTS for exactly matching texts, relative to the yaml file"`
+	SoundsDir          string            `yaml:"sounds_dir" doc:"Directory with start.wav and success.wav replacing the built-in sounds, relative to the yaml file"`
+	LoudnessTarget     float64           `yaml:"loudness_target" doc:"Integrated loudness of every output file in LUFS between -70 and -5" default:"not normalized"`
+	BackgroundMusic    *BackgroundMusic  `yaml:"background_music" doc:"Background music mixed under every output file"`
+	Countdown          Countdown         `yaml:"countdown" doc:"Countdown at the end of pauses and exercises: spoken, beeps or both" default:"spoken"`
+	Output             Output            `yaml:"output" doc:"Output files: files (one per pause and exercise and a playlist) or single" default:"files"`
+	PlaylistPaths      PlaylistPaths     `yaml:"playlist_paths" doc:"Paths in the playlist: uri (file:// with escaped absolute paths), absolute or relative" default:"uri"`
+	LRC                bool              `yaml:"lrc" doc:"Write a .lrc file with the timestamps of the spoken texts next to every output file"`
+	VTT                bool              `yaml:"vtt" doc:"Write a WebVTT .vtt file with the spoken texts as captions next to every output file"`
+	PlaylistName       string            `yaml:"playlist_name" doc:"File name of the playlist without the extension .m3u" default:"playlist"`
+	ExercisesPlaylist  bool              `yaml:"exercises_playlist" doc:"Write a second playlist <playlist_name>-exercises.m3u without the pauses"`
+	OutputDir          string            `yaml:"output_dir" doc:"Directory of the output files, relative to the yaml file, --output-dir takes precedence" default:"output-w2a"`
+	TempDir            string            `yaml:"temp_dir" doc:"Directory of the intermediate files, relative to the yaml file, --temp-dir takes precedence" default:"w2a-intermediate-files in the temp directory"`
+	Sync               string            `yaml:"sync" doc:"Directory the output files are mirrored to after a run, e.g. a mounted phone, relative to the yaml file, --sync takes precedence"`
 }
 
 type workout Workout
@@ -28,9 +57,6 @@ func (w *Workout) UnmarshalYAML(node *yaml.Node) error {
 	if err != nil {
 		return err
 	}
-	if y.TTS == nil {
-		return keyEmptyError("tts")
-	}
 	if y.Pause == nil {
 		return keyEmptyError("pause")
 	}
@@ -46,10 +72,39 @@ func (w *Workout) UnmarshalYAML(node *yaml.Node) error {
 	if len(y.Exercises) == 0 {
 		return keyEmptyError("exercises")
 	}
+	if y.LoudnessTarget != 0 && (y.LoudnessTarget < -70 || y.LoudnessTarget > -5) {
+		return fmt.Errorf("loudness_target must be between -70 and -5 LUFS")
+	}
+	switch y.PipelineSampleRate {
+	case 0, 22050, 44100, 48000:
+	default:
+		return fmt.Errorf("pipeline_sample_rate must be 22050, 44100 or 48000")
+	}
+	if y.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if y.HashLength != 0 && (y.HashLength < audio.DefaultHashLength || y.HashLength > audio.MaxHashLength) {
+		return fmt.Errorf("hash_length must be between %d and %d", audio.DefaultHashLength, audio.MaxHashLength)
+	}
+	if y.Channels < 0 || y.Channels > 2 {
+		return fmt.Errorf("channels must be 1 (mono) or 2 (stereo)")
+	}
+	for text, path := range y.Recordings {
+		if text == "" || path == "" {
+			return keyEmptyError("recordings")
+		}
+	}
+	if strings.ContainsAny(y.PlaylistName, `/\`) {
+		return fmt.Errorf("playlist_name must not contain a path separator")
+	}
 
+	w.Name = y.Name
+	w.Cover = y.Cover
+	w.Tags = y.Tags
 	w.LogLevel = y.LogLevel
 	w.TTS = y.TTS
 	w.AudioFormat = y.AudioFormat
+	w.AudioBitrate = y.AudioBitrate
 	w.I18n = y.I18n
 	w.BeforeWorkoutText = y.BeforeWorkoutText
 	w.AfterWorkoutText = y.AfterWorkoutText
@@ -57,5 +112,26 @@ func (w *Workout) UnmarshalYAML(node *yaml.Node) error {
 	w.HalfTime = y.HalfTime
 	w.ExerciseBeginning = y.ExerciseBeginning
 	w.Exercises = y.Exercises
+	w.Retry = y.Retry
+	w.Cache = y.Cache
+	w.HashLength = cmp.Or(y.HashLength, audio.DefaultHashLength)
+	w.Timeout = y.Timeout
+	w.Recordings = y.Recordings
+	w.SoundsDir = y.SoundsDir
+	w.LoudnessTarget = y.LoudnessTarget
+	w.BackgroundMusic = y.BackgroundMusic
+	w.Countdown = cmp.Or(y.Countdown, CountdownSpoken)
+	w.Output = cmp.Or(y.Output, OutputFiles)
+	w.PlaylistPaths = cmp.Or(y.PlaylistPaths, PlaylistPathsURI)
+	w.LRC = y.LRC
+	w.VTT = y.VTT
+	w.PlaylistName = cmp.Or(y.PlaylistName, "playlist")
+	w.ExercisesPlaylist = y.ExercisesPlaylist
+	w.OutputDir = y.OutputDir
+	w.TempDir = y.TempDir
+	w.Sync = y.Sync
+	w.PipelineSampleRate = y.PipelineSampleRate
+	w.Channels = y.Channels
+	w.ReplayGain = y.ReplayGain
 	return nil
 }
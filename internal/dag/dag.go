@@ -3,13 +3,15 @@ package dag
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"iter"
+	"runtime"
 	"slices"
 	"strings"
 	"sync"
-
-	"golang.org/x/sync/errgroup"
+	"sync/atomic"
+	"time"
 )
 
 // Node must be implemented to add a node to the graph.
@@ -19,20 +21,153 @@ type Node[T comparable] interface {
 	Run(ctx context.Context, values []T) (T, error)
 }
 
+// Skipper can be implemented by nodes to report that running them would do nothing,
+// e.g. because the result already exists. It is used by Plan and reported by Event.
+type Skipper interface {
+	Skip() bool
+}
+
+// Retrier can be implemented by nodes to define how often they are run again after an error.
+// It takes precedence over WithRetry.
+type Retrier interface {
+	Retry() Retry
+}
+
+// Timeouter can be implemented by nodes to limit the duration of a run.
+// A positive timeout takes precedence over WithTimeout.
+type Timeouter interface {
+	Timeout() time.Duration
+}
+
+// Retry defines how often a failed node is run again.
+// The backoff doubles after every failed attempt.
+type Retry struct {
+	Count   int
+	Backoff time.Duration
+	// Retryable reports if the error is worth another attempt. All errors are retried if nil.
+	Retryable func(err error) bool
+}
+
+// Hooks are called while a node runs. Nil hooks are not called.
+// Unlike WithProgress, hooks are called by the worker running the node,
+// so they may be called concurrently.
+type Hooks struct {
+	// OnNodeStart is called before a node runs.
+	OnNodeStart func(name string)
+	// OnNodeFinish is called after a node ran successfully.
+	OnNodeFinish func(name string, duration time.Duration)
+	// OnNodeError is called after a node failed, including all retries.
+	OnNodeError func(name string, err error)
+}
+
 // Dag is a directed acyclic graph.
 type Dag[T comparable] struct {
 	hashToIdx map[string]int
 	nodes     []*node[T]
+	// workers limits the nodes running at the same time.
+	workers         int
+	levels          bool
+	continueOnError bool
+	progress        *progress
+	hooks           Hooks
+	retry           Retry
+	timeout         time.Duration
+	// problems are found while adding nodes and edges and reported by Validate.
+	problems []error
+}
+
+type options struct {
+	maxParallel     int
+	progress        func(Event)
+	hooks           Hooks
+	retry           Retry
+	timeout         time.Duration
+	levels          bool
+	continueOnError bool
+}
+
+// Option configures a Dag.
+type Option func(*options)
+
+// WithMaxParallel limits the nodes running at the same time. Default is the number of CPUs.
+// Nodes waiting for their children do not count.
+func WithMaxParallel(n int) Option {
+	return func(o *options) {
+		o.maxParallel = n
+	}
+}
+
+// WithProgress calls fn when a node starts and when it finishes.
+// fn is called sequentially.
+func WithProgress(fn func(Event)) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// WithHooks sets the hooks called while nodes run.
+func WithHooks(h Hooks) Option {
+	return func(o *options) {
+		o.hooks = h
+	}
+}
+
+// WithRetry sets the Retry of nodes not implementing Retrier. Default is no retry.
+func WithRetry(r Retry) Option {
+	return func(o *options) {
+		o.retry = r
+	}
+}
+
+// WithTimeout limits the duration of every run attempt of nodes not implementing Timeouter.
+// Default is no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithLevels runs the nodes level by level: a node starts only after all nodes
+// of lower levels are complete. Leaves have level 0, every other node has one more
+// than the highest level of its children.
+func WithLevels() Option {
+	return func(o *options) {
+		o.levels = true
+	}
+}
+
+// WithContinueOnError runs all nodes which do not depend on a failed node.
+// The iterators yield the error for every passed node depending on failed nodes
+// and continue with the next one. The errors of several failed nodes are joined.
+func WithContinueOnError() Option {
+	return func(o *options) {
+		o.continueOnError = true
+	}
 }
 
 // New return a new Dag.
-func New[T comparable]() *Dag[T] {
-	return &Dag[T]{
-		hashToIdx: make(map[string]int),
+func New[T comparable](opts ...Option) *Dag[T] {
+	o := options{maxParallel: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	d := &Dag[T]{
+		hashToIdx:       make(map[string]int),
+		workers:         max(1, o.maxParallel),
+		levels:          o.levels,
+		continueOnError: o.continueOnError,
+		hooks:           o.hooks,
+		retry:           o.retry,
+		timeout:         o.timeout,
+	}
+	if o.progress != nil {
+		d.progress = &progress{fn: o.progress}
 	}
+	return d
 }
 
 // RunRootNodes starts execution by running the root nodes.
+// If the running nodes are limited, the nodes needed by root nodes added first are run first.
 func (d *Dag[T]) RunRootNodes(ctx context.Context) iter.Seq2[T, error] {
 	nodes, err := d.rootNodes()
 	if err != nil {
@@ -41,10 +176,22 @@ func (d *Dag[T]) RunRootNodes(ctx context.Context) iter.Seq2[T, error] {
 			yield(zero, err)
 		}
 	}
-	return runNodes(ctx, nodes)
+	d.progress.start(pending(nodes))
+	prioritize(nodes)
+	return runNodes(ctx, nodes, d.workers, d.levels, d.continueOnError)
+}
+
+// RunAll runs all disconnected subgraphs including isolated nodes.
+// Unlike RunRootNodes it does not fail on nodes without parents and children.
+func (d *Dag[T]) RunAll(ctx context.Context) iter.Seq2[T, error] {
+	nodes := d.allRootNodes()
+	d.progress.start(pending(nodes))
+	prioritize(nodes)
+	return runNodes(ctx, nodes, d.workers, d.levels, d.continueOnError)
 }
 
 // RunNodes starts execution by running passed nodes.
+// If the running nodes are limited, the nodes needed by earlier passed nodes are run first.
 func (d *Dag[T]) RunNodes(ctx context.Context, nodes []Node[T]) iter.Seq2[T, error] {
 	nodesToRun := make([]*node[T], 0)
 	for _, n := range nodes {
@@ -53,7 +200,125 @@ func (d *Dag[T]) RunNodes(ctx context.Context, nodes []Node[T]) iter.Seq2[T, err
 			nodesToRun = append(nodesToRun, d.nodes[idx])
 		}
 	}
-	return runNodes(ctx, nodesToRun)
+	d.progress.start(pending(nodesToRun))
+	prioritize(nodesToRun)
+	return runNodes(ctx, nodesToRun, d.workers, d.levels, d.continueOnError)
+}
+
+// Completion is the result of a node passed to RunNodesUnordered.
+type Completion[T comparable] struct {
+	// Index is the position of the node in the passed nodes.
+	Index  int
+	Result T
+	Err    error
+}
+
+// RunNodesUnordered runs the passed nodes like RunNodes but yields every result
+// as soon as the node is complete, not in the order the nodes were passed.
+func (d *Dag[T]) RunNodesUnordered(ctx context.Context, nodes []Node[T]) iter.Seq[Completion[T]] {
+	nodesToRun := make([]*node[T], 0)
+	indexes := make([]int, 0)
+	for i, n := range nodes {
+		idx, ok := d.hashToIdx[n.Hash()]
+		if ok {
+			nodesToRun = append(nodesToRun, d.nodes[idx])
+			indexes = append(indexes, i)
+		}
+	}
+	d.progress.start(pending(nodesToRun))
+	prioritize(nodesToRun)
+	return func(yield func(Completion[T]) bool) {
+		for c := range runNodesUnordered(ctx, nodesToRun, d.workers, d.levels, d.continueOnError) {
+			c.Index = indexes[c.Index]
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
+
+// Step is a node in the execution order returned by Plan.
+type Step struct {
+	Name string
+	Hash string
+	// Skipped is true if the node was executed before or reports to be skipped by Skipper.
+	Skipped bool
+}
+
+// Plan returns the steps to run the passed nodes, or all root nodes if none are passed,
+// without running them. Children come before their parents.
+func (d *Dag[T]) Plan(nodes ...Node[T]) ([]Step, error) {
+	var start []*node[T]
+	if len(nodes) == 0 {
+		roots, err := d.rootNodes()
+		if err != nil {
+			return nil, err
+		}
+		start = roots
+	}
+	for _, n := range nodes {
+		idx, ok := d.hashToIdx[n.Hash()]
+		if !ok {
+			return nil, fmt.Errorf("node %s not in graph", n.Name())
+		}
+		start = append(start, d.nodes[idx])
+	}
+
+	var steps []Step
+	visited := make(map[int]bool)
+	var visit func(n *node[T])
+	visit = func(n *node[T]) {
+		if visited[n.id] {
+			return
+		}
+		visited[n.id] = true
+		for _, c := range n.children {
+			visit(c)
+		}
+		n.lock.Lock()
+		skipped := n.runFuncExecuted
+		n.lock.Unlock()
+		if !skipped && n.skip != nil {
+			skipped = n.skip()
+		}
+		steps = append(steps, Step{Name: n.name, Hash: n.hash, Skipped: skipped})
+	}
+	for _, n := range start {
+		visit(n)
+	}
+	return steps, nil
+}
+
+// Topo returns all nodes in dependency order: every node comes after its children.
+// The order is deterministic and follows the order the nodes were added.
+func (d *Dag[T]) Topo() iter.Seq[Node[T]] {
+	return func(yield func(Node[T]) bool) {
+		visited := make(map[int]bool)
+		var visit func(n *node[T]) bool
+		visit = func(n *node[T]) bool {
+			if visited[n.id] {
+				return true
+			}
+			visited[n.id] = true
+			for _, c := range n.children {
+				if !visit(c) {
+					return false
+				}
+			}
+			return yield(n.node)
+		}
+		for _, n := range d.nodes {
+			if !visit(n) {
+				return
+			}
+		}
+	}
+}
+
+// AddNode adds a node without edges. It is not reported as orphaned by RunRootNodes.
+func (d *Dag[T]) AddNode(n Node[T]) {
+	id := d.addNode(n)
+	d.nodes[id].isolated = true
 }
 
 // AddChain adds a slice of connected nodes. The first node is the root.
@@ -99,7 +364,9 @@ func (d *Dag[T]) AddEdge(parent Node[T], child Node[T]) error {
 		return nil
 	}
 	if d.hasPath(childNode, parentNode) {
-		return fmt.Errorf("cyclic dependency: %s to %s", parentNode.name, childNode.name)
+		err := fmt.Errorf("cyclic dependency: %s to %s", parentNode.name, childNode.name)
+		d.addProblem(err)
+		return err
 	}
 
 	parentNode.children = append(parentNode.children, childNode)
@@ -110,18 +377,63 @@ func (d *Dag[T]) AddEdge(parent Node[T], child Node[T]) error {
 func (d *Dag[T]) addNode(n Node[T]) (id int) {
 	hash := n.Hash()
 	id, exists := d.hashToIdx[hash]
+	if exists && d.nodes[id].name != n.Name() {
+		d.addProblem(fmt.Errorf("hash conflict: %s of %s already used by %s", hash, n.Name(), d.nodes[id].name))
+	}
 	if !exists {
 		id = len(d.nodes)
 		d.hashToIdx[hash] = id
+		var skip func() bool
+		if s, ok := n.(Skipper); ok {
+			skip = s.Skip
+		}
+		retry := d.retry
+		if r, ok := n.(Retrier); ok {
+			retry = r.Retry()
+		}
+		timeout := d.timeout
+		if t, ok := n.(Timeouter); ok && t.Timeout() > 0 {
+			timeout = t.Timeout()
+		}
 		d.nodes = append(d.nodes, &node[T]{
-			id:      id,
-			name:    n.Name(),
-			runFunc: n.Run,
+			id:       id,
+			node:     n,
+			name:     n.Name(),
+			hash:     hash,
+			skip:     skip,
+			retry:    retry,
+			timeout:  timeout,
+			runFunc:  n.Run,
+			progress: d.progress,
+			hooks:    d.hooks,
 		})
 	}
 	return id
 }
 
+// addProblem records err once for Validate.
+func (d *Dag[T]) addProblem(err error) {
+	if slices.ContainsFunc(d.problems, func(p error) bool { return p.Error() == err.Error() }) {
+		return
+	}
+	d.problems = append(d.problems, err)
+}
+
+// Validate returns all problems of the graph joined: the rejected edges which would have
+// created a cycle, different nodes with the same hash and orphaned nodes.
+// It returns nil if the graph has no problems.
+func (d *Dag[T]) Validate() error {
+	problems := slices.Clone(d.problems)
+	if len(d.nodes) > 1 {
+		for _, n := range d.allRootNodes() {
+			if n.children == nil && !n.isolated {
+				problems = append(problems, fmt.Errorf("node %s is orphaned", n.name))
+			}
+		}
+	}
+	return errors.Join(problems...)
+}
+
 // hasPath checks if there is already a path from start to target node (DFS).
 func (d *Dag[T]) hasPath(src, dst *node[T]) bool {
 	visited := make(map[int]bool)
@@ -147,7 +459,20 @@ func (d *Dag[T]) hasChild(parent *node[T], childID int) bool {
 	})
 }
 
+// rootNodes returns the nodes without parents. A node without parents and children
+// is orphaned unless it was added with AddNode or is the only node.
 func (d *Dag[T]) rootNodes() ([]*node[T], error) {
+	roots := d.allRootNodes()
+	for _, n := range roots {
+		if len(d.nodes) > 1 && n.children == nil && !n.isolated {
+			return nil, fmt.Errorf("node %v is orphaned", n)
+		}
+	}
+	return roots, nil
+}
+
+// allRootNodes returns the nodes without parents including isolated nodes.
+func (d *Dag[T]) allRootNodes() []*node[T] {
 	childSet := make(map[int]bool)
 
 	for _, n := range d.nodes {
@@ -159,14 +484,11 @@ func (d *Dag[T]) rootNodes() ([]*node[T], error) {
 	var roots []*node[T]
 	for id, n := range d.nodes {
 		if !childSet[id] {
-			if len(d.nodes) > 1 && n.children == nil {
-				return nil, fmt.Errorf("node %v is orphaned", n)
-			}
 			roots = append(roots, n)
 		}
 	}
 
-	return roots, nil
+	return roots
 }
 
 func (d *Dag[T]) String() string {
@@ -176,11 +498,13 @@ func (d *Dag[T]) String() string {
 
 	for _, n := range d.nodes {
 		b.WriteString(fmt.Sprintf("    \"%d\" [label=\"", n.id))
-		b.WriteString(fmt.Sprintf("name: %s", n.name))
+		b.WriteString(fmt.Sprintf("name: %s", dotEscaper.Replace(n.name)))
 		b.WriteString("\\n")
 		b.WriteString(fmt.Sprintf("id: %d", n.id))
-		b.WriteString("\\n")
-		b.WriteString(fmt.Sprintf("result: %v", n.result))
+		if result, ok := n.executed(); ok {
+			b.WriteString("\\n")
+			b.WriteString(fmt.Sprintf("result: %v", result))
+		}
 		b.WriteString("\"];\n")
 	}
 
@@ -194,18 +518,63 @@ func (d *Dag[T]) String() string {
 	return b.String()
 }
 
+// Mermaid returns the graph as Mermaid flowchart.
+func (d *Dag[T]) Mermaid() string {
+	b := strings.Builder{}
+
+	b.WriteString("flowchart TD\n")
+
+	for _, n := range d.nodes {
+		b.WriteString(fmt.Sprintf("    %d[\"", n.id))
+		b.WriteString(fmt.Sprintf("name: %s", mermaidEscaper.Replace(n.name)))
+		b.WriteString("<br>")
+		b.WriteString(fmt.Sprintf("id: %d", n.id))
+		if result, ok := n.executed(); ok {
+			b.WriteString("<br>")
+			b.WriteString(fmt.Sprintf("result: %s", mermaidEscaper.Replace(fmt.Sprint(result))))
+		}
+		b.WriteString("\"]\n")
+	}
+
+	for id, n := range d.nodes {
+		for _, c := range n.children {
+			b.WriteString(fmt.Sprintf("    %d --> %d\n", id, c.id))
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+var (
+	dotEscaper     = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	mermaidEscaper = strings.NewReplacer(`"`, "#quot;")
+)
+
 type node[T comparable] struct {
-	id       int
-	name     string
+	id   int
+	node Node[T]
+	name string
+	hash string
+	skip func() bool
+	// isolated nodes were added explicitly without edges.
+	isolated bool
+	retry    Retry
+	timeout  time.Duration
 	children []*node[T]
 
 	lock            sync.Mutex
 	runFunc         func(ctx context.Context, values []T) (result T, err error)
 	runFuncExecuted bool
-	result          T
+	// priority is set before every run. A lower value is run first.
+	priority atomic.Int64
+	progress *progress
+	hooks    Hooks
+	result   T
 }
 
-func (n *node[T]) run(ctx context.Context) (T, error) {
+// execute runs the node with the results of its children.
+// After a successful run further calls return the same result.
+func (n *node[T]) execute(ctx context.Context, values []T) (T, error) {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 
@@ -213,87 +582,201 @@ func (n *node[T]) run(ctx context.Context) (T, error) {
 		return n.result, nil
 	}
 
-	var zeroVal T
-	var results []T
-	if len(n.children) > 0 {
-		rs, err := runChildren(ctx, n.children)
-		if err != nil {
-			return zeroVal, err
-		}
-		results = rs
+	skipped := n.progress != nil && n.skip != nil && n.skip()
+	n.progress.started(n.name)
+	if n.hooks.OnNodeStart != nil {
+		n.hooks.OnNodeStart(n.name)
 	}
-
-	result, err := n.runFunc(ctx, results)
+	start := time.Now()
+	result, err := n.runWithRetry(ctx, values)
+	duration := time.Since(start)
+	n.progress.finished(Event{Name: n.name, Err: err, Duration: duration, Skipped: skipped})
 	if err != nil {
+		if n.hooks.OnNodeError != nil {
+			n.hooks.OnNodeError(n.name, err)
+		}
+		var zeroVal T
 		return zeroVal, err
 	}
+	if n.hooks.OnNodeFinish != nil {
+		n.hooks.OnNodeFinish(n.name, duration)
+	}
 	n.result = result
 	n.runFuncExecuted = true
 	return result, nil
 }
 
-func runChildren[T comparable](ctx context.Context, children []*node[T]) ([]T, error) {
-	results := make([]T, len(children))
-	errg, ctx := errgroup.WithContext(ctx)
-	for i, n := range children {
-		errg.Go(func() error {
-			val, err := n.run(ctx)
-			if err != nil {
-				return err
-			}
-			results[i] = val
-			return nil
-		})
+// executed returns the result if the node was executed before.
+func (n *node[T]) executed() (T, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.result, n.runFuncExecuted
+}
+
+// runWithRetry runs runFunc until it succeeds or the retry policy is exhausted.
+func (n *node[T]) runWithRetry(ctx context.Context, values []T) (T, error) {
+	backoff := n.retry.Backoff
+	for attempt := 0; ; attempt++ {
+		result, err := n.runWithTimeout(ctx, values)
+		if err == nil || attempt >= n.retry.Count || ctx.Err() != nil ||
+			(n.retry.Retryable != nil && !n.retry.Retryable(err)) {
+			return result, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		}
+		backoff *= 2
 	}
-	err := errg.Wait()
-	if err != nil {
-		return nil, err
-	}
-	return results, nil
-}
-
-// runNodes returns an iterator that iterates results as soon as
-// contiguous parts from the start are complete.
-func runNodes[T comparable](ctx context.Context, nodes []*node[T]) iter.Seq2[T, error] {
-	return func(yield func(T, error) bool) {
-		lenNodes := len(nodes)
-		results := make([]T, lenNodes)
-		errs := make([]error, lenNodes)
-		done := make([]bool, lenNodes)
-
-		var mu sync.Mutex
-		cond := sync.NewCond(&mu)
-
-		for i, n := range nodes {
-			go func() {
-				val, err := n.run(ctx)
-				mu.Lock()
-				results[i] = val
-				errs[i] = err
-				done[i] = true
-				cond.Broadcast()
-				mu.Unlock()
-			}()
-		}
-
-		for i := range lenNodes {
-			mu.Lock()
-			for !done[i] {
-				mu.Unlock()
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-				mu.Lock()
-				cond.Wait()
-			}
-			val, err := results[i], errs[i]
-			mu.Unlock()
+}
 
-			if !yield(val, err) || err != nil || ctx.Err() != nil {
-				return
-			}
+// runWithTimeout runs runFunc and fails if it does not return within the timeout.
+func (n *node[T]) runWithTimeout(ctx context.Context, values []T) (T, error) {
+	if n.timeout <= 0 {
+		return n.runFunc(ctx, values)
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	type runResult struct {
+		result T
+		err    error
+	}
+	done := make(chan runResult, 1)
+	go func() {
+		result, err := n.runFunc(ctx, values)
+		done <- runResult{result, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return r.result, fmt.Errorf("%s: timed out after %s: %w", n.name, n.timeout, r.err)
+		}
+		return r.result, r.err
+	case <-ctx.Done():
+		var zeroVal T
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return zeroVal, fmt.Errorf("%s: timed out after %s: %w", n.name, n.timeout, ctx.Err())
 		}
+		return zeroVal, ctx.Err()
+	}
+}
+
+// Event is reported when a node starts or finishes.
+type Event struct {
+	Name     string
+	Finished bool
+	// Err is the error of a finished node.
+	Err error
+	// Completed counts the finished nodes of the current run.
+	Completed int
+	// Total counts the nodes of the current run which were not executed before.
+	Total int
+	// Running are the names of the nodes running right now.
+	Running []string
+	// Duration is the run time of a finished node including retries.
+	Duration time.Duration
+	// Skipped is true if a finished node implementing Skipper reported to be skipped before it ran.
+	Skipped bool
+}
+
+// progress reports events. A nil progress reports nothing.
+type progress struct {
+	fn        func(Event)
+	mu        sync.Mutex
+	total     int
+	completed int
+	running   []string
+}
+
+func (p *progress) start(total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+	p.completed = 0
+}
+
+func (p *progress) started(name string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = append(p.running, name)
+	p.report(Event{Name: name})
+}
+
+func (p *progress) finished(e Event) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if i := slices.Index(p.running, e.Name); i >= 0 {
+		p.running = slices.Delete(p.running, i, i+1)
+	}
+	p.completed++
+	e.Finished = true
+	p.report(e)
+}
+
+// report must be called with the lock held.
+func (p *progress) report(e Event) {
+	e.Completed = p.completed
+	e.Total = p.total
+	e.Running = slices.Clone(p.running)
+	p.fn(e)
+}
+
+// prioritize sets the priority of every node reachable from nodes
+// to the index of the first node it is reachable from.
+func prioritize[T comparable](nodes []*node[T]) {
+	visited := make(map[int]bool)
+	var visit func(n *node[T], priority int64)
+	visit = func(n *node[T], priority int64) {
+		if visited[n.id] {
+			return
+		}
+		visited[n.id] = true
+		n.priority.Store(priority)
+		for _, c := range n.children {
+			visit(c, priority)
+		}
+	}
+	for i, n := range nodes {
+		visit(n, int64(i))
+	}
+}
+
+// pending counts the nodes reachable from nodes which were not executed before.
+func pending[T comparable](nodes []*node[T]) int {
+	visited := make(map[int]bool)
+	total := 0
+	var count func(n *node[T])
+	count = func(n *node[T]) {
+		if visited[n.id] {
+			return
+		}
+		visited[n.id] = true
+		n.lock.Lock()
+		executed := n.runFuncExecuted
+		n.lock.Unlock()
+		if !executed {
+			total++
+		}
+		for _, c := range n.children {
+			count(c)
+		}
+	}
+	for _, n := range nodes {
+		count(n)
 	}
+	return total
 }
@@ -1,8 +1,15 @@
 package dag_test
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -28,22 +35,6 @@ func (c *sourceExecOnce) Hash() string {
 	return "sourceExecOnce"
 }
 
-type rootNode struct {
-	id string
-}
-
-func (r *rootNode) Run(_ context.Context, results []int) (int, error) {
-	return results[0], nil
-}
-
-func (r *rootNode) Name() string {
-	return r.id
-}
-
-func (r *rootNode) Hash() string {
-	return r.id
-}
-
 func TestDag_LeafNodesOnlyCalledOnce(t *testing.T) {
 	d := dag.New[int]()
 	srcInt := &sourceExecOnce{}
@@ -53,7 +44,9 @@ func TestDag_LeafNodesOnlyCalledOnce(t *testing.T) {
 	edges := make([][2]dag.Node[int], rootsLen)
 
 	for i := range rootsLen {
-		root := &rootNode{id: fmt.Sprintf("root%02d", i)}
+		root := dag.NewNodeFunc(fmt.Sprintf("root%02d", i), "", func(_ context.Context, results []int) (int, error) {
+			return results[0], nil
+		})
 		edges[i] = [2]dag.Node[int]{root, srcInt}
 	}
 
@@ -82,54 +75,34 @@ func TestDag_LeafNodesOnlyCalledOnce(t *testing.T) {
 	}
 }
 
-type sumInt struct {
-	value string
-}
-
-func (s *sumInt) Run(_ context.Context, values []int) (int, error) {
-	sum := 0
-	for i := range values {
-		sum += values[i]
-	}
-	return sum, nil
-}
-
-func (s *sumInt) Name() string {
-	return s.value
-}
-
-func (s *sumInt) Hash() string {
-	return s.value
-}
-
-type sourceInt struct {
-	value string
-}
-
-func (s *sourceInt) Run(_ context.Context, _ []int) (int, error) {
-	return 1, nil
-}
-
-func (s *sourceInt) Name() string {
-	return s.value
+func sumInt(name string) *dag.NodeFunc[int] {
+	return dag.NewNodeFunc(name, "", func(_ context.Context, values []int) (int, error) {
+		sum := 0
+		for i := range values {
+			sum += values[i]
+		}
+		return sum, nil
+	})
 }
 
-func (s *sourceInt) Hash() string {
-	return s.value
+func sourceInt(name string) *dag.NodeFunc[int] {
+	return dag.NewNodeFunc(name, "", func(_ context.Context, _ []int) (int, error) {
+		return 1, nil
+	})
 }
 
 func TestDag_CorrectValues(t *testing.T) {
 	d := dag.New[int]()
 
-	sumRoot1 := &sumInt{value: "sum1"}
-	sumRoot2 := &sumInt{value: "sum1"}
-	sum2 := &sumInt{value: "sum2"}
-	sum3 := &sumInt{value: "sum3"}
+	sumRoot1 := sumInt("sum1")
+	sumRoot2 := sumInt("sum1")
+	sum2 := sumInt("sum2")
+	sum3 := sumInt("sum3")
 
-	source1 := &sourceInt{value: "source1"}
-	source2 := &sourceInt{value: "source2"}
-	source3 := &sourceInt{value: "source3"}
-	source4 := &sourceInt{value: "source4"}
+	source1 := sourceInt("source1")
+	source2 := sourceInt("source2")
+	source3 := sourceInt("source3")
+	source4 := sourceInt("source4")
 
 	chains := [][]dag.Node[int]{
 		{sumRoot1, sum2, source1},
@@ -161,11 +134,11 @@ func TestDag_CorrectValues(t *testing.T) {
 func TestDag_OrphanedNode(t *testing.T) {
 	d := dag.New[int]()
 
-	sum := &sumInt{value: "sum"}
-	source1 := &sourceInt{value: "source1"}
-	source2 := &sourceInt{value: "source2"}
+	sum := sumInt("sum")
+	source1 := sourceInt("source1")
+	source2 := sourceInt("source2")
 
-	orphaned := &sourceInt{value: "orphaned"}
+	orphaned := sourceInt("orphaned")
 
 	chains := [][]dag.Node[int]{
 		{sum, source1},
@@ -189,14 +162,14 @@ func TestDag_OrphanedNode(t *testing.T) {
 func TestDag_AddChain(t *testing.T) {
 	d := dag.New[int]()
 
-	sum1 := &sumInt{value: "sum1"}
-	sum2 := &sumInt{value: "sum2"}
-	sum3 := &sumInt{value: "sum3"}
+	sum1 := sumInt("sum1")
+	sum2 := sumInt("sum2")
+	sum3 := sumInt("sum3")
 
-	source1 := &sourceInt{value: "source1"}
-	source2 := &sourceInt{value: "source2"}
-	source3 := &sourceInt{value: "source3"}
-	source4 := &sourceInt{value: "source4"}
+	source1 := sourceInt("source1")
+	source2 := sourceInt("source2")
+	source3 := sourceInt("source3")
+	source4 := sourceInt("source4")
 
 	chains := [][]dag.Node[int]{
 		{sum3, sum1, source1},
@@ -226,8 +199,8 @@ func TestDag_AddChain(t *testing.T) {
 func TestDag_CyclicDependency(t *testing.T) {
 	d := dag.New[int]()
 
-	source1 := &sourceInt{value: "source1"}
-	source2 := &sourceInt{value: "source2"}
+	source1 := sourceInt("source1")
+	source2 := sourceInt("source2")
 
 	edges := [][2]dag.Node[int]{
 		{source1, source2},
@@ -238,3 +211,682 @@ func TestDag_CyclicDependency(t *testing.T) {
 		t.Fatalf("failed to detect cyclic dependency")
 	}
 }
+
+type concurrencyNode struct {
+	id      string
+	running *atomic.Int32
+	maxSeen *atomic.Int32
+}
+
+func (c *concurrencyNode) Run(_ context.Context, _ []int) (int, error) {
+	n := c.running.Add(1)
+	for {
+		seen := c.maxSeen.Load()
+		if n <= seen || c.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	c.running.Add(-1)
+	return 1, nil
+}
+
+func (c *concurrencyNode) Name() string {
+	return c.id
+}
+
+func (c *concurrencyNode) Hash() string {
+	return c.id
+}
+
+func TestDag_WithMaxParallel(t *testing.T) {
+	d := dag.New[int](dag.WithMaxParallel(2))
+
+	var running, maxSeen atomic.Int32
+	sum := sumInt("sum")
+	for i := range 10 {
+		err := d.AddEdge(sum, &concurrencyNode{id: fmt.Sprintf("node%02d", i), running: &running, maxSeen: &maxSeen})
+		if err != nil {
+			t.Fatalf("failed to add edge: %v", err)
+		}
+	}
+
+	for result, err := range d.RunRootNodes(t.Context()) {
+		if err != nil {
+			t.Fatalf("failed to run root nodes: %v", err)
+		}
+		if result != 10 {
+			t.Fatalf("failed to run root nodes: want 10, got %d", result)
+		}
+	}
+	if got := maxSeen.Load(); got > 2 {
+		t.Fatalf("nodes running in parallel: want at most 2, got %d", got)
+	}
+}
+
+// orderNode records the order in which the nodes finish.
+type orderNode struct {
+	id    string
+	mu    *sync.Mutex
+	order *[]string
+	delay time.Duration
+}
+
+func (o *orderNode) Run(_ context.Context, _ []int) (int, error) {
+	time.Sleep(cmp.Or(o.delay, 5*time.Millisecond))
+	o.mu.Lock()
+	*o.order = append(*o.order, o.id)
+	o.mu.Unlock()
+	return 1, nil
+}
+
+func (o *orderNode) Name() string {
+	return o.id
+}
+
+func (o *orderNode) Hash() string {
+	return o.id
+}
+
+func TestDag_RunNodesPriority(t *testing.T) {
+	d := dag.New[int](dag.WithMaxParallel(1))
+
+	var mu sync.Mutex
+	var order []string
+	var nodes []dag.Node[int]
+	var want []string
+	for i := 4; i >= 0; i-- {
+		file := sumInt(fmt.Sprintf("file%d", i))
+		leaf := &orderNode{id: fmt.Sprintf("leaf%d", i), mu: &mu, order: &order}
+		err := d.AddEdge(file, leaf)
+		if err != nil {
+			t.Fatalf("failed to add edge: %v", err)
+		}
+		nodes = append(nodes, file)
+		want = append(want, leaf.id)
+	}
+
+	for _, err := range d.RunNodes(t.Context(), nodes) {
+		if err != nil {
+			t.Fatalf("failed to run nodes: %v", err)
+		}
+	}
+
+	if !slices.Equal(order, want) {
+		t.Fatalf("order: want %v, got %v", want, order)
+	}
+}
+
+func TestDag_WithProgress(t *testing.T) {
+	var events []dag.Event
+	d := dag.New[int](dag.WithProgress(func(e dag.Event) {
+		events = append(events, e)
+	}))
+
+	sum := sumInt("sum")
+	err := d.AddEdges([][2]dag.Node[int]{
+		{sum, sourceInt("source1")},
+		{sum, sourceInt("source2")},
+	})
+	if err != nil {
+		t.Fatalf("failed to add edge: %v", err)
+	}
+
+	for _, err := range d.RunRootNodes(t.Context()) {
+		if err != nil {
+			t.Fatalf("failed to run root nodes: %v", err)
+		}
+	}
+
+	if len(events) != 6 {
+		t.Fatalf("events: want 6, got %d", len(events))
+	}
+	last := events[len(events)-1]
+	if last.Name != "sum" || !last.Finished || last.Completed != 3 || last.Total != 3 || len(last.Running) != 0 {
+		t.Fatalf("last event: got %+v", last)
+	}
+	for _, e := range events {
+		if !e.Finished && !slices.Contains(e.Running, e.Name) {
+			t.Fatalf("started node not running: %+v", e)
+		}
+	}
+}
+
+func TestDag_WithHooks(t *testing.T) {
+	var lock sync.Mutex
+	var started, finished, failed []string
+	d := dag.New[int](dag.WithHooks(dag.Hooks{
+		OnNodeStart: func(name string) {
+			lock.Lock()
+			defer lock.Unlock()
+			started = append(started, name)
+		},
+		OnNodeFinish: func(name string, _ time.Duration) {
+			lock.Lock()
+			defer lock.Unlock()
+			finished = append(finished, name)
+		},
+		OnNodeError: func(name string, _ error) {
+			lock.Lock()
+			defer lock.Unlock()
+			failed = append(failed, name)
+		},
+	}), dag.WithMaxParallel(1))
+
+	errFailed := errors.New("failed")
+	sum := sumInt("sum")
+	err := d.AddEdges([][2]dag.Node[int]{
+		{sum, sourceInt("source")},
+		{sum, dag.NewNodeFunc("fail", "", func(_ context.Context, _ []int) (int, error) {
+			return 0, errFailed
+		})},
+	})
+	if err != nil {
+		t.Fatalf("failed to add edge: %v", err)
+	}
+
+	var runErr error
+	for _, err := range d.RunRootNodes(t.Context()) {
+		runErr = cmp.Or(runErr, err)
+	}
+	if !errors.Is(runErr, errFailed) {
+		t.Fatalf("want error %v, got %v", errFailed, runErr)
+	}
+
+	if want := []string{"source", "fail"}; !slices.Equal(started, want) {
+		t.Fatalf("started: want %v, got %v", want, started)
+	}
+	if want := []string{"source"}; !slices.Equal(finished, want) {
+		t.Fatalf("finished: want %v, got %v", want, finished)
+	}
+	if want := []string{"fail"}; !slices.Equal(failed, want) {
+		t.Fatalf("failed: want %v, got %v", want, failed)
+	}
+}
+
+type skipNode struct {
+	*dag.NodeFunc[int]
+}
+
+func (s *skipNode) Skip() bool {
+	return true
+}
+
+func TestDag_Plan(t *testing.T) {
+	d := dag.New[int]()
+
+	sum := sumInt("sum")
+	err := d.AddEdges([][2]dag.Node[int]{
+		{sum, sourceInt("source1")},
+		{sum, &skipNode{sourceInt("source2")}},
+	})
+	if err != nil {
+		t.Fatalf("failed to add edge: %v", err)
+	}
+
+	steps, err := d.Plan()
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	want := []dag.Step{
+		{Name: "source1", Hash: "source1"},
+		{Name: "source2", Hash: "source2", Skipped: true},
+		{Name: "sum", Hash: "sum"},
+	}
+	if !slices.Equal(steps, want) {
+		t.Fatalf("Plan() = %+v, want %+v", steps, want)
+	}
+
+	for _, err := range d.RunRootNodes(t.Context()) {
+		if err != nil {
+			t.Fatalf("failed to run root nodes: %v", err)
+		}
+	}
+	steps, err = d.Plan(sum)
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	for _, s := range steps {
+		if !s.Skipped {
+			t.Fatalf("executed node not skipped: %+v", s)
+		}
+	}
+}
+
+func TestDag_WithProgressSkipped(t *testing.T) {
+	skipped := make(map[string]bool)
+	d := dag.New[int](dag.WithProgress(func(e dag.Event) {
+		if e.Finished {
+			skipped[e.Name] = e.Skipped
+		}
+	}))
+
+	sum := sumInt("sum")
+	err := d.AddEdges([][2]dag.Node[int]{
+		{sum, sourceInt("source1")},
+		{sum, &skipNode{sourceInt("source2")}},
+	})
+	if err != nil {
+		t.Fatalf("failed to add edge: %v", err)
+	}
+
+	for _, err := range d.RunRootNodes(t.Context()) {
+		if err != nil {
+			t.Fatalf("failed to run root nodes: %v", err)
+		}
+	}
+	want := map[string]bool{"sum": false, "source1": false, "source2": true}
+	if !maps.Equal(skipped, want) {
+		t.Fatalf("skipped: want %v, got %v", want, skipped)
+	}
+}
+
+var errPermanent = errors.New("permanent")
+
+type flakyNode struct {
+	id       string
+	failures int
+	err      error
+	attempts atomic.Int32
+	retry    *dag.Retry
+}
+
+func (f *flakyNode) Run(_ context.Context, _ []int) (int, error) {
+	if int(f.attempts.Add(1)) <= f.failures {
+		return 0, f.err
+	}
+	return 1, nil
+}
+
+func (f *flakyNode) Name() string {
+	return f.id
+}
+
+func (f *flakyNode) Hash() string {
+	return f.id
+}
+
+type retrierNode struct {
+	*flakyNode
+}
+
+func (r retrierNode) Retry() dag.Retry {
+	return *r.retry
+}
+
+func TestDag_WithRetry(t *testing.T) {
+	isTransient := func(err error) bool {
+		return !errors.Is(err, errPermanent)
+	}
+	tests := []struct {
+		name         string
+		opts         []dag.Option
+		flaky        *flakyNode
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{
+			name:         "no retry",
+			flaky:        &flakyNode{id: "flaky", failures: 1, err: errors.New("transient")},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retried until success",
+			opts:         []dag.Option{dag.WithRetry(dag.Retry{Count: 3, Backoff: time.Millisecond})},
+			flaky:        &flakyNode{id: "flaky", failures: 2, err: errors.New("transient")},
+			wantAttempts: 3,
+		},
+		{
+			name:         "retries exhausted",
+			opts:         []dag.Option{dag.WithRetry(dag.Retry{Count: 1})},
+			flaky:        &flakyNode{id: "flaky", failures: 5, err: errors.New("transient")},
+			wantErr:      true,
+			wantAttempts: 2,
+		},
+		{
+			name:         "error not retryable",
+			opts:         []dag.Option{dag.WithRetry(dag.Retry{Count: 3, Retryable: isTransient})},
+			flaky:        &flakyNode{id: "flaky", failures: 1, err: errPermanent},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "node retry overrides default",
+			opts:         []dag.Option{dag.WithRetry(dag.Retry{Count: 3})},
+			flaky:        &flakyNode{id: "flaky", failures: 1, err: errors.New("transient"), retry: &dag.Retry{}},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dag.New[int](tt.opts...)
+			var n dag.Node[int] = tt.flaky
+			if tt.flaky.retry != nil {
+				n = retrierNode{tt.flaky}
+			}
+			err := d.AddChain(sumInt("sum"), n)
+			if err != nil {
+				t.Fatalf("failed to add chain: %v", err)
+			}
+			var runErr error
+			for _, err := range d.RunRootNodes(t.Context()) {
+				runErr = err
+			}
+			if (runErr != nil) != tt.wantErr {
+				t.Fatalf("RunRootNodes() error = %v, wantErr %v", runErr, tt.wantErr)
+			}
+			if got := tt.flaky.attempts.Load(); got != tt.wantAttempts {
+				t.Fatalf("attempts: want %d, got %d", tt.wantAttempts, got)
+			}
+		})
+	}
+}
+
+type blockingNode struct {
+	id      string
+	timeout time.Duration
+}
+
+func (b *blockingNode) Run(ctx context.Context, _ []int) (int, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func (b *blockingNode) Name() string {
+	return b.id
+}
+
+func (b *blockingNode) Hash() string {
+	return b.id
+}
+
+func (b *blockingNode) Timeout() time.Duration {
+	return b.timeout
+}
+
+func TestDag_WithTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []dag.Option
+		node *blockingNode
+	}{
+		{
+			name: "default timeout",
+			opts: []dag.Option{dag.WithTimeout(10 * time.Millisecond)},
+			node: &blockingNode{id: "blocking"},
+		},
+		{
+			name: "node timeout",
+			node: &blockingNode{id: "blocking", timeout: 10 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dag.New[int](tt.opts...)
+			err := d.AddChain(sumInt("sum"), tt.node)
+			if err != nil {
+				t.Fatalf("failed to add chain: %v", err)
+			}
+			var runErr error
+			for _, err := range d.RunRootNodes(t.Context()) {
+				runErr = err
+			}
+			if !errors.Is(runErr, context.DeadlineExceeded) {
+				t.Fatalf("RunRootNodes() error = %v, want %v", runErr, context.DeadlineExceeded)
+			}
+			if !strings.Contains(runErr.Error(), "blocking: timed out after 10ms") {
+				t.Fatalf("RunRootNodes() error = %v, want node name and timeout", runErr)
+			}
+		})
+	}
+}
+
+func TestDag_Topo(t *testing.T) {
+	d := dag.New[int]()
+
+	sum1 := sumInt("sum1")
+	sum2 := sumInt("sum2")
+	shared := sourceInt("shared")
+	err := d.AddEdges([][2]dag.Node[int]{
+		{sum1, sourceInt("source1")},
+		{sum1, shared},
+		{sum2, shared},
+		{sum2, sourceInt("source2")},
+	})
+	if err != nil {
+		t.Fatalf("failed to add edge: %v", err)
+	}
+
+	var got []string
+	for n := range d.Topo() {
+		got = append(got, n.Name())
+	}
+	want := []string{"source1", "shared", "sum1", "source2", "sum2"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Topo() = %v, want %v", got, want)
+	}
+}
+
+func TestDag_AddNode(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(d *dag.Dag[int]) iter.Seq2[int, error]
+		isolate bool
+		wantErr bool
+	}{
+		{
+			name: "orphaned node",
+			run: func(d *dag.Dag[int]) iter.Seq2[int, error] {
+				return d.RunRootNodes(t.Context())
+			},
+			wantErr: true,
+		},
+		{
+			name: "isolated node",
+			run: func(d *dag.Dag[int]) iter.Seq2[int, error] {
+				return d.RunRootNodes(t.Context())
+			},
+			isolate: true,
+		},
+		{
+			name: "run all",
+			run: func(d *dag.Dag[int]) iter.Seq2[int, error] {
+				return d.RunAll(t.Context())
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dag.New[int]()
+			err := d.AddChain(sumInt("sum"), sourceInt("source"))
+			if err != nil {
+				t.Fatalf("failed to add chain: %v", err)
+			}
+			independent := sourceInt("independent")
+			if tt.isolate {
+				d.AddNode(independent)
+			} else {
+				err = d.AddChain(independent)
+				if err != nil {
+					t.Fatalf("failed to add chain: %v", err)
+				}
+			}
+
+			var results []int
+			var runErr error
+			for result, err := range tt.run(d) {
+				results = append(results, result)
+				runErr = err
+			}
+			if (runErr != nil) != tt.wantErr {
+				t.Fatalf("run error = %v, wantErr %v", runErr, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(results, []int{1, 1}) {
+				t.Fatalf("results: want [1 1], got %v", results)
+			}
+		})
+	}
+}
+
+type conflictNode struct {
+	*dag.NodeFunc[int]
+}
+
+func (c *conflictNode) Name() string {
+	return "conflict"
+}
+
+func TestDag_Validate(t *testing.T) {
+	d := dag.New[int]()
+	sum := sumInt("sum")
+	source := sourceInt("source")
+	err := d.AddChain(sum, source)
+	if err != nil {
+		t.Fatalf("failed to add chain: %v", err)
+	}
+	if err = d.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+
+	// The errors of adding are ignored to collect all problems.
+	_ = d.AddEdge(source, sum)
+	_ = d.AddEdge(sum, &conflictNode{sourceInt("source")})
+	_ = d.AddChain(sourceInt("orphan1"))
+	_ = d.AddChain(sourceInt("orphan2"))
+	d.AddNode(sourceInt("isolated"))
+
+	err = d.Validate()
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		t.Fatalf("Validate() = %v, want joined errors", err)
+	}
+	var got []string
+	for _, e := range joined.Unwrap() {
+		got = append(got, e.Error())
+	}
+	want := []string{
+		"cyclic dependency: source to sum",
+		"hash conflict: source of conflict already used by source",
+		"node orphan1 is orphaned",
+		"node orphan2 is orphaned",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Validate() = %q, want %q", got, want)
+	}
+}
+
+func TestDag_WithLevels(t *testing.T) {
+	d := dag.New[int](dag.WithLevels(), dag.WithMaxParallel(4))
+
+	var mu sync.Mutex
+	var order []string
+	level := make(map[string]int)
+	newNode := func(id string, l int) *orderNode {
+		level[id] = l
+		return &orderNode{id: id, mu: &mu, order: &order}
+	}
+	a2, a1, a0 := newNode("a2", 2), newNode("a1", 1), newNode("a0", 0)
+	b1, b0 := newNode("b1", 1), newNode("b0", 0)
+	// Without levels b1 would finish before a0.
+	a0.delay = 30 * time.Millisecond
+	err := d.AddChain(a2, a1, a0)
+	if err != nil {
+		t.Fatalf("failed to add chain: %v", err)
+	}
+	err = d.AddChain(b1, b0)
+	if err != nil {
+		t.Fatalf("failed to add chain: %v", err)
+	}
+
+	for _, err := range d.RunNodes(t.Context(), []dag.Node[int]{b1, a2}) {
+		if err != nil {
+			t.Fatalf("failed to run nodes: %v", err)
+		}
+	}
+
+	if len(order) != 5 {
+		t.Fatalf("order: want 5 nodes, got %v", order)
+	}
+	for i := 1; i < len(order); i++ {
+		if level[order[i]] < level[order[i-1]] {
+			t.Fatalf("order: lower level after higher level: %v", order)
+		}
+	}
+}
+
+func TestDag_WithContinueOnError(t *testing.T) {
+	d := dag.New[int](dag.WithContinueOnError())
+
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+	failA := &flakyNode{id: "failA", failures: 1, err: errA}
+	failB := &flakyNode{id: "failB", failures: 1, err: errB}
+	root1 := sumInt("root1")
+	root2 := sumInt("root2")
+	root3 := sumInt("root3")
+	err := d.AddEdges([][2]dag.Node[int]{
+		{root1, failA},
+		{root2, sourceInt("source")},
+		{root3, failA},
+		{root3, failB},
+	})
+	if err != nil {
+		t.Fatalf("failed to add edge: %v", err)
+	}
+
+	var results []int
+	var errs []error
+	for result, err := range d.RunNodes(t.Context(), []dag.Node[int]{root1, root2, root3}) {
+		results = append(results, result)
+		errs = append(errs, err)
+	}
+
+	if !slices.Equal(results, []int{0, 1, 0}) {
+		t.Fatalf("results: want [0 1 0], got %v", results)
+	}
+	if errs[0] != errA {
+		t.Fatalf("root1: want %v, got %v", errA, errs[0])
+	}
+	if errs[1] != nil {
+		t.Fatalf("root2: want nil, got %v", errs[1])
+	}
+	if !errors.Is(errs[2], errA) || !errors.Is(errs[2], errB) {
+		t.Fatalf("root3: want %v and %v, got %v", errA, errB, errs[2])
+	}
+}
+
+func TestDag_RunNodesUnordered(t *testing.T) {
+	d := dag.New[int](dag.WithMaxParallel(2))
+
+	release := make(chan struct{})
+	slow := dag.NewNodeFunc("slow", "", func(_ context.Context, _ []int) (int, error) {
+		<-release
+		return 1, nil
+	})
+	fast := dag.NewNodeFunc("fast", "", func(_ context.Context, _ []int) (int, error) {
+		return 2, nil
+	})
+	unknown := sourceInt("unknown")
+	d.AddNode(slow)
+	d.AddNode(fast)
+
+	var indexes []int
+	for c := range d.RunNodesUnordered(t.Context(), []dag.Node[int]{slow, unknown, fast}) {
+		if c.Err != nil {
+			t.Fatalf("failed to run nodes: %v", c.Err)
+		}
+		if want := []int{1, 0, 2}[c.Index]; c.Result != want {
+			t.Fatalf("result of %d: want %d, got %d", c.Index, want, c.Result)
+		}
+		indexes = append(indexes, c.Index)
+		// The slow node only completes after the fast node was yielded.
+		if c.Index == 2 {
+			close(release)
+		}
+	}
+	if want := []int{2, 0}; !slices.Equal(indexes, want) {
+		t.Fatalf("indexes: want %v, got %v", want, indexes)
+	}
+}
@@ -58,3 +58,44 @@ func Example_graphviz() {
 	//     "0" -> "2";
 	// }
 }
+
+func Example_mermaid() {
+	d := dag.New[string]()
+	source1 := &myNode{value: "source1"}
+	source2 := &myNode{value: "source2"}
+	concat := &myNode{value: "concat"}
+
+	chains := [][]dag.Node[string]{
+		{concat, source1},
+		{concat, source2},
+	}
+	for _, chain := range chains {
+		_ = d.AddChain(chain...)
+	}
+
+	fmt.Println(d.Mermaid())
+	// Output:
+	// flowchart TD
+	//     0["name: concat<br>id: 0"]
+	//     1["name: source1<br>id: 1"]
+	//     2["name: source2<br>id: 2"]
+	//     0 --> 1
+	//     0 --> 2
+}
+
+func ExampleNewNodeFunc() {
+	d := dag.New[string]()
+	hello := dag.NewNodeFunc("hello", "", func(_ context.Context, _ []string) (string, error) {
+		return "hello", nil
+	})
+	upper := dag.NewNodeFunc("upper", "", func(_ context.Context, values []string) (string, error) {
+		return strings.ToUpper(values[0]), nil
+	})
+	_ = d.AddChain(upper, hello)
+
+	for res := range d.RunRootNodes(context.Background()) {
+		fmt.Println(res)
+	}
+	// Output:
+	// HELLO
+}
@@ -0,0 +1,359 @@
+package dag
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"sync"
+)
+
+type job[T comparable] struct {
+	n      *node[T]
+	values []T
+}
+
+type completion[T comparable] struct {
+	n      *node[T]
+	result T
+	err    error
+}
+
+// runNodes returns an iterator that iterates results as soon as
+// contiguous parts from the start are complete.
+func runNodes[T comparable](ctx context.Context, nodes []*node[T], workers int, levels bool, continueOnError bool) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		execute(ctx, nodes, workers, levels, continueOnError, true, func(_ int, result T, err error) bool {
+			return yield(result, err)
+		})
+	}
+}
+
+// runNodesUnordered returns an iterator that iterates results as soon as a node is complete.
+func runNodesUnordered[T comparable](ctx context.Context, nodes []*node[T], workers int, levels bool, continueOnError bool) iter.Seq[Completion[T]] {
+	return func(yield func(Completion[T]) bool) {
+		execute(ctx, nodes, workers, levels, continueOnError, false, func(idx int, result T, err error) bool {
+			return yield(Completion[T]{Index: idx, Result: result, Err: err})
+		})
+	}
+}
+
+// execute runs the nodes and passes the result of every node with its index to yield
+// until yield returns false. If ordered is true, results are passed as soon as
+// contiguous parts from the start are complete, otherwise as soon as a node is complete.
+// The nodes run on a pool of workers. A node is passed to a worker as soon as
+// all its children are complete, nodes with a lower priority value first.
+// The first error cancels the running nodes and no further nodes are started
+// unless continueOnError is true. Then only the nodes depending on the failed node fail.
+// If levels is true, a node is only started if no node of a lower level is pending.
+func execute[T comparable](
+	ctx context.Context,
+	nodes []*node[T],
+	workers int,
+	levels bool,
+	continueOnError bool,
+	ordered bool,
+	yield func(idx int, result T, err error) bool,
+) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	jobs := make(chan job[T])
+	// Every running node can complete without blocking if the iteration stops early.
+	completions := make(chan completion[T], workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for j := range jobs {
+				result, err := j.n.execute(ctx, j.values)
+				completions <- completion[T]{n: j.n, result: result, err: err}
+			}
+		})
+	}
+	defer func() {
+		cancel()
+		close(jobs)
+		wg.Wait()
+	}()
+
+	e := newExecution(nodes, levels)
+	running := 0
+	// next is the first node whose result was not yielded.
+	next := 0
+	yielded := make([]bool, len(nodes))
+	var firstErr error
+	ctxDone := ctx.Done()
+	for {
+		for i := next; i < len(nodes); i++ {
+			if yielded[i] {
+				continue
+			}
+			if !e.finished(nodes[i]) {
+				if ordered {
+					break
+				}
+				continue
+			}
+			id := nodes[i].id
+			if !yield(i, e.results[id], e.err(id)) {
+				return
+			}
+			yielded[i] = true
+		}
+		for next < len(nodes) && yielded[next] {
+			next++
+		}
+		if next == len(nodes) {
+			return
+		}
+		if firstErr != nil && running == 0 {
+			var zeroVal T
+			yield(next, zeroVal, firstErr)
+			return
+		}
+
+		// A nil channel disables the case if there is nothing to start.
+		var jobsCh chan job[T]
+		var j job[T]
+		if firstErr == nil && e.startable() {
+			jobsCh = jobs
+			n := e.ready.nodes[0].n
+			j = job[T]{n: n, values: e.values(n)}
+		}
+		select {
+		case jobsCh <- j:
+			heap.Pop(&e.ready)
+			running++
+		case c := <-completions:
+			running--
+			if c.err != nil && continueOnError {
+				e.fail(c.n.id, c.n.id, c.err)
+				continue
+			}
+			if c.err != nil {
+				if firstErr == nil {
+					firstErr = c.err
+					cancel()
+				}
+				continue
+			}
+			e.complete(c.n, c.result)
+		case <-ctxDone:
+			ctxDone = nil
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+		}
+	}
+}
+
+// execution tracks the nodes reachable from the started nodes.
+type execution[T comparable] struct {
+	results map[int]T
+	done    map[int]bool
+	// pending counts the children of a node which are not done.
+	pending map[int]int
+	parents map[int][]*node[T]
+	ready   readyQueue[T]
+
+	// levels is set for WithLevels.
+	levels bool
+	// level is the level of a node. Executed nodes have no level.
+	level map[int]int
+	// remaining counts the nodes per level which are neither done nor failed.
+	remaining []int
+
+	// failedBy holds the failed nodes a node depends on, including itself.
+	failedBy map[int][]int
+	errs     map[int]error
+	// unsettled counts the children of a node which are not settled. A node is settled
+	// if it is done or if it failed and all its children are settled.
+	unsettled map[int]int
+	settled   map[int]bool
+}
+
+func newExecution[T comparable](nodes []*node[T], levels bool) *execution[T] {
+	e := &execution[T]{
+		results: make(map[int]T),
+		done:    make(map[int]bool),
+		pending: make(map[int]int),
+		parents: make(map[int][]*node[T]),
+		ready:   readyQueue[T]{byLevel: levels},
+		levels:  levels,
+		level:   make(map[int]int),
+
+		failedBy:  make(map[int][]int),
+		errs:      make(map[int]error),
+		unsettled: make(map[int]int),
+		settled:   make(map[int]bool),
+	}
+	visited := make(map[int]bool)
+	var visit func(n *node[T])
+	visit = func(n *node[T]) {
+		if visited[n.id] {
+			return
+		}
+		visited[n.id] = true
+		if result, ok := n.executed(); ok {
+			e.results[n.id] = result
+			e.done[n.id] = true
+			return
+		}
+		level := 0
+		for _, c := range n.children {
+			visit(c)
+			e.parents[c.id] = append(e.parents[c.id], n)
+			if !e.done[c.id] {
+				e.pending[n.id]++
+				e.unsettled[n.id]++
+				level = max(level, e.level[c.id]+1)
+			}
+		}
+		e.level[n.id] = level
+		for len(e.remaining) <= level {
+			e.remaining = append(e.remaining, 0)
+		}
+		e.remaining[level]++
+		if e.pending[n.id] == 0 {
+			heap.Push(&e.ready, readyNode[T]{n: n, level: level})
+		}
+	}
+	for _, n := range nodes {
+		visit(n)
+	}
+	return e
+}
+
+// startable reports whether the first ready node can be started.
+func (e *execution[T]) startable() bool {
+	if e.ready.Len() == 0 {
+		return false
+	}
+	if !e.levels {
+		return true
+	}
+	for level := range e.ready.nodes[0].level {
+		if e.remaining[level] > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// finished reports whether n is done or failed without running children.
+func (e *execution[T]) finished(n *node[T]) bool {
+	return e.done[n.id] || e.settled[n.id]
+}
+
+// err returns the errors of the failed nodes the node depends on.
+func (e *execution[T]) err(id int) error {
+	failedBy := e.failedBy[id]
+	if len(failedBy) == 1 {
+		return e.errs[failedBy[0]]
+	}
+	errs := make([]error, len(failedBy))
+	for i, f := range failedBy {
+		errs[i] = e.errs[f]
+	}
+	return errors.Join(errs...)
+}
+
+// fail records that the node with id cannot run because the node failed with err.
+// The parents fail as well.
+func (e *execution[T]) fail(id int, failed int, err error) {
+	if slices.Contains(e.failedBy[id], failed) {
+		return
+	}
+	if len(e.failedBy[id]) == 0 {
+		e.remaining[e.level[id]]--
+	}
+	e.failedBy[id] = append(e.failedBy[id], failed)
+	e.errs[failed] = err
+	for _, p := range e.parents[id] {
+		e.fail(p.id, failed, err)
+	}
+	if id == failed {
+		e.settle(id)
+	}
+}
+
+// settle marks the node as settled and settles failed parents without unsettled children.
+func (e *execution[T]) settle(id int) {
+	e.settled[id] = true
+	for _, p := range e.parents[id] {
+		e.unsettled[p.id]--
+		if e.unsettled[p.id] == 0 && len(e.failedBy[p.id]) > 0 {
+			e.settle(p.id)
+		}
+	}
+}
+
+// complete records the result and queues the parents whose children are all done.
+func (e *execution[T]) complete(n *node[T], result T) {
+	e.results[n.id] = result
+	e.done[n.id] = true
+	e.remaining[e.level[n.id]]--
+	for _, p := range e.parents[n.id] {
+		e.pending[p.id]--
+		if e.pending[p.id] == 0 {
+			heap.Push(&e.ready, readyNode[T]{n: p, level: e.level[p.id]})
+		}
+	}
+	e.settle(n.id)
+}
+
+// values returns the results of the children of n in order.
+func (e *execution[T]) values(n *node[T]) []T {
+	if len(n.children) == 0 {
+		return nil
+	}
+	values := make([]T, len(n.children))
+	for i, c := range n.children {
+		values[i] = e.results[c.id]
+	}
+	return values
+}
+
+type readyNode[T comparable] struct {
+	n     *node[T]
+	level int
+}
+
+// readyQueue implements heap.Interface. Nodes with a lower priority value come first,
+// equal priorities in the order the nodes were added. If byLevel is true,
+// nodes of a lower level come before all others.
+type readyQueue[T comparable] struct {
+	nodes   []readyNode[T]
+	byLevel bool
+}
+
+func (q *readyQueue[T]) Len() int {
+	return len(q.nodes)
+}
+
+func (q *readyQueue[T]) Less(i, j int) bool {
+	a, b := q.nodes[i], q.nodes[j]
+	if q.byLevel && a.level != b.level {
+		return a.level < b.level
+	}
+	pa, pb := a.n.priority.Load(), b.n.priority.Load()
+	if pa != pb {
+		return pa < pb
+	}
+	return a.n.id < b.n.id
+}
+
+func (q *readyQueue[T]) Swap(i, j int) {
+	q.nodes[i], q.nodes[j] = q.nodes[j], q.nodes[i]
+}
+
+func (q *readyQueue[T]) Push(x any) {
+	q.nodes = append(q.nodes, x.(readyNode[T]))
+}
+
+func (q *readyQueue[T]) Pop() any {
+	n := len(q.nodes)
+	last := q.nodes[n-1]
+	q.nodes = q.nodes[:n-1]
+	return last
+}
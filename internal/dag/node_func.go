@@ -0,0 +1,34 @@
+package dag
+
+import "context"
+
+// NodeFunc adapts a function to a Node, so simple nodes need no own type.
+type NodeFunc[T comparable] struct {
+	name string
+	hash string
+	fn   func(ctx context.Context, values []T) (T, error)
+}
+
+// NewNodeFunc returns a node that runs fn. The name is used as hash if hash is empty.
+func NewNodeFunc[T comparable](name string, hash string, fn func(ctx context.Context, values []T) (T, error)) *NodeFunc[T] {
+	if hash == "" {
+		hash = name
+	}
+	return &NodeFunc[T]{
+		name: name,
+		hash: hash,
+		fn:   fn,
+	}
+}
+
+func (n *NodeFunc[T]) Hash() string {
+	return n.hash
+}
+
+func (n *NodeFunc[T]) Name() string {
+	return n.name
+}
+
+func (n *NodeFunc[T]) Run(ctx context.Context, values []T) (T, error) {
+	return n.fn(ctx, values)
+}
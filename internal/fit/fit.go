@@ -0,0 +1,201 @@
+// Package fit encodes workouts in the Flexible and Interoperable Data Transfer (FIT) format
+// of Garmin. Copied to the folder 'NewFiles' of a watch, the workout shows up in the workouts.
+package fit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// Intensity of a workout step.
+type Intensity uint8
+
+const (
+	Active Intensity = 0
+	Rest   Intensity = 1
+)
+
+// Step is a step of a workout.
+type Step struct {
+	Name string
+	// Duration of the step. A zero duration lasts until the lap button is pressed.
+	Duration  time.Duration
+	Intensity Intensity
+}
+
+// Workout is a named list of steps.
+type Workout struct {
+	Name  string
+	Steps []Step
+}
+
+const (
+	protocolVersion = 0x20
+	profileVersion  = 2132
+
+	// nameSize is the size of the string fields including the terminating zero byte.
+	nameSize = 32
+
+	definitionHeader = 0x40
+
+	localFileID      = 0
+	localWorkout     = 1
+	localWorkoutStep = 2
+
+	mesgFileID      = 0
+	mesgWorkout     = 26
+	mesgWorkoutStep = 27
+
+	baseEnum    = 0x00
+	baseString  = 0x07
+	baseUint16  = 0x84
+	baseUint32  = 0x86
+	baseUint32z = 0x8c
+
+	fileTypeWorkout         = 5
+	manufacturerDevelopment = 255
+	sportTraining           = 10
+	subSportCardioTraining  = 26
+	durationTypeTime        = 0
+	durationTypeOpen        = 5
+	targetTypeOpen          = 2
+	fieldMessageIndex       = 254
+)
+
+// fitEpoch is the start of the FIT timestamps.
+var fitEpoch = time.Date(1989, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+type field struct {
+	num      uint8
+	size     uint8
+	baseType uint8
+}
+
+var (
+	fileIDFields = []field{
+		{0, 1, baseEnum},    // type
+		{1, 2, baseUint16},  // manufacturer
+		{2, 2, baseUint16},  // product
+		{3, 4, baseUint32z}, // serial_number
+		{4, 4, baseUint32},  // time_created
+	}
+	workoutFields = []field{
+		{4, 1, baseEnum},          // sport
+		{11, 1, baseEnum},         // sub_sport
+		{6, 2, baseUint16},        // num_valid_steps
+		{8, nameSize, baseString}, // wkt_name
+	}
+	workoutStepFields = []field{
+		{fieldMessageIndex, 2, baseUint16}, // message_index
+		{0, nameSize, baseString},          // wkt_step_name
+		{1, 1, baseEnum},                   // duration_type
+		{2, 4, baseUint32},                 // duration_value in milliseconds
+		{3, 1, baseEnum},                   // target_type
+		{4, 4, baseUint32},                 // target_value
+		{7, 1, baseEnum},                   // intensity
+	}
+)
+
+// Encode writes the workout as FIT file created at the passed time.
+func Encode(w io.Writer, wkt Workout, created time.Time) error {
+	data := &bytes.Buffer{}
+
+	define(data, localFileID, mesgFileID, fileIDFields)
+	data.WriteByte(localFileID)
+	data.WriteByte(fileTypeWorkout)
+	writeUint16(data, manufacturerDevelopment)
+	writeUint16(data, 0)
+	writeUint32(data, 1)
+	writeUint32(data, uint32(max(0, created.Sub(fitEpoch)/time.Second)))
+
+	define(data, localWorkout, mesgWorkout, workoutFields)
+	data.WriteByte(localWorkout)
+	data.WriteByte(sportTraining)
+	data.WriteByte(subSportCardioTraining)
+	writeUint16(data, uint16(len(wkt.Steps)))
+	writeString(data, wkt.Name)
+
+	define(data, localWorkoutStep, mesgWorkoutStep, workoutStepFields)
+	for i, s := range wkt.Steps {
+		data.WriteByte(localWorkoutStep)
+		writeUint16(data, uint16(i))
+		writeString(data, s.Name)
+		if s.Duration > 0 {
+			data.WriteByte(durationTypeTime)
+			writeUint32(data, uint32(s.Duration.Milliseconds()))
+		} else {
+			data.WriteByte(durationTypeOpen)
+			writeUint32(data, 0)
+		}
+		data.WriteByte(targetTypeOpen)
+		writeUint32(data, 0)
+		data.WriteByte(byte(s.Intensity))
+	}
+
+	header := &bytes.Buffer{}
+	header.WriteByte(14)
+	header.WriteByte(protocolVersion)
+	writeUint16(header, profileVersion)
+	writeUint32(header, uint32(data.Len()))
+	header.WriteString(".FIT")
+	writeUint16(header, crc(0, header.Bytes()))
+
+	file := append(header.Bytes(), data.Bytes()...)
+	file = binary.LittleEndian.AppendUint16(file, crc(0, file))
+	_, err := w.Write(file)
+	return err
+}
+
+// define writes the definition message of the local message type.
+func define(b *bytes.Buffer, local uint8, global uint16, fields []field) {
+	b.WriteByte(definitionHeader | local)
+	// Reserved byte and little endian architecture.
+	b.WriteByte(0)
+	b.WriteByte(0)
+	writeUint16(b, global)
+	b.WriteByte(uint8(len(fields)))
+	for _, f := range fields {
+		b.WriteByte(f.num)
+		b.WriteByte(f.size)
+		b.WriteByte(f.baseType)
+	}
+}
+
+// writeString writes s zero-padded to nameSize. Longer strings are cut at a rune boundary.
+func writeString(b *bytes.Buffer, s string) {
+	for len(s) > nameSize-1 {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	b.WriteString(s)
+	b.Write(make([]byte, nameSize-len(s)))
+}
+
+func writeUint16(b *bytes.Buffer, v uint16) {
+	b.Write(binary.LittleEndian.AppendUint16(nil, v))
+}
+
+func writeUint32(b *bytes.Buffer, v uint32) {
+	b.Write(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+var crcTable = [16]uint16{
+	0x0000, 0xcc01, 0xd801, 0x1400, 0xf001, 0x3c00, 0x2800, 0xe401,
+	0xa001, 0x6c00, 0x7800, 0xb401, 0x5000, 0x9c01, 0x8801, 0x4400,
+}
+
+// crc returns the FIT checksum (CRC-16/ARC) of data.
+func crc(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		tmp := crcTable[crc&0xf]
+		crc = (crc >> 4) & 0x0fff
+		crc = crc ^ tmp ^ crcTable[b&0xf]
+		tmp = crcTable[crc&0xf]
+		crc = (crc >> 4) & 0x0fff
+		crc = crc ^ tmp ^ crcTable[(b>>4)&0xf]
+	}
+	return crc
+}
@@ -0,0 +1,54 @@
+package fit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrc(t *testing.T) {
+	got := crc(0, []byte("123456789"))
+	if got != 0xbb3d {
+		t.Fatalf("crc() = %#x, want %#x", got, 0xbb3d)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := Encode(buf, Workout{
+		Name: "Workout",
+		Steps: []Step{
+			{Name: "Get ready"},
+			{Name: "Pause", Duration: 10 * time.Second, Intensity: Rest},
+			{Name: strings.Repeat("ü", 20), Duration: 30 * time.Second, Intensity: Active},
+		},
+	}, fitEpoch.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	b := buf.Bytes()
+
+	if string(b[8:12]) != ".FIT" {
+		t.Fatalf("Encode() header = %q, want .FIT", b[8:12])
+	}
+	if crc(0, b[:14]) != 0 {
+		t.Fatal("Encode() header crc invalid")
+	}
+	if crc(0, b) != 0 {
+		t.Fatal("Encode() file crc invalid")
+	}
+	dataSize := binary.LittleEndian.Uint32(b[4:8])
+	if int(dataSize) != len(b)-14-2 {
+		t.Fatalf("Encode() data size = %d, want %d", dataSize, len(b)-14-2)
+	}
+	// A string of 31 bytes is cut to the last complete rune.
+	if !bytes.Contains(b, append([]byte(strings.Repeat("ü", 15)), 0)) {
+		t.Fatal("Encode() step name not cut at rune boundary")
+	}
+	// The last step lasts 30000 ms.
+	if !bytes.Contains(b, binary.LittleEndian.AppendUint32([]byte{durationTypeTime}, 30000)) {
+		t.Fatal("Encode() step duration not found")
+	}
+}
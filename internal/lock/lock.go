@@ -0,0 +1,140 @@
+// Package lock prevents concurrent runs on the same directories with lock files.
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLocked is wrapped by the error of Acquire if another process holds a lock.
+var ErrLocked = errors.New("locked by another w2a run")
+
+// File is the name of the lock file in a locked directory.
+// The leading '.' excludes it from the output files.
+const File = ".w2a.lock"
+
+const (
+	// refreshInterval is the interval the modification time of the lock files is updated.
+	refreshInterval = 10 * time.Second
+	// staleAfter is the age of a lock file whose process is assumed to be killed.
+	staleAfter = 6 * refreshInterval
+)
+
+// Lock holds the lock files of directories until it is released.
+type Lock struct {
+	paths []string
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// Acquire creates the lock files in dirs. The dirs are created if they do not exist.
+// If a dir is locked by another process, the acquired lock files are released
+// and the error wraps ErrLocked. Lock files not refreshed for a minute are
+// left by killed processes and are taken over.
+func Acquire(dirs ...string) (*Lock, error) {
+	l := &Lock{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	for _, dir := range dirs {
+		path, err := filepath.Abs(filepath.Join(dir, File))
+		if err != nil {
+			return nil, errors.Join(err, l.remove())
+		}
+		if slices.Contains(l.paths, path) {
+			continue
+		}
+		err = create(path)
+		if err != nil {
+			return nil, errors.Join(err, l.remove())
+		}
+		l.paths = append(l.paths, path)
+	}
+	go l.refresh()
+	return l, nil
+}
+
+// create creates the lock file at path or takes over a stale lock file.
+func create(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		info, statErr := os.Stat(path)
+		if statErr != nil || time.Since(info.ModTime()) < staleAfter {
+			return fmt.Errorf("%w: %s%s", ErrLocked, path, holder(path))
+		}
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%w: %s%s", ErrLocked, path, holder(path))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(f, os.Getpid())
+	return errors.Join(err, f.Close())
+}
+
+// holder returns the process id in the lock file at path for error messages.
+func holder(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(" (pid %d)", pid)
+}
+
+// refresh updates the modification time of the lock files until the lock is released,
+// so they are not taken over as stale.
+func (l *Lock) refresh() {
+	defer close(l.done)
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			for _, path := range l.paths {
+				_ = os.Chtimes(path, now, now)
+			}
+		}
+	}
+}
+
+// Release removes the lock files. Lock files removed with their directory are ignored.
+func (l *Lock) Release() error {
+	close(l.stop)
+	<-l.done
+	return l.remove()
+}
+
+func (l *Lock) remove() error {
+	var errs []error
+	for _, path := range l.paths {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,79 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	tempDir := t.TempDir()
+	outputDir := filepath.Join(t.TempDir(), "output")
+
+	l, err := Acquire(tempDir, outputDir, tempDir)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	for _, dir := range []string{tempDir, outputDir} {
+		if _, err = os.Stat(filepath.Join(dir, File)); err != nil {
+			t.Fatalf("lock file missing: %v", err)
+		}
+	}
+
+	// A second run fails and keeps the lock files of the first run.
+	other := t.TempDir()
+	_, err = Acquire(other, outputDir)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("Acquire() error = %v, want %v", err, ErrLocked)
+	}
+	if _, err = os.Stat(filepath.Join(other, File)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("lock file of failed run not removed: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(outputDir, File)); err != nil {
+		t.Fatalf("lock file of first run removed: %v", err)
+	}
+
+	err = l.Release()
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	l, err = Acquire(other, outputDir)
+	if err != nil {
+		t.Fatalf("Acquire() after Release() error = %v", err)
+	}
+	err = l.Release()
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+}
+
+func TestAcquire_Stale(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, File)
+	err := os.WriteFile(path, []byte("1\n"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write lock file: %v", err)
+	}
+
+	_, err = Acquire(dir)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("Acquire() error = %v, want %v", err, ErrLocked)
+	}
+
+	// The lock file of a killed process is not refreshed.
+	old := time.Now().Add(-2 * staleAfter)
+	err = os.Chtimes(path, old, old)
+	if err != nil {
+		t.Fatalf("failed to change mtime: %v", err)
+	}
+	l, err := Acquire(dir)
+	if err != nil {
+		t.Fatalf("Acquire() of stale lock error = %v", err)
+	}
+	err = l.Release()
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	barLen = 30
+	// clearLine moves the cursor to the start of the line and erases the line.
+	clearLine = "\r\033[K"
+)
+
+// ProgressBar renders a progress bar on the last line of a terminal.
+// Lines written to it are printed above the progress bar.
+type ProgressBar struct {
+	writer io.Writer
+	width  int
+
+	lock sync.Mutex
+	// bar is the rendered progress bar. It is empty if nothing was rendered yet.
+	bar string
+	// pending holds written bytes until the line is complete.
+	pending []byte
+}
+
+// NewProgressBar returns a progress bar which is cut to width characters.
+func NewProgressBar(writer io.Writer, width int) *ProgressBar {
+	return &ProgressBar{writer: writer, width: width}
+}
+
+// Update renders the progress bar with the current operation.
+func (p *ProgressBar) Update(completed int, total int, current string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	done := barLen
+	if total > 0 {
+		done = min(barLen, barLen*completed/total)
+	}
+	bar := fmt.Sprintf("[%s%s] %d/%d %s",
+		strings.Repeat("#", done), strings.Repeat(" ", barLen-done), completed, total, current)
+	if runes := []rune(bar); len(runes) > p.width {
+		bar = string(runes[:p.width])
+	}
+	p.bar = bar
+	_, _ = fmt.Fprint(p.writer, clearLine+p.bar)
+}
+
+// Write prints complete lines above the progress bar.
+func (p *ProgressBar) Write(b []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.pending = append(p.pending, b...)
+	idx := bytes.LastIndexByte(p.pending, '\n')
+	if idx < 0 {
+		return len(b), nil
+	}
+	lines := p.pending[:idx+1]
+	_, err := fmt.Fprint(p.writer, clearLine+string(lines)+p.bar)
+	p.pending = p.pending[idx+1:]
+	return len(b), err
+}
+
+// Done ends the line of the progress bar.
+func (p *ProgressBar) Done() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.bar != "" {
+		_, _ = fmt.Fprintln(p.writer)
+	}
+}
@@ -1,10 +1,13 @@
 package m3u
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"math"
+	"net/url"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"golang.org/x/text/unicode/norm"
@@ -15,13 +18,26 @@ type item struct {
 	dur         time.Duration
 }
 
+// PathStyle defines how the file paths are written into the playlist.
+type PathStyle int
+
+const (
+	// PathURI writes percent-escaped absolute paths with the file:// scheme.
+	PathURI PathStyle = iota
+	// PathAbsolute writes plain absolute paths.
+	PathAbsolute
+	// PathRelative writes the file names. The files must be next to the playlist.
+	PathRelative
+)
+
 type Playlist struct {
 	w     io.Writer
+	style PathStyle
 	items []item
 }
 
-func NewPlaylist(w io.Writer) *Playlist {
-	return &Playlist{w: w}
+func NewPlaylist(w io.Writer, style PathStyle) *Playlist {
+	return &Playlist{w: w, style: style}
 }
 
 func (p *Playlist) Add(absFilePath string, dur time.Duration) {
@@ -39,7 +55,7 @@ func (p *Playlist) Write() error {
 		if err != nil {
 			return err
 		}
-		_, err = io.WriteString(p.w, fmt.Sprintf("file://%s\n", escape(it.absFilePath)))
+		_, err = io.WriteString(p.w, p.entry(it.absFilePath)+"\n")
 		if err != nil {
 			return err
 		}
@@ -47,6 +63,39 @@ func (p *Playlist) Write() error {
 	return nil
 }
 
+func (p *Playlist) entry(absFilePath string) string {
+	switch p.style {
+	case PathAbsolute:
+		return absFilePath
+	case PathRelative:
+		return filepath.Base(absFilePath)
+	default:
+		return FileURI(absFilePath)
+	}
+}
+
+// FileURI returns the file URI of the absolute path as written by PathURI.
+func FileURI(absFilePath string) string {
+	return fileURI(absFilePath, filepath.Separator)
+}
+
+// fileURI returns the file URI of the absolute path with the separator of the OS.
+// Windows paths like C:\dir\file.mp3 become file:///C:/dir/file.mp3 and
+// UNC paths like \\server\share\file.mp3 become file://server/share/file.mp3.
+func fileURI(absFilePath string, separator rune) string {
+	path := absFilePath
+	if separator == '\\' {
+		path = strings.ReplaceAll(path, `\`, "/")
+	}
+	if strings.HasPrefix(path, "//") {
+		return "file:" + escape(path)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return "file://" + escape(path)
+}
+
 func escape(input string) string {
 	s := norm.NFD.String(input)
 	var escaped string
@@ -59,3 +108,77 @@ func escape(input string) string {
 	}
 	return escaped
 }
+
+// Read returns the file paths of a playlist written by Write.
+// Relative paths are resolved from dir, the directory of the playlist.
+func Read(r io.Reader, dir string) ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		path, err := entryPath(line, filepath.Separator)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		paths = append(paths, path)
+	}
+	return paths, scanner.Err()
+}
+
+// entryPath returns the file path of a playlist entry in any PathStyle
+// with the separator of the OS. It reverses fileURI.
+func entryPath(line string, separator rune) (string, error) {
+	if !strings.HasPrefix(line, "file:") {
+		return line, nil
+	}
+	uriPath := strings.TrimPrefix(line, "file:")
+	if strings.HasPrefix(uriPath, "///") {
+		// No host: the path starts after the third slash.
+		uriPath = strings.TrimPrefix(uriPath, "//")
+	}
+	path, err := url.PathUnescape(uriPath)
+	if err != nil {
+		return "", err
+	}
+	path = norm.NFC.String(path)
+	if separator == '\\' {
+		if isDrivePath(path) {
+			path = strings.TrimPrefix(path, "/")
+		}
+		path = strings.ReplaceAll(path, "/", `\`)
+	}
+	return path, nil
+}
+
+// isDrivePath reports whether the path of a file URI starts with a drive letter, e.g. /C:/dir.
+func isDrivePath(path string) bool {
+	return len(path) >= 3 && path[0] == '/' && path[2] == ':' &&
+		('a' <= path[1] && path[1] <= 'z' || 'A' <= path[1] && path[1] <= 'Z')
+}
+
+// Relative copies a playlist written by Write and replaces the file paths
+// with the file names, so the playlist works next to the files anywhere.
+func Relative(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			path, err := entryPath(line, filepath.Separator)
+			if err != nil {
+				return err
+			}
+			line = filepath.Base(path)
+		}
+		_, err := io.WriteString(w, line+"\n")
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
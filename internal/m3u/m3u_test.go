@@ -2,6 +2,8 @@ package m3u
 
 import (
 	"bytes"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 )
@@ -9,11 +11,13 @@ import (
 func TestPlaylist_Write(t *testing.T) {
 	tests := []struct {
 		name  string
+		style PathStyle
 		items []item
 		want  string
 	}{
 		{
 			"one item",
+			PathURI,
 			[]item{
 				{absFilePath: "/test/test1.mp3", dur: time.Second * 10},
 			},
@@ -24,6 +28,7 @@ file:///test/test1.mp3
 		},
 		{
 			"escape non ASCII characters",
+			PathURI,
 			[]item{
 				{absFilePath: "/über/test/testütestätestötest.mp3", dur: time.Second * 10},
 			},
@@ -34,6 +39,7 @@ file:///u%CC%88ber/test/testu%CC%88testa%CC%88testo%CC%88test.mp3
 		},
 		{
 			"multiple items",
+			PathURI,
 			[]item{
 				{absFilePath: "/test/test1.mp3", dur: time.Second * 10},
 				{absFilePath: "/test/test2.mp3", dur: time.Second * 8},
@@ -46,10 +52,33 @@ file:///test/test1.mp3
 file:///test/test2.mp3
 #EXTINF:123,test3.mp3
 file:///test/test3.mp3
+`,
+		},
+		{
+			"plain absolute paths",
+			PathAbsolute,
+			[]item{
+				{absFilePath: "/über/test/test1.mp3", dur: time.Second * 10},
+			},
+			`#EXTM3U
+#EXTINF:10,test1.mp3
+/über/test/test1.mp3
+`,
+		},
+		{
+			"relative paths",
+			PathRelative,
+			[]item{
+				{absFilePath: "/über/test/test1.mp3", dur: time.Second * 10},
+			},
+			`#EXTM3U
+#EXTINF:10,test1.mp3
+test1.mp3
 `,
 		},
 		{
 			"rount time down",
+			PathURI,
 			[]item{
 				{absFilePath: "/test/test1.mp3", dur: time.Millisecond * 9999},
 			},
@@ -62,7 +91,7 @@ file:///test/test1.mp3
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			buffer := &bytes.Buffer{}
-			p := NewPlaylist(buffer)
+			p := NewPlaylist(buffer, tt.style)
 			for _, it := range tt.items {
 				p.Add(it.absFilePath, it.dur)
 			}
@@ -76,3 +105,97 @@ file:///test/test1.mp3
 		})
 	}
 }
+
+func TestRead(t *testing.T) {
+	// The root is / or the volume of the working dir on Windows, e.g. C:\.
+	root, err := filepath.Abs(string(filepath.Separator))
+	if err != nil {
+		t.Fatalf("failed to get root: %v", err)
+	}
+	abs := func(elem ...string) string {
+		return filepath.Join(append([]string{root}, elem...)...)
+	}
+	tests := []struct {
+		name  string
+		style PathStyle
+		want  []string
+	}{
+		{"uri", PathURI, []string{abs("test", "test1.mp3"), abs("über", "test", "testü%test.mp3")}},
+		{"absolute", PathAbsolute, []string{abs("test", "test1.mp3"), abs("über", "test", "testü%test.mp3")}},
+		{"relative", PathRelative, []string{abs("dir", "test1.mp3"), abs("dir", "testü%test.mp3")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			p := NewPlaylist(buf, tt.style)
+			p.Add(abs("test", "test1.mp3"), time.Second*10)
+			p.Add(abs("über", "test", "testü%test.mp3"), time.Second*8)
+			err := p.Write()
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			got, err := Read(buf, abs("dir"))
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Read() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelative(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := NewPlaylist(buf, PathURI)
+	p.Add("/test/test1.mp3", time.Second*10)
+	p.Add("/über/test/testütestätestötest.mp3", time.Second*8)
+	err := p.Write()
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got := &bytes.Buffer{}
+	err = Relative(buf, got)
+	if err != nil {
+		t.Fatalf("Relative() error = %v", err)
+	}
+	want := `#EXTM3U
+#EXTINF:10,test1.mp3
+test1.mp3
+#EXTINF:8,testütestätestötest.mp3
+testütestätestötest.mp3
+`
+	if got.String() != want {
+		t.Fatalf("Relative() = %v, want %v", got.String(), want)
+	}
+}
+
+func TestFileURI(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		separator rune
+		want      string
+	}{
+		{"unix", "/über/test1.mp3", '/', "file:///u%CC%88ber/test1.mp3"},
+		{"windows drive", `C:\Users\über\test1.mp3`, '\\', "file:///C:/Users/u%CC%88ber/test1.mp3"},
+		{"windows unc", `\\server\share\test1.mp3`, '\\', "file://server/share/test1.mp3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fileURI(tt.path, tt.separator)
+			if got != tt.want {
+				t.Fatalf("fileURI() = %v, want %v", got, tt.want)
+			}
+			path, err := entryPath(got, tt.separator)
+			if err != nil {
+				t.Fatalf("entryPath() error = %v", err)
+			}
+			if path != tt.path {
+				t.Fatalf("entryPath() = %v, want %v", path, tt.path)
+			}
+		})
+	}
+}
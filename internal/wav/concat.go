@@ -0,0 +1,134 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrFormatMismatch is returned if wav files differ in sample rate or channels.
+var ErrFormatMismatch = errors.New("wav files differ in sample rate or channels")
+
+// Concat writes the samples of all inputs one after another as one wav file to w.
+// Inputs must have the same sample rate and channels. If the sample encodings
+// differ the samples are converted to 16 bit PCM.
+func Concat(w io.Writer, inputs ...io.ReadSeeker) error {
+	if len(inputs) == 0 {
+		return errors.New("no wav files to concatenate")
+	}
+	headers := make([]*Header, len(inputs))
+	for i, in := range inputs {
+		h, err := ReadHeader(in)
+		if err != nil {
+			return err
+		}
+		headers[i] = h
+	}
+
+	out := headers[0].Format
+	for _, h := range headers[1:] {
+		if h.Format.SampleRate != out.SampleRate || h.Format.Channels != out.Channels {
+			return fmt.Errorf("%w: %+v and %+v", ErrFormatMismatch, out, h.Format)
+		}
+		if h.Format != out {
+			out = Format{
+				SampleRate:    out.SampleRate,
+				Channels:      out.Channels,
+				BitsPerSample: 16,
+			}
+		}
+	}
+
+	var dataLen int64
+	for _, h := range headers {
+		dataLen += h.DataLen / int64(h.Format.blockAlign()) * int64(out.blockAlign())
+	}
+	err := WriteHeader(w, out, int(dataLen))
+	if err != nil {
+		return err
+	}
+
+	for i, in := range inputs {
+		h := headers[i]
+		_, err = in.Seek(h.DataOffset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		data := io.LimitReader(in, h.DataLen)
+		if h.Format == out {
+			_, err = io.Copy(w, data)
+		} else {
+			err = convert(w, out, data, h.Format)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convert converts samples in format from to 16 bit PCM.
+func convert(w io.Writer, to Format, r io.Reader, from Format) error {
+	if to.BitsPerSample != 16 || to.Float {
+		return errors.New("wav conversion only supports 16 bit PCM output")
+	}
+	sampleLen := from.BitsPerSample / 8
+	in := make([]byte, 4096*sampleLen)
+	out := make([]byte, 4096*2)
+	for {
+		n, err := io.ReadFull(r, in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		samples := n / sampleLen
+		for i := range samples {
+			v, decodeErr := decode(in[i*sampleLen:(i+1)*sampleLen], from)
+			if decodeErr != nil {
+				return decodeErr
+			}
+			binary.LittleEndian.PutUint16(out[i*2:], uint16(encode16(v)))
+		}
+		_, writeErr := w.Write(out[:samples*2])
+		if writeErr != nil {
+			return writeErr
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// decode returns the sample value in the range -1 to 1.
+func decode(b []byte, f Format) (float64, error) {
+	if f.Float {
+		switch f.BitsPerSample {
+		case 32:
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+		case 64:
+			return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+		}
+		return 0, fmt.Errorf("unsupported float wav with %d bits", f.BitsPerSample)
+	}
+	switch f.BitsPerSample {
+	case 8:
+		return (float64(b[0]) - 128) / 128, nil
+	case 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15), nil
+	case 24:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		return float64(v) / (1 << 23), nil
+	case 32:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31), nil
+	}
+	return 0, fmt.Errorf("unsupported PCM wav with %d bits", f.BitsPerSample)
+}
+
+func encode16(v float64) int16 {
+	v = math.Max(-1, math.Min(1, v))
+	return int16(math.Round(v * math.MaxInt16))
+}
@@ -0,0 +1,108 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func wavBytes(t *testing.T, f Format, samples []byte) *bytes.Reader {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	err := WriteHeader(buf, f, len(samples))
+	if err != nil {
+		t.Fatalf("WriteHeader(): %v", err)
+	}
+	buf.Write(samples)
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestConcat_SameFormat(t *testing.T) {
+	a := wavBytes(t, Mono22050, []byte{1, 0, 2, 0})
+	b := wavBytes(t, Mono22050, []byte{3, 0})
+
+	buf := &bytes.Buffer{}
+	err := Concat(buf, a, b)
+	if err != nil {
+		t.Fatalf("Concat(): %v", err)
+	}
+	h, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadHeader(): %v", err)
+	}
+	if h.Format != Mono22050 {
+		t.Fatalf("format: want %+v, got %+v", Mono22050, h.Format)
+	}
+	want := []byte{1, 0, 2, 0, 3, 0}
+	if got := buf.Bytes()[h.DataOffset:]; !bytes.Equal(got, want) {
+		t.Fatalf("samples: want %v, got %v", want, got)
+	}
+}
+
+func TestConcat_ConvertFloat(t *testing.T) {
+	float32Mono := Format{SampleRate: 22050, Channels: 1, BitsPerSample: 32, Float: true}
+	samples := make([]byte, 8)
+	binary.LittleEndian.PutUint32(samples[0:], math.Float32bits(1))
+	binary.LittleEndian.PutUint32(samples[4:], math.Float32bits(-0.5))
+	a := wavBytes(t, float32Mono, samples)
+	b := wavBytes(t, Mono22050, []byte{1, 0})
+
+	buf := &bytes.Buffer{}
+	err := Concat(buf, a, b)
+	if err != nil {
+		t.Fatalf("Concat(): %v", err)
+	}
+	h, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadHeader(): %v", err)
+	}
+	if h.Format != Mono22050 {
+		t.Fatalf("format: want %+v, got %+v", Mono22050, h.Format)
+	}
+	got := buf.Bytes()[h.DataOffset:]
+	want := []int16{math.MaxInt16, -16384, 1}
+	if len(got) != len(want)*2 {
+		t.Fatalf("samples length: want %d, got %d", len(want)*2, len(got))
+	}
+	for i, w := range want {
+		if s := int16(binary.LittleEndian.Uint16(got[i*2:])); s != w {
+			t.Fatalf("sample %d: want %d, got %d", i, w, s)
+		}
+	}
+}
+
+func TestConcat_FormatMismatch(t *testing.T) {
+	a := wavBytes(t, Mono22050, []byte{1, 0})
+	b := wavBytes(t, Format{SampleRate: 44100, Channels: 1, BitsPerSample: 16}, []byte{1, 0})
+
+	err := Concat(&bytes.Buffer{}, a, b)
+	if !errors.Is(err, ErrFormatMismatch) {
+		t.Fatalf("Concat() error = %v, want %v", err, ErrFormatMismatch)
+	}
+}
+
+func TestReadHeader_SkipsChunks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := WriteSilence(buf, Mono22050, time.Second)
+	if err != nil {
+		t.Fatalf("WriteSilence(): %v", err)
+	}
+	b := buf.Bytes()
+	// Insert a LIST chunk with odd length between fmt and data chunk.
+	list := []byte{'L', 'I', 'S', 'T', 3, 0, 0, 0, 'a', 'b', 'c', 0}
+	withList := append(append(append([]byte{}, b[:36]...), list...), b[36:]...)
+
+	h, err := ReadHeader(bytes.NewReader(withList))
+	if err != nil {
+		t.Fatalf("ReadHeader(): %v", err)
+	}
+	if h.DataOffset != headerLen+int64(len(list)) {
+		t.Fatalf("data offset: want %d, got %d", headerLen+len(list), h.DataOffset)
+	}
+	if h.DataLen != 44100 {
+		t.Fatalf("data length: want 44100, got %d", h.DataLen)
+	}
+}
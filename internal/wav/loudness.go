@@ -0,0 +1,173 @@
+package wav
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"os"
+)
+
+// Loudness is the result of the measurement according to ITU-R BS.1770.
+type Loudness struct {
+	// Integrated is the gated loudness in LUFS. It is -Inf for silence.
+	Integrated float64
+	// Peak is the maximum absolute sample value in the range 0 to 1.
+	Peak float64
+}
+
+// FileLoudness measures the loudness of the wav file at path.
+func FileLoudness(path string) (*Loudness, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return MeasureLoudness(f)
+}
+
+// MeasureLoudness measures the integrated loudness with K-weighting,
+// 400ms blocks overlapping by 75%, an absolute gate at -70 LUFS and
+// a relative gate 10 LU below the loudness of the absolute gated blocks.
+func MeasureLoudness(r io.ReadSeeker) (*Loudness, error) {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.Seek(h.DataOffset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := h.Format.Channels
+	filters := make([]kWeighting, channels)
+	for i := range filters {
+		filters[i] = newKWeighting(float64(h.Format.SampleRate))
+	}
+
+	// The blocks are built from 100ms steps.
+	stepLen := h.Format.SampleRate / 10
+	var steps []float64
+	var stepSum float64
+	var stepFrames int
+	var peak float64
+
+	sampleLen := h.Format.BitsPerSample / 8
+	frame := make([]byte, channels*sampleLen)
+	br := bufio.NewReader(io.LimitReader(r, h.DataLen))
+	for {
+		_, err = io.ReadFull(br, frame)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for c := range channels {
+			v, decodeErr := decode(frame[c*sampleLen:(c+1)*sampleLen], h.Format)
+			if decodeErr != nil {
+				return nil, decodeErr
+			}
+			peak = math.Max(peak, math.Abs(v))
+			y := filters[c].process(v)
+			stepSum += y * y
+		}
+		stepFrames++
+		if stepFrames == stepLen {
+			steps = append(steps, stepSum/float64(stepLen))
+			stepSum = 0
+			stepFrames = 0
+		}
+	}
+
+	var blocks []float64
+	for i := 0; i+4 <= len(steps); i++ {
+		blocks = append(blocks, (steps[i]+steps[i+1]+steps[i+2]+steps[i+3])/4)
+	}
+
+	absGated := gate(blocks, math.Pow(10, (-70+0.691)/10))
+	if len(absGated) == 0 {
+		return &Loudness{Integrated: math.Inf(-1), Peak: peak}, nil
+	}
+	relGated := gate(absGated, mean(absGated)*math.Pow(10, -10.0/10))
+	return &Loudness{
+		Integrated: -0.691 + 10*math.Log10(mean(relGated)),
+		Peak:       peak,
+	}, nil
+}
+
+// gate returns the block powers above the threshold power.
+func gate(blocks []float64, threshold float64) []float64 {
+	var gated []float64
+	for _, b := range blocks {
+		if b > threshold {
+			gated = append(gated, b)
+		}
+	}
+	return gated
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// kWeighting is the high shelf and high pass filter of ITU-R BS.1770.
+// The coefficients are calculated for the sample rate as in libebur128.
+type kWeighting struct {
+	shelf, highPass biquad
+}
+
+func newKWeighting(sampleRate float64) kWeighting {
+	const (
+		shelfFreq = 1681.974450955533
+		shelfGain = 3.999843853973347
+		shelfQ    = 0.7071752369554196
+		passFreq  = 38.13547087602444
+		passQ     = 0.5003270373238773
+	)
+	k := math.Tan(math.Pi * shelfFreq / sampleRate)
+	vh := math.Pow(10, shelfGain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	k = math.Tan(math.Pi * passFreq / sampleRate)
+	a0 = 1 + k/passQ + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/passQ + k*k) / a0,
+	}
+	return kWeighting{shelf: shelf, highPass: highPass}
+}
+
+func (k *kWeighting) process(v float64) float64 {
+	return k.highPass.process(k.shelf.process(v))
+}
+
+// biquad is a second order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (b *biquad) process(x float64) float64 {
+	y := b.b0*x + b.b1*b.x1 + b.b2*b.x2 - b.a1*b.y1 - b.a2*b.y2
+	b.x2, b.x1 = b.x1, x
+	b.y2, b.y1 = b.y1, y
+	return y
+}
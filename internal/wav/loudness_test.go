@@ -0,0 +1,44 @@
+package wav
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMeasureLoudness(t *testing.T) {
+	// A 1 kHz sine at full scale has -3.01 LUFS. The tone has half the amplitude.
+	for _, sampleRate := range []int{22050, 48000} {
+		buf := &bytes.Buffer{}
+		err := WriteTone(buf, Mono(sampleRate), 3*time.Second, 1000, Sine)
+		if err != nil {
+			t.Fatalf("WriteTone(): %v", err)
+		}
+		l, err := MeasureLoudness(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("MeasureLoudness(): %v", err)
+		}
+		if math.Abs(l.Integrated-(-9.03)) > 0.1 {
+			t.Fatalf("%d Hz: Integrated = %.2f LUFS, want -9.03", sampleRate, l.Integrated)
+		}
+		if math.Abs(l.Peak-0.5) > 0.01 {
+			t.Fatalf("%d Hz: Peak = %.3f, want 0.5", sampleRate, l.Peak)
+		}
+	}
+}
+
+func TestMeasureLoudness_Silence(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := WriteSilence(buf, Mono22050, time.Second)
+	if err != nil {
+		t.Fatalf("WriteSilence(): %v", err)
+	}
+	l, err := MeasureLoudness(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("MeasureLoudness(): %v", err)
+	}
+	if !math.IsInf(l.Integrated, -1) {
+		t.Fatalf("Integrated = %.2f LUFS, want -Inf", l.Integrated)
+	}
+}
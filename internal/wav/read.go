@@ -0,0 +1,135 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Header is the parsed header of a wav file.
+type Header struct {
+	Format Format
+	// DataOffset is the position of the first sample in the file.
+	DataOffset int64
+	// DataLen is the length of all samples in bytes.
+	DataLen int64
+}
+
+// Duration returns the playback duration of the samples.
+func (h *Header) Duration() time.Duration {
+	frames := h.DataLen / int64(h.Format.blockAlign())
+	return time.Duration(frames) * time.Second / time.Duration(h.Format.SampleRate)
+}
+
+// FileDuration returns the playback duration of the wav file at path.
+func FileDuration(path string) (time.Duration, error) {
+	h, err := FileHeader(path)
+	if err != nil {
+		return 0, err
+	}
+	return h.Duration(), nil
+}
+
+// FileHeader reads the header of the wav file at path.
+func FileHeader(path string) (*Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return ReadHeader(f)
+}
+
+// ReadHeader reads the RIFF chunks until the data chunk is found.
+// Unknown chunks are skipped.
+func ReadHeader(r io.ReadSeeker) (*Header, error) {
+	riff := make([]byte, 12)
+	_, err := io.ReadFull(r, riff)
+	if err != nil {
+		return nil, fmt.Errorf("read wav header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, errors.New("not a wav file")
+	}
+
+	var format *Format
+	offset := int64(12)
+	chunk := make([]byte, 8)
+	for {
+		_, err = io.ReadFull(r, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("read wav chunk: %w", err)
+		}
+		offset += 8
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			format, err = readFormat(r, size)
+			if err != nil {
+				return nil, err
+			}
+		case "data":
+			if format == nil {
+				return nil, errors.New("wav data chunk before fmt chunk")
+			}
+			end, err := r.Seek(0, io.SeekEnd)
+			if err != nil {
+				return nil, err
+			}
+			// Streamed wav files may have a wrong data length.
+			size = min(size, end-offset)
+			size -= size % int64(format.blockAlign())
+			_, err = r.Seek(offset, io.SeekStart)
+			if err != nil {
+				return nil, err
+			}
+			return &Header{
+				Format:     *format,
+				DataOffset: offset,
+				DataLen:    size,
+			}, nil
+		default:
+			_, err = r.Seek(size+size%2, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+		}
+		offset += size + size%2
+	}
+}
+
+func readFormat(r io.Reader, size int64) (*Format, error) {
+	if size < 16 {
+		return nil, errors.New("wav fmt chunk too short")
+	}
+	b := make([]byte, size+size%2)
+	_, err := io.ReadFull(r, b)
+	if err != nil {
+		return nil, fmt.Errorf("read wav fmt chunk: %w", err)
+	}
+	tag := binary.LittleEndian.Uint16(b[0:2])
+	if tag == formatExtensible && size >= 26 {
+		// The first two bytes of the sub format GUID are the format tag.
+		tag = binary.LittleEndian.Uint16(b[24:26])
+	}
+	if tag != formatPCM && tag != formatFloat {
+		return nil, fmt.Errorf("unsupported wav format tag %d", tag)
+	}
+	f := &Format{
+		Channels:      int(binary.LittleEndian.Uint16(b[2:4])),
+		SampleRate:    int(binary.LittleEndian.Uint32(b[4:8])),
+		BitsPerSample: int(binary.LittleEndian.Uint16(b[14:16])),
+		Float:         tag == formatFloat,
+	}
+	if f.Channels <= 0 || f.SampleRate <= 0 || f.BitsPerSample <= 0 || f.BitsPerSample%8 != 0 {
+		return nil, errors.New("invalid wav fmt chunk")
+	}
+	return f, nil
+}
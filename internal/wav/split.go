@@ -0,0 +1,102 @@
+package wav
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"time"
+)
+
+// silenceThreshold is the absolute sample value up to which a sample is silent (-60 dBFS).
+const silenceThreshold = 0.001
+
+// Part is a range of the samples of a wav file.
+type Part struct {
+	// Offset is the position of the first sample of the part in the file.
+	Offset int64
+	// Len is the length of the samples of the part in bytes.
+	Len int64
+}
+
+// SplitAtSilence returns the header of the wav file and the parts of the samples
+// separated by silences of at least gap. Every part keeps up to margin of the
+// silence before and after it. The silence at the start and the end of the
+// file is trimmed to margin as well.
+func SplitAtSilence(r io.ReadSeeker, gap time.Duration, margin time.Duration) (*Header, []Part, error) {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rate := int64(h.Format.SampleRate)
+	gapFrames := int64(gap.Seconds() * float64(rate))
+	marginFrames := int64(margin.Seconds() * float64(rate))
+	totalFrames := h.DataLen / int64(h.Format.blockAlign())
+
+	var parts []Part
+	addPart := func(start, end int64) {
+		start = max(start-marginFrames, 0)
+		end = min(end+marginFrames, totalFrames)
+		parts = append(parts, Part{
+			Offset: h.DataOffset + start*int64(h.Format.blockAlign()),
+			Len:    (end - start) * int64(h.Format.blockAlign()),
+		})
+	}
+
+	// start is the first sound frame of the current part, -1 before it.
+	start := int64(-1)
+	// end is the frame after the last sound frame of the current part.
+	var end int64
+	sampleLen := h.Format.BitsPerSample / 8
+	frame := make([]byte, h.Format.blockAlign())
+	br := bufio.NewReader(io.LimitReader(r, h.DataLen))
+	for i := range totalFrames {
+		_, err = io.ReadFull(br, frame)
+		if err != nil {
+			return nil, nil, err
+		}
+		silent := true
+		for c := range h.Format.Channels {
+			v, err := decode(frame[c*sampleLen:(c+1)*sampleLen], h.Format)
+			if err != nil {
+				return nil, nil, err
+			}
+			if v > silenceThreshold || v < -silenceThreshold {
+				silent = false
+				break
+			}
+		}
+		if !silent {
+			if start < 0 {
+				start = i
+			}
+			end = i + 1
+			continue
+		}
+		if start >= 0 && i+1-end >= gapFrames {
+			addPart(start, end)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		addPart(start, end)
+	}
+	return h, parts, nil
+}
+
+// WritePart writes the samples of part of the wav file r with header h as wav file to w.
+func WritePart(w io.Writer, r io.ReadSeeker, h *Header, part Part) error {
+	if part.Offset < h.DataOffset || part.Offset+part.Len > h.DataOffset+h.DataLen {
+		return errors.New("wav part out of range")
+	}
+	err := WriteHeader(w, h.Format, int(part.Len))
+	if err != nil {
+		return err
+	}
+	_, err = r.Seek(part.Offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, r, part.Len)
+	return err
+}
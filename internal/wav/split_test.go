@@ -0,0 +1,64 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestSplitAtSilence(t *testing.T) {
+	// 10 frames per second: 100ms per frame.
+	f := Mono(10)
+	frames := []int16{0, 0, 900, -900, 0, 900, 0, 0, 0, 0, 900, 0, 0}
+	samples := make([]byte, 2*len(frames))
+	for i, v := range frames {
+		binary.LittleEndian.PutUint16(samples[2*i:], uint16(v))
+	}
+	r := wavBytes(t, f, samples)
+
+	h, parts, err := SplitAtSilence(r, 300*time.Millisecond, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("SplitAtSilence(): %v", err)
+	}
+	// The short silence at frame 4 does not split, the margin keeps one silent frame around the parts.
+	want := []Part{
+		{Offset: headerLen + 2, Len: 12},
+		{Offset: headerLen + 18, Len: 6},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("parts: want %v, got %v", want, parts)
+	}
+	for i := range want {
+		if parts[i] != want[i] {
+			t.Fatalf("parts: want %v, got %v", want, parts)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	err = WritePart(buf, r, h, parts[1])
+	if err != nil {
+		t.Fatalf("WritePart(): %v", err)
+	}
+	got, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadHeader(): %v", err)
+	}
+	if got.Duration() != 300*time.Millisecond {
+		t.Fatalf("Duration() = %s, want 300ms", got.Duration())
+	}
+	if !bytes.Equal(buf.Bytes()[got.DataOffset:], samples[18:24]) {
+		t.Fatalf("samples: want %v, got %v", samples[18:24], buf.Bytes()[got.DataOffset:])
+	}
+}
+
+func TestSplitAtSilence_Silence(t *testing.T) {
+	r := wavBytes(t, Mono(10), make([]byte, 20))
+	_, parts, err := SplitAtSilence(r, 300*time.Millisecond, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("SplitAtSilence(): %v", err)
+	}
+	if len(parts) != 0 {
+		t.Fatalf("parts: want none, got %v", parts)
+	}
+}
@@ -0,0 +1,73 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// Oscillator returns a sample value between -1 and 1 for a phase between 0 and 1.
+type Oscillator func(phase float64) float64
+
+func Sine(phase float64) float64 {
+	return math.Sin(2 * math.Pi * phase)
+}
+
+func Square(phase float64) float64 {
+	if phase < 0.5 {
+		return 1
+	}
+	return -1
+}
+
+func Triangle(phase float64) float64 {
+	return 1 - 4*math.Abs(phase-0.5)
+}
+
+func Sawtooth(phase float64) float64 {
+	return 2*phase - 1
+}
+
+const (
+	// toneAmplitude leaves headroom for mixing with speech.
+	toneAmplitude = 0.5
+	// toneFade avoids clicks at the start and end of a tone.
+	toneFade = 5 * time.Millisecond
+)
+
+// WriteTone writes a wav file with a tone of duration d. Only 16 bit PCM is supported.
+func WriteTone(w io.Writer, f Format, d time.Duration, frequency float64, osc Oscillator) error {
+	if d <= 0 {
+		return errors.New("negative or zero duration for tone")
+	}
+	if frequency <= 0 || frequency >= float64(f.SampleRate)/2 {
+		return errors.New("tone frequency must be between 0 and half the sample rate")
+	}
+	if f.BitsPerSample != 16 || f.Float {
+		return errors.New("tone only supports 16 bit PCM")
+	}
+	dataLen := f.dataLen(d)
+	err := WriteHeader(w, f, dataLen)
+	if err != nil {
+		return err
+	}
+
+	frames := dataLen / f.blockAlign()
+	fadeFrames := int(toneFade.Seconds() * float64(f.SampleRate))
+	frame := make([]byte, f.blockAlign())
+	for i := range frames {
+		phase := math.Mod(float64(i)*frequency/float64(f.SampleRate), 1)
+		gain := toneAmplitude * min(1, float64(i)/float64(fadeFrames), float64(frames-1-i)/float64(fadeFrames))
+		sample := uint16(encode16(gain * osc(phase)))
+		for c := range f.Channels {
+			binary.LittleEndian.PutUint16(frame[c*2:], sample)
+		}
+		_, err = w.Write(frame)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
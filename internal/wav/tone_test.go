@@ -0,0 +1,44 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestWriteTone(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := WriteTone(buf, Mono22050, 100*time.Millisecond, 440, Sine)
+	if err != nil {
+		t.Fatalf("WriteTone(): %v", err)
+	}
+	h, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadHeader(): %v", err)
+	}
+	if h.Duration() != 100*time.Millisecond {
+		t.Fatalf("Duration() = %s, want 100ms", h.Duration())
+	}
+
+	samples := buf.Bytes()[h.DataOffset:]
+	first := int16(binary.LittleEndian.Uint16(samples))
+	last := int16(binary.LittleEndian.Uint16(samples[len(samples)-2:]))
+	if first != 0 || last != 0 {
+		t.Fatalf("tone not faded: first sample %d, last sample %d", first, last)
+	}
+	var peak int16
+	for i := 0; i < len(samples); i += 2 {
+		peak = max(peak, int16(binary.LittleEndian.Uint16(samples[i:])))
+	}
+	if peak < 16000 || peak > 16384 {
+		t.Fatalf("peak: want about half of full scale, got %d", peak)
+	}
+}
+
+func TestWriteTone_InvalidFrequency(t *testing.T) {
+	err := WriteTone(&bytes.Buffer{}, Mono22050, time.Second, 12000, Sine)
+	if err == nil {
+		t.Fatal("expected error for frequency above half the sample rate")
+	}
+}
@@ -0,0 +1,139 @@
+// Package wav reads and writes uncompressed PCM wav files.
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// Format describes the samples of a wav file.
+type Format struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	// Float is true for IEEE float samples, otherwise samples are PCM integers.
+	Float bool
+}
+
+// Mono22050 is the default format of the intermediate wav files.
+var Mono22050 = Mono(22050)
+
+// Mono returns the 16-bit mono format with the sample rate.
+func Mono(sampleRate int) Format {
+	return Format{
+		SampleRate:    sampleRate,
+		Channels:      1,
+		BitsPerSample: 16,
+	}
+}
+
+func (f Format) blockAlign() int {
+	return f.Channels * f.BitsPerSample / 8
+}
+
+func (f Format) byteRate() int {
+	return f.SampleRate * f.blockAlign()
+}
+
+// dataLen returns the length in bytes of d rounded down to whole sample frames.
+func (f Format) dataLen(d time.Duration) int {
+	frames := int(d.Seconds() * float64(f.SampleRate))
+	return frames * f.blockAlign()
+}
+
+const headerLen = 44
+
+const (
+	formatPCM        = 1
+	formatFloat      = 3
+	formatExtensible = 0xFFFE
+)
+
+// WriteHeader writes a canonical 44 byte wav header for dataLen bytes of samples.
+func WriteHeader(w io.Writer, f Format, dataLen int) error {
+	if f.SampleRate <= 0 || f.Channels <= 0 || f.BitsPerSample <= 0 || f.BitsPerSample%8 != 0 {
+		return errors.New("invalid wav format")
+	}
+	header := make([]byte, headerLen)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(headerLen-8+dataLen))
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	if f.Float {
+		binary.LittleEndian.PutUint16(header[20:], formatFloat)
+	} else {
+		binary.LittleEndian.PutUint16(header[20:], formatPCM)
+	}
+	binary.LittleEndian.PutUint16(header[22:], uint16(f.Channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(f.SampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(f.byteRate()))
+	binary.LittleEndian.PutUint16(header[32:], uint16(f.blockAlign()))
+	binary.LittleEndian.PutUint16(header[34:], uint16(f.BitsPerSample))
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(dataLen))
+	_, err := w.Write(header)
+	return err
+}
+
+// WriteSilence writes a wav file with zero samples of duration d.
+func WriteSilence(w io.Writer, f Format, d time.Duration) error {
+	if d <= 0 {
+		return errors.New("negative or zero duration for silence")
+	}
+	dataLen := f.dataLen(d)
+	err := WriteHeader(w, f, dataLen)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, zeroReader{}, int64(dataLen))
+	return err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// Pad writes the wav file r extended with zero samples to length d to w.
+// A file longer than d is written unchanged.
+func Pad(w io.Writer, r io.ReadSeeker, d time.Duration) error {
+	return resize(w, r, d, false)
+}
+
+// Fit writes the wav file r extended with zero samples or cut to length d to w.
+func Fit(w io.Writer, r io.ReadSeeker, d time.Duration) error {
+	return resize(w, r, d, true)
+}
+
+// resize writes the wav file r extended to length d to w. If cut is set, a longer file is cut to d.
+func resize(w io.Writer, r io.ReadSeeker, d time.Duration, cut bool) error {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return err
+	}
+	dataLen := int64(h.Format.dataLen(d))
+	copyLen := h.DataLen
+	if cut {
+		copyLen = min(copyLen, dataLen)
+	}
+	padLen := max(dataLen-copyLen, 0)
+	err = WriteHeader(w, h.Format, int(copyLen+padLen))
+	if err != nil {
+		return err
+	}
+	_, err = r.Seek(h.DataOffset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, r, copyLen)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, zeroReader{}, padLen)
+	return err
+}
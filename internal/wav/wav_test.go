@@ -0,0 +1,121 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteSilence(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := WriteSilence(buf, Mono22050, 1500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WriteSilence(): %v", err)
+	}
+	b := buf.Bytes()
+
+	wantDataLen := 33075 * 2
+	if len(b) != headerLen+wantDataLen {
+		t.Fatalf("file length: want %d, got %d", headerLen+wantDataLen, len(b))
+	}
+	if string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" || string(b[36:40]) != "data" {
+		t.Fatalf("invalid header: %q", b[:headerLen])
+	}
+	if got := binary.LittleEndian.Uint32(b[40:]); got != uint32(wantDataLen) {
+		t.Fatalf("data chunk length: want %d, got %d", wantDataLen, got)
+	}
+	if bytes.ContainsFunc(b[headerLen:], func(r rune) bool { return r != 0 }) {
+		t.Fatal("silence contains non-zero samples")
+	}
+}
+
+func TestPad(t *testing.T) {
+	// 10 frames per second: 100ms per frame.
+	tests := []struct {
+		name string
+		d    time.Duration
+		want []byte
+	}{
+		{"shorter", 300 * time.Millisecond, []byte{1, 0, 2, 0, 0, 0}},
+		{"longer", 100 * time.Millisecond, []byte{1, 0, 2, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := Pad(buf, wavBytes(t, Mono(10), []byte{1, 0, 2, 0}), tt.d)
+			if err != nil {
+				t.Fatalf("Pad(): %v", err)
+			}
+			h, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("ReadHeader(): %v", err)
+			}
+			if got := buf.Bytes()[h.DataOffset:]; !bytes.Equal(got, tt.want) {
+				t.Fatalf("samples: want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	// 10 frames per second: 100ms per frame.
+	tests := []struct {
+		name string
+		d    time.Duration
+		want []byte
+	}{
+		{"shorter", 300 * time.Millisecond, []byte{1, 0, 2, 0, 0, 0}},
+		{"longer", 100 * time.Millisecond, []byte{1, 0}},
+		{"same", 200 * time.Millisecond, []byte{1, 0, 2, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := Fit(buf, wavBytes(t, Mono(10), []byte{1, 0, 2, 0}), tt.d)
+			if err != nil {
+				t.Fatalf("Fit(): %v", err)
+			}
+			h, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("ReadHeader(): %v", err)
+			}
+			if h.DataLen != int64(len(tt.want)) {
+				t.Fatalf("data length: want %d, got %d", len(tt.want), h.DataLen)
+			}
+			if got := buf.Bytes()[h.DataOffset:]; !bytes.Equal(got, tt.want) {
+				t.Fatalf("samples: want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteSilence_ZeroDuration(t *testing.T) {
+	err := WriteSilence(&bytes.Buffer{}, Mono22050, 0)
+	if err == nil {
+		t.Fatal("expected error for zero duration")
+	}
+}
+
+func TestFileDuration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silence.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+	err = WriteSilence(f, Mono22050, 2500*time.Millisecond)
+	_ = f.Close()
+	if err != nil {
+		t.Fatalf("WriteSilence(): %v", err)
+	}
+
+	got, err := FileDuration(path)
+	if err != nil {
+		t.Fatalf("FileDuration(): %v", err)
+	}
+	if got != 2500*time.Millisecond {
+		t.Fatalf("FileDuration() = %s, want 2.5s", got)
+	}
+}
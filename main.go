@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -11,15 +10,21 @@ import (
 	"github.com/mrclmr/w2a/cmd/w2a"
 )
 
-var version = "dev"
+// Set by goreleaser.
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
 
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
 
-	err := cmd.ExecuteContext(ctx, version)
-	if err != nil && !errors.Is(err, context.Canceled) {
+	err := cmd.ExecuteContext(ctx, cmd.BuildInfo{Version: version, Commit: commit, Date: date})
+	code := cmd.ExitCode(ctx, err)
+	if err != nil && code != cmd.ExitCanceled {
 		_, _ = fmt.Fprintf(os.Stderr, "w2a: %v\n", err)
-		os.Exit(1)
 	}
+	stop()
+	os.Exit(code)
 }